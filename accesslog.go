@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// newAccessLogWriter returns where access logs go: stdout by default, or a
+// size and age rotated file when -access-log is set. Both are safe for
+// concurrent use.
+func newAccessLogWriter(cfg config) io.Writer {
+	if cfg.accessLog == "" {
+		return os.Stdout
+	}
+	return &lumberjack.Logger{
+		Filename:   cfg.accessLog,
+		MaxSize:    cfg.accessLogMaxSizeMB,
+		MaxAge:     cfg.accessLogMaxAgeDays,
+		MaxBackups: cfg.accessLogMaxBackups,
+	}
+}
+
+// accessLogMiddleware writes one JSON line per request to logger once the
+// response is done.
+func accessLogMiddleware(logger *slog.Logger, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started := time.Now()
+		rec := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(rec, r)
+
+		if rec.status == 0 {
+			rec.status = http.StatusOK
+		}
+		logger.Info("request",
+			"method", r.Method,
+			"uri", r.URL.RequestURI(),
+			"status", rec.status,
+			"bytes", rec.bytes,
+			"durationMs", float64(time.Since(started).Microseconds())/1000,
+			"remote", clientIP(r),
+			"peer", r.RemoteAddr,
+			"userAgent", r.UserAgent(),
+			"requestId", requestIDFromContext(r.Context()),
+		)
+	})
+}
+
+// statusRecorder captures the status code and body size of a response. It
+// passes Flush and Hijack through so event streams and WebSockets keep
+// working behind it.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (rec *statusRecorder) WriteHeader(status int) {
+	if rec.status == 0 {
+		rec.status = status
+	}
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *statusRecorder) Write(p []byte) (int, error) {
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+	n, err := rec.ResponseWriter.Write(p)
+	rec.bytes += int64(n)
+	return n, err
+}
+
+func (rec *statusRecorder) Flush() {
+	if flusher, ok := rec.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (rec *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := rec.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("response does not support hijacking")
+	}
+	rec.status = http.StatusSwitchingProtocols
+	return hijacker.Hijack()
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (rec *statusRecorder) Unwrap() http.ResponseWriter {
+	return rec.ResponseWriter
+}
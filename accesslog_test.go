@@ -0,0 +1,140 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// accessLogLine is the part of an access log entry the tests look at.
+type accessLogLine struct {
+	Msg    string `json:"msg"`
+	Method string `json:"method"`
+	URI    string `json:"uri"`
+	Status int    `json:"status"`
+	Bytes  int64  `json:"bytes"`
+}
+
+// newFileAccessLog returns an access-logged handler writing to a file in a
+// temporary directory, the way main wires -access-log.
+func newFileAccessLog(t *testing.T, next http.Handler) (http.Handler, *lumberjack.Logger) {
+	cfg := testConfig()
+	cfg.accessLog = filepath.Join(t.TempDir(), "access.log")
+	writer, ok := newAccessLogWriter(cfg).(*lumberjack.Logger)
+	if !ok {
+		t.Fatalf("-access-log did not give a rotating file writer")
+	}
+	t.Cleanup(func() { writer.Close() })
+	return accessLogMiddleware(slog.New(slog.NewJSONHandler(writer, nil)), next), writer
+}
+
+func readAccessLog(t *testing.T, path string) []accessLogLine {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var lines []accessLogLine
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var line accessLogLine
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			t.Fatalf("line %d is not JSON: %v: %s", len(lines)+1, err, scanner.Bytes())
+		}
+		lines = append(lines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return lines
+}
+
+func TestAccessLogFile(t *testing.T) {
+	svc := newTestSvc()
+	mustAdd(t, svc, Article{ID: "a1", Title: "T"})
+	h, writer := newFileAccessLog(t, newTestHandler(svc))
+
+	const n = 50
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			target := "/articles/a1"
+			if i%2 == 1 {
+				target = fmt.Sprintf("/articles/missing-%d", i)
+			}
+			doRequest(h, "GET", target, "")
+		}()
+	}
+	wg.Wait()
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := readAccessLog(t, writer.Filename)
+	if len(lines) != n {
+		t.Fatalf("got %d lines, want %d", len(lines), n)
+	}
+	counts := map[int]int{}
+	for _, line := range lines {
+		counts[line.Status]++
+		if line.Msg != "request" || line.Method != "GET" || !strings.HasPrefix(line.URI, "/articles/") {
+			t.Errorf("unexpected entry %+v", line)
+		}
+		if line.Status == http.StatusOK && line.Bytes == 0 {
+			t.Errorf("200 entry without a body size: %+v", line)
+		}
+	}
+	if counts[http.StatusOK] != n/2 || counts[http.StatusNotFound] != n/2 {
+		t.Errorf("statuses = %v, want %d of 200 and 404", counts, n/2)
+	}
+}
+
+func TestAccessLogFileRotates(t *testing.T) {
+	h, writer := newFileAccessLog(t, okHandler)
+
+	// Each entry is a few hundred bytes; a long URI gets past the 1 MB
+	// -access-log-max-size in a few hundred requests.
+	pad := strings.Repeat("x", 4096)
+	const n = 400
+	for i := 0; i < n; i++ {
+		doRequest(h, "GET", fmt.Sprintf("/%d?pad=%s", i, pad), "")
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := filepath.Glob(filepath.Join(filepath.Dir(writer.Filename), "access*.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) < 2 {
+		t.Fatalf("files = %v, want the current log and a rotated one", files)
+	}
+	total := 0
+	for _, file := range files {
+		total += len(readAccessLog(t, file))
+	}
+	if total != n {
+		t.Errorf("%d entries across %d files, want %d", total, len(files), n)
+	}
+}
+
+func TestAccessLogDefaultsToStdout(t *testing.T) {
+	if w := newAccessLogWriter(testConfig()); w != io.Writer(os.Stdout) {
+		t.Errorf("writer = %T, want os.Stdout", w)
+	}
+}
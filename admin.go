@@ -0,0 +1,150 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync/atomic"
+
+	"github.com/gorilla/mux"
+)
+
+// readOnlyRetryAfter is the Retry-After value, in seconds, sent with writes
+// rejected in read-only mode.
+const readOnlyRetryAfter = "120"
+
+// adminHttpTransport serves operator endpoints. All of them require the
+// admin scope; destructive ones are only registered when enabled, and the
+// metrics snapshot only when metrics are collected.
+type adminHttpTransport struct {
+	svc         ArticlesService
+	readOnly    *atomic.Bool
+	logLevel    *slog.LevelVar
+	destructive bool
+	metrics     *httpMetrics
+}
+
+func newAdminHttpTransport(svc ArticlesService, readOnly *atomic.Bool, logLevel *slog.LevelVar, destructive bool, metrics *httpMetrics) *adminHttpTransport {
+	return &adminHttpTransport{svc: svc, readOnly: readOnly, logLevel: logLevel, destructive: destructive, metrics: metrics}
+}
+
+func (t *adminHttpTransport) setupRoutes(r *mux.Router) *mux.Router {
+	r.Use(requireScope(scopeAdmin))
+	r.HandleFunc("/readonly", t.readOnlyMode).Methods("GET")
+	r.HandleFunc("/readonly", t.setReadOnlyMode).Methods("POST")
+	r.HandleFunc("/loglevel", t.logLevelState).Methods("GET")
+	r.HandleFunc("/loglevel", t.setLogLevel).Methods("POST")
+	r.HandleFunc("/reindex", t.reindex).Methods("POST")
+	r.HandleFunc("/selfcheck", t.selfCheck).Methods("GET")
+	if t.destructive {
+		r.HandleFunc("/articles", t.clearArticles).Methods("DELETE")
+	}
+	if t.metrics != nil {
+		r.HandleFunc("/metrics.json", t.metricsSnapshot).Methods("GET")
+	}
+	return r
+}
+
+// metricsSnapshot serves the collected metrics as JSON, for operators who
+// want a quick look with curl rather than a Prometheus server.
+func (t *adminHttpTransport) metricsSnapshot(w http.ResponseWriter, _ *http.Request) {
+	snapshot, err := t.metrics.snapshot()
+	if err != nil {
+		log.Println(err)
+		w.WriteHeader(serverErrorStatus(err))
+		io.WriteString(w, errorBody(err))
+		return
+	}
+	writeEncoded(w, jsonEncoding{}, http.StatusOK, snapshot)
+}
+
+type readOnlyState struct {
+	Enabled bool `json:"enabled"`
+}
+
+func (t *adminHttpTransport) readOnlyMode(w http.ResponseWriter, _ *http.Request) {
+	writeEncoded(w, jsonEncoding{}, http.StatusOK, readOnlyState{Enabled: t.readOnly.Load()})
+}
+
+func (t *adminHttpTransport) setReadOnlyMode(w http.ResponseWriter, r *http.Request) {
+	var state readOnlyState
+	if err := json.NewDecoder(r.Body).Decode(&state); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+
+	t.readOnly.Store(state.Enabled)
+	log.Printf("read-only mode set to %v by %s from %s", state.Enabled, principalFromContext(r.Context()).Name, clientIP(r))
+	t.readOnlyMode(w, r)
+}
+
+type logLevelRequest struct {
+	Level slog.Level `json:"level"`
+}
+
+func (t *adminHttpTransport) logLevelState(w http.ResponseWriter, _ *http.Request) {
+	writeEncoded(w, jsonEncoding{}, http.StatusOK, logLevelRequest{Level: t.logLevel.Level()})
+}
+
+// setLogLevel changes the log level, e.g. to {"level": "debug"}, for every
+// log line written from then on.
+func (t *adminHttpTransport) setLogLevel(w http.ResponseWriter, r *http.Request) {
+	var req logLevelRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+
+	t.logLevel.Set(req.Level)
+	log.Printf("log level set to %s by %s from %s", req.Level, principalFromContext(r.Context()).Name, clientIP(r))
+	t.logLevelState(w, r)
+}
+
+type clearArticlesResponse struct {
+	Removed int `json:"removed"`
+}
+
+// clearArticles deletes every stored article. It requires ?confirm=true so
+// a stray DELETE can't wipe the store.
+func (t *adminHttpTransport) clearArticles(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Query().Get("confirm") != "true" {
+		w.WriteHeader(http.StatusBadRequest)
+		io.WriteString(w, "this deletes every article; add ?confirm=true to proceed")
+		return
+	}
+
+	removed, err := t.svc.Clear(r.Context())
+	if err != nil {
+		log.Println(err)
+		w.WriteHeader(serverErrorStatus(err))
+		io.WriteString(w, errorBody(err))
+		return
+	}
+
+	log.Printf("%d articles cleared by %s from %s", removed, principalFromContext(r.Context()).Name, clientIP(r))
+	writeEncoded(w, jsonEncoding{}, http.StatusOK, clearArticlesResponse{Removed: removed})
+}
+
+// readOnlyMiddleware rejects mutating requests with 503 while readOnly is
+// set. Safe methods and the admin endpoints keep working, so operators can
+// switch the mode back off.
+func readOnlyMiddleware(readOnly *atomic.Bool, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if readOnly.Load() && !strings.HasPrefix(r.URL.Path, "/admin/") {
+			w.Header().Set("Retry-After", readOnlyRetryAfter)
+			w.WriteHeader(http.StatusServiceUnavailable)
+			io.WriteString(w, "service is in read-only mode")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
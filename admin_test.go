@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"slices"
+	"sync/atomic"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	testAdminKey = "admin-key"
+	testWriteKey = "write-key"
+	testReadKey  = "read-key"
+)
+
+// testKeys grants testAdminKey the admin scope, testWriteKey the write
+// scope and testReadKey none.
+var testKeys = map[string][]string{
+	testAdminKey: {scopeAdmin},
+	testWriteKey: {scopeWrite},
+	testReadKey:  {},
+}
+
+// adminTestServer mounts the article and admin routes over one service
+// behind the read-only switch and testKeys, the way main does.
+type adminTestServer struct {
+	http.Handler
+	svc      *articleSvc
+	readOnly *atomic.Bool
+	logLevel *slog.LevelVar
+}
+
+func newAdminTestServer(svc *articleSvc, destructive bool, metrics *httpMetrics) *adminTestServer {
+	s := &adminTestServer{svc: svc, readOnly: new(atomic.Bool), logLevel: new(slog.LevelVar)}
+	root := mux.NewRouter()
+	newArticlesHttpTransport(svc).setupRoutes(root.PathPrefix("/articles").Subrouter())
+	newAdminHttpTransport(svc, s.readOnly, s.logLevel, destructive, metrics).setupRoutes(root.PathPrefix("/admin").Subrouter())
+	s.Handler = testAuth(readOnlyMiddleware(s.readOnly, root), testKeys)
+	return s
+}
+
+func TestReadOnlyMode(t *testing.T) {
+	svc := newTestSvc()
+	mustAdd(t, svc, Article{ID: "a1", Title: "T"})
+	s := newAdminTestServer(svc, false, nil)
+
+	if rec := doRequest(s, "POST", "/admin/readonly", `{"enabled":true}`, "X-API-Key", testAdminKey); rec.Code != http.StatusOK || rec.Body.String() != "{\"enabled\":true}\n" {
+		t.Fatalf("enabling: got %d %s", rec.Code, rec.Body)
+	}
+
+	writes := []struct{ method, target, body string }{
+		{"PUT", "/articles", `{"id":"a2","title":"T"}`},
+		{"PUT", "/articles/a1", `{"id":"a1","title":"Changed"}`},
+		{"DELETE", "/articles/a1", ""},
+		{"POST", "/articles/a1/pin", ""},
+	}
+	for _, w := range writes {
+		rec := doRequest(s, w.method, w.target, w.body)
+		if rec.Code != http.StatusServiceUnavailable || rec.Header().Get("Retry-After") != readOnlyRetryAfter {
+			t.Errorf("%s %s: got %d Retry-After %q, want 503 with Retry-After", w.method, w.target, rec.Code, rec.Header().Get("Retry-After"))
+		}
+	}
+	if got := mustGet(t, svc, "a1"); got.Title != "T" || got.Pinned {
+		t.Errorf("a rejected write went through: %+v", got)
+	}
+
+	for _, target := range []string{"/articles", "/articles/a1"} {
+		if rec := doRequest(s, "GET", target, ""); rec.Code != http.StatusOK {
+			t.Errorf("GET %s: status = %d, want 200", target, rec.Code)
+		}
+	}
+	if rec := doRequest(s, "GET", "/admin/readonly", "", "X-API-Key", testAdminKey); rec.Body.String() != "{\"enabled\":true}\n" {
+		t.Errorf("GET /admin/readonly = %s", rec.Body)
+	}
+
+	if rec := doRequest(s, "POST", "/admin/readonly", `{"enabled":false}`, "X-API-Key", testAdminKey); rec.Code != http.StatusOK {
+		t.Fatalf("disabling: got %d %s", rec.Code, rec.Body)
+	}
+	if rec := doRequest(s, "PUT", "/articles", `{"id":"a2","title":"T"}`); rec.Code != http.StatusCreated {
+		t.Errorf("write after disabling: status = %d, want 201", rec.Code)
+	}
+}
+
+func TestReadOnlyModeNeedsAdmin(t *testing.T) {
+	s := newAdminTestServer(newTestSvc(), false, nil)
+	tests := []struct {
+		key    string
+		status int
+	}{
+		{key: "", status: http.StatusUnauthorized},
+		{key: testWriteKey, status: http.StatusForbidden},
+		{key: testAdminKey, status: http.StatusOK},
+	}
+	for _, tt := range tests {
+		if rec := doRequest(s, "POST", "/admin/readonly", `{"enabled":true}`, "X-API-Key", tt.key); rec.Code != tt.status {
+			t.Errorf("key %q: status = %d, want %d", tt.key, rec.Code, tt.status)
+		}
+	}
+}
+
+func TestClearArticles(t *testing.T) {
+	tests := []struct {
+		name        string
+		destructive bool
+		key         string
+		target      string
+		wantCode    int
+		wantBody    string
+		wantIDs     []string
+	}{
+		{"clears with confirm", true, testAdminKey, "/admin/articles?confirm=true", http.StatusOK, "{\"removed\":3}\n", nil},
+		{"needs confirm", true, testAdminKey, "/admin/articles", http.StatusBadRequest, "", []string{"a1", "a2", "a3"}},
+		{"needs confirm=true", true, testAdminKey, "/admin/articles?confirm=1", http.StatusBadRequest, "", []string{"a1", "a2", "a3"}},
+		{"needs a key", true, "", "/admin/articles?confirm=true", http.StatusUnauthorized, "", []string{"a1", "a2", "a3"}},
+		{"needs the admin scope", true, testWriteKey, "/admin/articles?confirm=true", http.StatusForbidden, "", []string{"a1", "a2", "a3"}},
+		{"disabled without -enable-admin", false, testAdminKey, "/admin/articles?confirm=true", http.StatusNotFound, "", []string{"a1", "a2", "a3"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := newTestSvc()
+			mustAdd(t, svc, Article{ID: "a1", Title: "T"}, Article{ID: "a2", Title: "T"}, Article{ID: "a3", Title: "T", Tags: []string{"go"}})
+			s := newAdminTestServer(svc, tt.destructive, nil)
+
+			rec := doRequest(s, "DELETE", tt.target, "", "X-API-Key", tt.key)
+			if rec.Code != tt.wantCode {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantCode, rec.Body)
+			}
+			if tt.wantBody != "" && rec.Body.String() != tt.wantBody {
+				t.Errorf("body = %q, want %q", rec.Body, tt.wantBody)
+			}
+			if ids := storedIDs(t, svc.repo); !slices.Equal(ids, tt.wantIDs) {
+				t.Errorf("stored %v, want %v", ids, tt.wantIDs)
+			}
+		})
+	}
+}
+
+func TestClearArticlesEmptiesEveryView(t *testing.T) {
+	svc := newTestSvc()
+	mustAdd(t, svc, Article{ID: "a1", Title: "T", Tags: []string{"go"}}, Article{ID: "a2", Title: "T"})
+	s := newAdminTestServer(svc, true, nil)
+
+	if rec := doRequest(s, "DELETE", "/admin/articles?confirm=true", "", "X-API-Key", testAdminKey); rec.Code != http.StatusOK {
+		t.Fatalf("clear: status = %d: %s", rec.Code, rec.Body)
+	}
+
+	if rec := doRequest(s, "GET", "/articles", ""); rec.Code != http.StatusOK || rec.Body.String() != "[]\n" {
+		t.Errorf("listing after clear = %d %q, want 200 []", rec.Code, rec.Body)
+	}
+	if rec := doRequest(s, "GET", "/articles/a1", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("GET a cleared article: status = %d, want 404", rec.Code)
+	}
+	if freq, err := svc.repo.TagFrequency(context.Background()); err != nil || len(freq) != 0 {
+		t.Errorf("TagFrequency after clear = %v, %v", freq, err)
+	}
+	if rec := doRequest(s, "DELETE", "/admin/articles?confirm=true", "", "X-API-Key", testAdminKey); rec.Body.String() != "{\"removed\":0}\n" {
+		t.Errorf("clearing an empty store = %q, want removed 0", rec.Body)
+	}
+	if rec := doRequest(s, "PUT", "/articles", `{"id":"a1","title":"Again"}`, "X-API-Key", testWriteKey); rec.Code != http.StatusCreated {
+		t.Errorf("re-creating a cleared ID: status = %d: %s", rec.Code, rec.Body)
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"sync"
+
+	"github.com/gorilla/mux"
+)
+
+type articleLoaderKey struct{}
+
+// articleLoader fetches the article named by a request's {id} at most once,
+// so middlewares and the handler that all need it share one repo call.
+// Errors, ErrArticleNotFound included, are memoized too.
+type articleLoader struct {
+	id   string
+	load func() (*Article, error)
+
+	once    sync.Once
+	article *Article
+	err     error
+}
+
+// get returns the article, loading it on first use. Each caller gets its own
+// copy, as from ArticlesService.Article.
+func (l *articleLoader) get() (*Article, error) {
+	l.once.Do(func() {
+		l.article, l.err = l.load()
+	})
+	if l.err != nil {
+		return nil, l.err
+	}
+	article := *l.article
+	return &article, nil
+}
+
+// articleLoaderMiddleware attaches an articleLoader to requests whose route
+// has an {id}. It must be installed with Router.Use so the route variables
+// are known.
+func (t *articlesHttpTransport) articleLoaderMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if id, ok := mux.Vars(r)["id"]; ok {
+			r = r.WithContext(t.withArticleLoader(r.Context(), id))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// withArticleLoader returns a copy of ctx carrying a fresh loader for the
+// article id.
+func (t *articlesHttpTransport) withArticleLoader(ctx context.Context, id string) context.Context {
+	loader := &articleLoader{id: id, load: func() (*Article, error) {
+		return t.svc.Article(ctx, id)
+	}}
+	return context.WithValue(ctx, articleLoaderKey{}, loader)
+}
+
+// requestedArticle returns the article id through the request's loader when
+// it has one for id, and straight from the service otherwise. Only reads
+// should use it: an article loaded before a write is stale after it.
+func (t *articlesHttpTransport) requestedArticle(r *http.Request, id string) (*Article, error) {
+	return t.loadedArticle(r.Context(), id)
+}
+
+// loadedArticle is requestedArticle for code that only has the context.
+func (t *articlesHttpTransport) loadedArticle(ctx context.Context, id string) (*Article, error) {
+	if loader, ok := ctx.Value(articleLoaderKey{}).(*articleLoader); ok && loader.id == id {
+		return loader.get()
+	}
+	return t.svc.Article(ctx, id)
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+func TestArticleLoaderSharesOneRepoCall(t *testing.T) {
+	repo := newCountingRepo()
+	svc := newArticleSvc(repo)
+	mustAdd(t, svc, Article{ID: "a1", Title: "A"})
+	transport := newArticlesHttpTransport(svc)
+
+	// Two middlewares and the handler all want the article.
+	var errs []error
+	need := func(r *http.Request) {
+		_, err := transport.requestedArticle(r, mux.Vars(r)["id"])
+		errs = append(errs, err)
+	}
+	needing := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			need(r)
+			next.ServeHTTP(w, r)
+		})
+	}
+	r := mux.NewRouter()
+	r.Use(transport.articleLoaderMiddleware, needing, needing)
+	r.HandleFunc("/articles/{id}", func(w http.ResponseWriter, r *http.Request) { need(r) })
+
+	tests := []struct {
+		id      string
+		wantErr error
+	}{
+		{"a1", nil},
+		{"missing", ErrArticleNotFound},
+	}
+	for _, tt := range tests {
+		errs = nil
+		before := repo.byID.Load()
+		doRequest(r, "GET", "/articles/"+tt.id, "")
+		if n := repo.byID.Load() - before; n != 1 {
+			t.Errorf("%s: three consumers hit the repo %d times, want 1", tt.id, n)
+		}
+		if len(errs) != 3 {
+			t.Fatalf("%s: %d consumers ran, want 3", tt.id, len(errs))
+		}
+		for i, err := range errs {
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("%s: consumer %d got %v, want %v", tt.id, i, err, tt.wantErr)
+			}
+		}
+	}
+}
+
+func TestPatchLoadsArticleOnce(t *testing.T) {
+	repo := newCountingRepo()
+	svc := newArticleSvc(repo)
+	mustAdd(t, svc, Article{ID: "a1", Title: "A"}, Article{ID: "a2", Title: "B"})
+	h := newTestHandler(svc)
+	mergePatch := []string{"Content-Type", mergePatchContentType}
+
+	// Patches that change nothing are not written, so the lookup that
+	// checks access and feeds the patch is the only repo call.
+	tests := []struct {
+		method, target, body string
+		want                 int64
+	}{
+		{"PATCH", "/articles/a1", `{"title":"A"}`, 1},
+		{"PATCH", "/articles/batch", `[{"id":"a1","patch":{"title":"A"}},{"id":"a2","patch":{}}]`, 2},
+	}
+	for _, tt := range tests {
+		before := repo.byID.Load()
+		if rec := doRequest(h, tt.method, tt.target, tt.body, mergePatch...); rec.Code != http.StatusOK {
+			t.Fatalf("%s %s: status = %d", tt.method, tt.target, rec.Code)
+		}
+		if n := repo.byID.Load() - before; n != tt.want {
+			t.Errorf("%s %s: repo hit %d times, want %d", tt.method, tt.target, n, tt.want)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+)
+
+const (
+	scopeAdmin = "admin"
+	scopeWrite = "write"
+)
+
+// apiKey is one entry of the -api-keys file, granting a named principal a
+// set of scopes.
+type apiKey struct {
+	Name   string   `json:"name"`
+	Key    string   `json:"key"`
+	Scopes []string `json:"scopes"`
+}
+
+// principal is the authenticated caller of a request.
+type principal struct {
+	Name   string
+	Scopes map[string]bool
+}
+
+func (p *principal) hasScope(scope string) bool {
+	return p != nil && p.Scopes[scope]
+}
+
+type ctxKey int
+
+const principalCtxKey ctxKey = iota
+
+func principalFromContext(ctx context.Context) *principal {
+	p, _ := ctx.Value(principalCtxKey).(*principal)
+	return p
+}
+
+// authenticator resolves API keys to principals. Keys are indexed by their
+// SHA-256 digest so lookups don't compare secrets byte by byte.
+type authenticator struct {
+	keys map[[sha256.Size]byte]*principal
+}
+
+// loadAPIKeys reads a JSON array of apiKey entries. An empty path yields an
+// authenticator that knows no keys.
+func loadAPIKeys(path string) (*authenticator, error) {
+	auth := &authenticator{keys: make(map[[sha256.Size]byte]*principal)}
+	if path == "" {
+		return auth, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []apiKey
+	if err := json.Unmarshal(data, &keys); err != nil {
+		return nil, err
+	}
+
+	for _, key := range keys {
+		if key.Key == "" || key.Name == "" {
+			return nil, errors.New("api keys need a name and a key")
+		}
+		p := &principal{Name: key.Name, Scopes: make(map[string]bool, len(key.Scopes))}
+		for _, scope := range key.Scopes {
+			p.Scopes[scope] = true
+		}
+		auth.keys[sha256.Sum256([]byte(key.Key))] = p
+	}
+	return auth, nil
+}
+
+// enabled reports whether any API keys are configured.
+func (a *authenticator) enabled() bool {
+	return len(a.keys) > 0
+}
+
+// canSeeDrafts reports whether the caller may read draft articles.
+func canSeeDrafts(r *http.Request) bool {
+	p := principalFromContext(r.Context())
+	return p.hasScope(scopeWrite) || p.hasScope(scopeAdmin)
+}
+
+// showsDrafts reports whether the caller of r is shown drafts at all.
+func (t *articlesHttpTransport) showsDrafts(r *http.Request) bool {
+	return !t.hideDrafts || canSeeDrafts(r)
+}
+
+// hidesDraft reports whether article is a draft that must look missing to
+// the caller of r, because drafts are hidden and the caller can't see them.
+func (t *articlesHttpTransport) hidesDraft(r *http.Request, article Article) bool {
+	return article.Status == StatusDraft && !t.showsDrafts(r)
+}
+
+// withoutDrafts drops the drafts from articles.
+func withoutDrafts(articles []Article) []Article {
+	visible := make([]Article, 0, len(articles))
+	for _, article := range articles {
+		if article.Status != StatusDraft {
+			visible = append(visible, article)
+		}
+	}
+	return visible
+}
+
+// bearerToken extracts the API key from either "Authorization: Bearer <key>"
+// or the X-API-Key header.
+func bearerToken(r *http.Request) string {
+	if h := r.Header.Get("Authorization"); len(h) > len("Bearer ") && strings.EqualFold(h[:len("Bearer ")], "Bearer ") {
+		return strings.TrimSpace(h[len("Bearer "):])
+	}
+	return r.Header.Get("X-API-Key")
+}
+
+// middleware attaches the principal of a valid API key to the request
+// context. Requests without valid credentials pass through anonymously;
+// requireScope decides whether a route needs them.
+func (a *authenticator) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if token := bearerToken(r); token != "" {
+			if p, ok := a.keys[sha256.Sum256([]byte(token))]; ok {
+				r = r.WithContext(context.WithValue(r.Context(), principalCtxKey, p))
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// requireScope rejects requests whose principal lacks scope, with 401 for
+// anonymous callers and 403 for authenticated ones. The admin scope
+// satisfies every other scope.
+func requireScope(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			p := principalFromContext(r.Context())
+			if p == nil {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				w.WriteHeader(http.StatusUnauthorized)
+				io.WriteString(w, "unauthorized")
+				return
+			}
+			if !p.hasScope(scope) && !p.hasScope(scopeAdmin) {
+				w.WriteHeader(http.StatusForbidden)
+				io.WriteString(w, "forbidden")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"net"
+	"net/http"
+	"syscall"
+)
+
+// ErrBackendUnavailable is wrapped by repos whose backing store can't be
+// reached, such as a database that is down or restarting.
+var ErrBackendUnavailable = errors.New("backend unavailable")
+
+// unavailableMessage is the body sent instead of a backend-unavailable
+// error, whose text can name hosts, DSNs or SQL.
+const unavailableMessage = "service temporarily unavailable, try again later"
+
+// backendUnavailable reports whether err means the backing store couldn't
+// be reached, as opposed to it rejecting the request: ErrBackendUnavailable,
+// the database/sql broken connection errors, and refused, reset or failed
+// network connections.
+func backendUnavailable(err error) bool {
+	var opErr *net.OpError
+	switch {
+	case errors.Is(err, ErrBackendUnavailable),
+		errors.Is(err, driver.ErrBadConn),
+		errors.Is(err, sql.ErrConnDone),
+		errors.Is(err, syscall.ECONNREFUSED),
+		errors.Is(err, syscall.ECONNRESET),
+		errors.As(err, &opErr):
+		return true
+	}
+	return false
+}
+
+// serverErrorStatus is the status for an error no more specific status
+// fits: 503 when the backend is unavailable, so clients retry, and 500
+// otherwise.
+func serverErrorStatus(err error) int {
+	if backendUnavailable(err) {
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusInternalServerError
+}
+
+// errorBody is the text of err to put in a response. Backend-unavailable
+// errors are replaced by a generic message; the caller logs the original.
+func errorBody(err error) string {
+	if backendUnavailable(err) {
+		return unavailableMessage
+	}
+	return err.Error()
+}
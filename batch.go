@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	jsonpatch "github.com/evanphx/json-patch/v5"
+)
+
+const (
+	// maxBatchSize caps how many updates one batch request may carry.
+	maxBatchSize = 100
+	// batchItemTimeout bounds each update within a batch, so a slow item
+	// cannot eat the time budget of the ones after it.
+	batchItemTimeout = 5 * time.Second
+)
+
+// batchPatchItem is one entry of a PATCH /articles/batch request: the id of
+// the article and a JSON Merge Patch (RFC 7386) to apply to it.
+type batchPatchItem struct {
+	ID    string          `json:"id"`
+	Patch json.RawMessage `json:"patch"`
+}
+
+// batchPatchResult reports the outcome of one batch entry. Status carries
+// the code the same update would have got from PATCH /articles/{id}.
+type batchPatchResult struct {
+	ID     string `json:"id"`
+	OK     bool   `json:"ok"`
+	Status int    `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// batchPatchArticles applies a merge patch to each listed article in turn.
+// Every entry is applied independently: a failure is reported in its result
+// and does not stop the entries after it. An ID listed more than once is
+// ambiguous, so every entry carrying it fails with 400 and none is applied.
+func (t *articlesHttpTransport) batchPatchArticles(w http.ResponseWriter, r *http.Request) {
+	var items []batchPatchItem
+	if err := json.NewDecoder(r.Body).Decode(&items); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+
+	if len(items) == 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		io.WriteString(w, "batch is empty")
+		return
+	}
+	if len(items) > maxBatchSize {
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
+		fmt.Fprintf(w, "batch exceeds %d items", maxBatchSize)
+		return
+	}
+
+	occurrences := make(map[string]int, len(items))
+	for _, item := range items {
+		occurrences[item.ID]++
+	}
+
+	results := make([]batchPatchResult, 0, len(items))
+	for _, item := range items {
+		if item.ID != "" && occurrences[item.ID] > 1 {
+			results = append(results, batchPatchResult{
+				ID:     item.ID,
+				Status: http.StatusBadRequest,
+				Error:  errDuplicateBatchID.Error(),
+			})
+			continue
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), batchItemTimeout)
+		err := t.mergePatchArticle(t.withArticleLoader(ctx, item.ID), item.ID, item.Patch)
+		cancel()
+
+		result := batchPatchResult{ID: item.ID, OK: err == nil, Status: http.StatusOK}
+		if err != nil {
+			log.Println(err)
+			result.Status = batchErrorStatus(err)
+			result.Error = errorBody(err)
+		}
+		results = append(results, result)
+	}
+
+	t.writeJSON(w, r, http.StatusOK, results)
+}
+
+var (
+	errBadPatch         = errors.New("invalid merge patch")
+	errDuplicateBatchID = errors.New("id appears more than once in the batch")
+)
+
+// mergePatchArticle applies a JSON Merge Patch to the stored article and
+// saves the result. The article id can't be changed by the patch. The
+// current article is read through ctx's loader when it has one.
+func (t *articlesHttpTransport) mergePatchArticle(ctx context.Context, id string, patch []byte) error {
+	if id == "" {
+		return &ValidationError{Fields: []FieldError{{Field: "id", Message: "is required"}}}
+	}
+	if len(patch) == 0 {
+		return fmt.Errorf("%w: patch is required", errBadPatch)
+	}
+
+	current, err := t.loadedArticle(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	original, err := json.Marshal(current)
+	if err != nil {
+		return err
+	}
+
+	patched, err := jsonpatch.MergePatch(original, patch)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errBadPatch, err)
+	}
+	if jsonpatch.Equal(original, patched) {
+		return nil
+	}
+
+	var article Article
+	if err := json.Unmarshal(patched, &article); err != nil {
+		return fmt.Errorf("%w: %v", errBadPatch, err)
+	}
+	article.ID = id
+
+	return t.svc.UpdateArticle(ctx, article)
+}
+
+// batchErrorStatus maps an item error to the status PATCH /articles/{id}
+// would have answered with.
+func batchErrorStatus(err error) int {
+	var (
+		verr *ValidationError
+		terr *TransformError
+	)
+	switch {
+	case errors.Is(err, ErrArticleNotFound):
+		return http.StatusNotFound
+	case errors.As(err, &verr), errors.As(err, &terr):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, ErrTooManyPinned):
+		return http.StatusConflict
+	case errors.Is(err, ErrArticleLocked):
+		return http.StatusLocked
+	case errors.Is(err, errBadPatch):
+		return http.StatusBadRequest
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout
+	default:
+		return serverErrorStatus(err)
+	}
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestBatchPatchArticles(t *testing.T) {
+	svc := newTestSvc()
+	mustAdd(t, svc,
+		Article{ID: "a1", Title: "One", Tags: []string{"old"}},
+		Article{ID: "a2", Title: "Two"},
+		Article{ID: "a3", Title: "Three"},
+		Article{ID: "a4", Title: "Four"},
+	)
+	h := newTestHandler(svc)
+
+	body := `[
+		{"id": "a1", "patch": {"tags": ["news"]}},
+		{"id": "missing", "patch": {"title": "Nope"}},
+		{"id": "a2", "patch": {"title": "Two, edited"}},
+		{"id": "a3", "patch": {"title": ""}},
+		{"id": "", "patch": {"title": "No ID"}},
+		{"id": "a4", "patch": "not an object"}
+	]`
+
+	rec := doRequest(h, "PATCH", "/articles/batch", body)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+	}
+	var results []batchPatchResult
+	decodeJSON(t, rec, &results)
+
+	want := []batchPatchResult{
+		{ID: "a1", OK: true, Status: http.StatusOK},
+		{ID: "missing", Status: http.StatusNotFound, Error: "article not found"},
+		{ID: "a2", OK: true, Status: http.StatusOK},
+		{ID: "a3", Status: http.StatusUnprocessableEntity, Error: "invalid article: title: is required"},
+		{ID: "", Status: http.StatusUnprocessableEntity, Error: "invalid article: id: is required"},
+		{ID: "a4", Status: http.StatusBadRequest},
+	}
+	if len(results) != len(want) {
+		t.Fatalf("got %d results, want %d: %+v", len(results), len(want), results)
+	}
+	for i, got := range results {
+		w := want[i]
+		if got.ID != w.ID || got.OK != w.OK || got.Status != w.Status {
+			t.Errorf("result %d = %+v, want %+v", i, got, w)
+		}
+		if w.Error != "" && got.Error != w.Error {
+			t.Errorf("result %d error = %q, want %q", i, got.Error, w.Error)
+		}
+		if !w.OK && got.Error == "" {
+			t.Errorf("result %d has no error message", i)
+		}
+	}
+
+	if got := mustGet(t, svc, "a1"); !slices.Equal(got.Tags, []string{"news"}) || got.Title != "One" {
+		t.Errorf("a1 = %+v, want tags [news] and the title kept", got)
+	}
+	if got := mustGet(t, svc, "a2"); got.Title != "Two, edited" {
+		t.Errorf("a2 title = %q, want the patched one", got.Title)
+	}
+	for id, title := range map[string]string{"a3": "Three", "a4": "Four"} {
+		if got := mustGet(t, svc, id); got.Title != title {
+			t.Errorf("%s title = %q, want it unchanged", id, got.Title)
+		}
+	}
+	if ids := storedIDs(t, svc.repo); !slices.Equal(ids, []string{"a1", "a2", "a3", "a4"}) {
+		t.Errorf("stored %v", ids)
+	}
+}
+
+func TestBatchPatchArticlesRejects(t *testing.T) {
+	oversized := make([]string, maxBatchSize+1)
+	for i := range oversized {
+		oversized[i] = fmt.Sprintf(`{"id":"a%d","patch":{}}`, i)
+	}
+
+	tests := []struct {
+		name     string
+		body     string
+		wantCode int
+	}{
+		{"empty batch", `[]`, http.StatusBadRequest},
+		{"not an array", `{"id":"a1"}`, http.StatusBadRequest},
+		{"empty body", ``, http.StatusBadRequest},
+		{"too many items", "[" + strings.Join(oversized, ",") + "]", http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := newTestSvc()
+			mustAdd(t, svc, Article{ID: "a1", Title: "One"})
+
+			rec := doRequest(newTestHandler(svc), "PATCH", "/articles/batch", tt.body)
+			if rec.Code != tt.wantCode {
+				t.Errorf("status = %d, want %d: %s", rec.Code, tt.wantCode, rec.Body)
+			}
+		})
+	}
+}
+
+func TestBatchPatchDuplicateIDs(t *testing.T) {
+	svc := newTestSvc()
+	mustAdd(t, svc, Article{ID: "a1", Title: "One"}, Article{ID: "a2", Title: "Two"})
+	h := newTestHandler(svc)
+
+	rec := doRequest(h, "PATCH", "/articles/batch", `[
+		{"id": "a1", "patch": {"title": "First"}},
+		{"id": "a2", "patch": {"title": "Two, edited"}},
+		{"id": "a1", "patch": {"title": "Second"}},
+		{"id": "missing", "patch": {}},
+		{"id": "missing", "patch": {}}
+	]`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+	}
+	var results []batchPatchResult
+	decodeJSON(t, rec, &results)
+
+	want := []batchPatchResult{
+		{ID: "a1", Status: http.StatusBadRequest, Error: errDuplicateBatchID.Error()},
+		{ID: "a2", OK: true, Status: http.StatusOK},
+		{ID: "a1", Status: http.StatusBadRequest, Error: errDuplicateBatchID.Error()},
+		{ID: "missing", Status: http.StatusBadRequest, Error: errDuplicateBatchID.Error()},
+		{ID: "missing", Status: http.StatusBadRequest, Error: errDuplicateBatchID.Error()},
+	}
+	if !slices.Equal(results, want) {
+		t.Errorf("results = %+v, want %+v", results, want)
+	}
+	if got := mustGet(t, svc, "a1"); got.Title != "One" {
+		t.Errorf("a1 title = %q, want neither duplicate applied", got.Title)
+	}
+	if got := mustGet(t, svc, "a2"); got.Title != "Two, edited" {
+		t.Errorf("a2 title = %q, want the patch applied", got.Title)
+	}
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// changeLog hands out the global, monotonic sequence numbers stamped onto
+// articles and remembers deleted IDs as tombstones. Writes go through it one
+// at a time, so sequence numbers become visible in order and a client that
+// has seen seq N has seen every change up to N.
+type changeLog struct {
+	mu         sync.Mutex
+	seq        int64
+	tombstones map[string]tombstone
+	// purged is the highest sequence number of a purged tombstone. Clients
+	// that last synced before it may have missed deletes.
+	purged int64
+	// clock stamps tombstones; it is the service's clock.
+	clock Clock
+}
+
+// tombstone remembers when an article was deleted.
+type tombstone struct {
+	seq int64
+	at  time.Time
+}
+
+// write runs fn with the next sequence number. The number is only used up,
+// and the tombstone for id only set or cleared, when fn succeeds.
+func (l *changeLog) write(id string, deleted bool, fn func(seq int64) error) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	seq := l.seq + 1
+	if err := fn(seq); err != nil {
+		return err
+	}
+	l.seq = seq
+	l.mark(id, seq, deleted)
+	return nil
+}
+
+// clear runs fn, which must remove every article and report the removed IDs,
+// and tombstones each of them with its own sequence number.
+func (l *changeLog) clear(fn func() ([]string, error)) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	ids, err := fn()
+	if err != nil {
+		return err
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		l.seq++
+		l.mark(id, l.seq, true)
+	}
+	return nil
+}
+
+// deleted reports how many IDs currently have a tombstone.
+func (l *changeLog) deleted() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.tombstones)
+}
+
+// mark records or clears the tombstone for id. l.mu must be held.
+func (l *changeLog) mark(id string, seq int64, deleted bool) {
+	if !deleted {
+		delete(l.tombstones, id)
+		return
+	}
+	if l.tombstones == nil {
+		l.tombstones = make(map[string]tombstone)
+	}
+	l.tombstones[id] = tombstone{seq: seq, at: l.clock.Now()}
+}
+
+// purge drops tombstones of deletes that happened before olderThan and
+// reports how many were dropped.
+func (l *changeLog) purge(olderThan time.Time) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	purged := 0
+	for id, ts := range l.tombstones {
+		if ts.at.Before(olderThan) {
+			delete(l.tombstones, id)
+			l.purged = max(l.purged, ts.seq)
+			purged++
+		}
+	}
+	return purged
+}
+
+// ErrChangesPurged is returned by Changes when delete markers the client
+// hasn't seen yet were already purged. The client must sync again from 0.
+var ErrChangesPurged = errors.New("changes were purged")
+
+// ArticleChange is one entry of the change feed: either the current state of
+// an article or, when Deleted is set, a marker that it was deleted.
+type ArticleChange struct {
+	Seq     int64    `json:"seq"`
+	ID      string   `json:"id"`
+	Deleted bool     `json:"deleted,omitempty"`
+	Article *Article `json:"article,omitempty"`
+}
+
+// Changes returns every article and delete marker with a sequence number
+// above since, ordered by sequence number, along with the current maximum
+// sequence number to pass as since next time. An article changed several
+// times appears once, with its latest state. Articles evicted by a capacity
+// limit are not reported as deleted. When delete markers above since have
+// been purged it returns ErrChangesPurged, unless since is 0: a full sync
+// needs no delete markers.
+func (svc *articleSvc) Changes(ctx context.Context, since int64) ([]ArticleChange, int64, error) {
+	svc.changes.mu.Lock()
+	defer svc.changes.mu.Unlock()
+
+	if since > 0 && since < svc.changes.purged {
+		return nil, 0, fmt.Errorf("%w: delete markers up to seq %d are gone", ErrChangesPurged, svc.changes.purged)
+	}
+
+	articles, err := svc.repo.AllArticles(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	changes := []ArticleChange{}
+	for i := range articles {
+		if articles[i].Seq > since {
+			changes = append(changes, ArticleChange{Seq: articles[i].Seq, ID: articles[i].ID, Article: &articles[i]})
+		}
+	}
+	for id, ts := range svc.changes.tombstones {
+		if ts.seq > since {
+			changes = append(changes, ArticleChange{Seq: ts.seq, ID: id, Deleted: true})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Seq < changes[j].Seq })
+	return changes, svc.changes.seq, nil
+}
+
+// PurgeDeleted permanently forgets the delete markers of articles deleted
+// before olderThan and reports how many were purged.
+func (svc *articleSvc) PurgeDeleted(_ context.Context, olderThan time.Time) (int, error) {
+	return svc.changes.purge(olderThan), nil
+}
+
+// purgeDeletedEvery runs PurgeDeleted every interval, purging delete markers
+// older than retention, until ctx is done.
+func purgeDeletedEvery(ctx context.Context, svc ArticlesService, interval, retention time.Duration, clock Clock) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			purged, err := svc.PurgeDeleted(ctx, clock.Now().Add(-retention))
+			if err != nil {
+				slog.Error("purging delete markers failed", "err", err)
+				continue
+			}
+			if purged > 0 {
+				slog.Info("purged delete markers", "count", purged, "retention", retention)
+			}
+		}
+	}
+}
+
+type changesResponse struct {
+	Changes []ArticleChange `json:"changes"`
+	Seq     int64           `json:"seq"`
+}
+
+func (t *articlesHttpTransport) articleChanges(w http.ResponseWriter, r *http.Request) {
+	var since int64
+	if raw := r.URL.Query().Get("since"); raw != "" {
+		var err error
+		since, err = strconv.ParseInt(raw, 10, 64)
+		if err != nil || since < 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			io.WriteString(w, "since must be a non-negative integer")
+			return
+		}
+	}
+
+	changes, seq, err := t.svc.Changes(r.Context(), since)
+	if errors.Is(err, ErrChangesPurged) {
+		w.WriteHeader(http.StatusGone)
+		io.WriteString(w, err.Error()+"; sync again with since=0")
+		return
+	}
+	if err != nil {
+		log.Println(err)
+		w.WriteHeader(serverErrorStatus(err))
+		io.WriteString(w, errorBody(err))
+		return
+	}
+
+	// Hidden drafts and restricted articles are left out, as if they
+	// didn't exist.
+	denied := t.deniedTags(r)
+	visible := changes[:0]
+	for _, change := range changes {
+		if change.Article == nil || (!t.hidesDraft(r, *change.Article) && !restricted(*change.Article, denied)) {
+			visible = append(visible, change)
+		}
+	}
+
+	t.writeJSON(w, r, http.StatusOK, changesResponse{Changes: visible, Seq: seq})
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"context"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// changeFeedClient syncs a local copy of the articles from GET
+// /articles/changes, the way a sync client would.
+type changeFeedClient struct {
+	h     http.Handler
+	seq   int64
+	local map[string]string // ID to title
+}
+
+// sync fetches the changes since the last sync, applies them and returns
+// them as "id" or "-id" for deletes.
+func (c *changeFeedClient) sync(t *testing.T) []string {
+	t.Helper()
+	rec := doRequest(c.h, "GET", "/articles/changes?since="+strconv.FormatInt(c.seq, 10), "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+	}
+	var resp changesResponse
+	decodeJSON(t, rec, &resp)
+
+	got := []string{}
+	last := c.seq
+	for _, change := range resp.Changes {
+		if change.Seq <= last {
+			t.Errorf("seq %d of %s is not above %d", change.Seq, change.ID, last)
+		}
+		last = change.Seq
+		if change.Deleted {
+			delete(c.local, change.ID)
+			got = append(got, "-"+change.ID)
+			continue
+		}
+		c.local[change.ID] = change.Article.Title
+		got = append(got, change.ID)
+	}
+	if resp.Seq < last {
+		t.Errorf("current seq %d is below the last change %d", resp.Seq, last)
+	}
+	c.seq = resp.Seq
+	return got
+}
+
+func TestArticleChangesIncremental(t *testing.T) {
+	svc := newTestSvc()
+	h := newTestHandler(svc)
+	client := &changeFeedClient{h: h, local: map[string]string{}}
+
+	put := func(target, body string, want int) {
+		t.Helper()
+		if rec := doRequest(h, "PUT", target, body); rec.Code != want {
+			t.Fatalf("PUT %s: status = %d, want %d: %s", target, rec.Code, want, rec.Body)
+		}
+	}
+	del := func(id string) {
+		t.Helper()
+		if rec := doRequest(h, "DELETE", "/articles/"+id, ""); rec.Code != http.StatusNoContent {
+			t.Fatalf("DELETE %s: status = %d: %s", id, rec.Code, rec.Body)
+		}
+	}
+
+	steps := []struct {
+		name   string
+		mutate func()
+		want   []string
+	}{
+		{"nothing yet", func() {}, []string{}},
+		{"creates", func() {
+			put("/articles", `{"id":"a","title":"A"}`, http.StatusCreated)
+			put("/articles", `{"id":"b","title":"B"}`, http.StatusCreated)
+			put("/articles", `{"id":"c","title":"C"}`, http.StatusCreated)
+		}, []string{"a", "b", "c"}},
+		{"no change since", func() {}, []string{}},
+		{"update", func() {
+			put("/articles/b", `{"title":"B2"}`, http.StatusOK)
+		}, []string{"b"}},
+		{"updated twice appears once", func() {
+			put("/articles/a", `{"title":"A2"}`, http.StatusOK)
+			put("/articles/c", `{"title":"C2"}`, http.StatusOK)
+			put("/articles/a", `{"title":"A3"}`, http.StatusOK)
+		}, []string{"c", "a"}},
+		{"failed writes change nothing", func() {
+			put("/articles", `{"id":"a","title":"Again"}`, http.StatusConflict)
+			put("/articles/b", `{"title":""}`, http.StatusUnprocessableEntity)
+		}, []string{}},
+		{"delete", func() { del("b") }, []string{"-b"}},
+		{"re-create after delete", func() {
+			put("/articles", `{"id":"b","title":"B3"}`, http.StatusCreated)
+		}, []string{"b"}},
+		{"delete then create another", func() {
+			del("c")
+			put("/articles", `{"id":"d","title":"D"}`, http.StatusCreated)
+		}, []string{"-c", "d"}},
+	}
+	for _, step := range steps {
+		step.mutate()
+		if got := client.sync(t); !slices.Equal(got, step.want) {
+			t.Errorf("%s: changes = %v, want %v", step.name, got, step.want)
+		}
+	}
+
+	want := map[string]string{"a": "A3", "b": "B3", "d": "D"}
+	if !maps.Equal(client.local, want) {
+		t.Errorf("synced copy = %v, want %v", client.local, want)
+	}
+
+	// A fresh client catches up from 0 in one go. The delete marker for c
+	// is included but has nothing to remove.
+	fresh := &changeFeedClient{h: h, local: map[string]string{}}
+	fresh.sync(t)
+	if !maps.Equal(fresh.local, want) {
+		t.Errorf("full sync = %v, want %v", fresh.local, want)
+	}
+}
+
+func TestArticleChangesAfterClear(t *testing.T) {
+	svc := newTestSvc()
+	mustAdd(t, svc, Article{ID: "a", Title: "A"}, Article{ID: "b", Title: "B"})
+	client := &changeFeedClient{h: newTestHandler(svc), local: map[string]string{}}
+	client.sync(t)
+
+	if _, err := svc.Clear(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := client.sync(t); !slices.Equal(got, []string{"-a", "-b"}) {
+		t.Errorf("changes after clear = %v, want [-a -b]", got)
+	}
+	if len(client.local) != 0 {
+		t.Errorf("synced copy = %v, want empty", client.local)
+	}
+}
+
+func TestArticleChangesBadSince(t *testing.T) {
+	h := newTestHandler(newTestSvc())
+	for _, since := range []string{"-1", "abc", "1.5"} {
+		if rec := doRequest(h, "GET", "/articles/changes?since="+since, ""); rec.Code != http.StatusBadRequest {
+			t.Errorf("since=%s: status = %d, want 400", since, rec.Code)
+		}
+	}
+}
+
+// newPurgeSvc deletes old at the clock's start and recent two hours later,
+// then moves the clock on another hour, leaving a tombstone for each.
+func newPurgeSvc(t *testing.T) (*articleSvc, *fakeClock) {
+	t.Helper()
+	clock := newFakeClock(time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC))
+	svc := newTestSvc(withClock(clock))
+	mustAdd(t, svc, Article{ID: "old", Title: "Old"}, Article{ID: "recent", Title: "Recent"}, Article{ID: "kept", Title: "Kept"})
+
+	ctx := context.Background()
+	if err := svc.DeleteArticle(ctx, "old"); err != nil {
+		t.Fatal(err)
+	}
+	clock.Advance(2 * time.Hour)
+	if err := svc.DeleteArticle(ctx, "recent"); err != nil {
+		t.Fatal(err)
+	}
+	clock.Advance(time.Hour)
+	return svc, clock
+}
+
+func TestPurgeDeleted(t *testing.T) {
+	svc, clock := newPurgeSvc(t)
+
+	purged, err := svc.PurgeDeleted(context.Background(), clock.Now().Add(-90*time.Minute))
+	if err != nil || purged != 1 {
+		t.Fatalf("PurgeDeleted = %d, %v; want 1 purged", purged, err)
+	}
+
+	h := newTestHandler(svc)
+	client := &changeFeedClient{h: h, local: map[string]string{}}
+	if got := client.sync(t); !slices.Equal(got, []string{"kept", "-recent"}) {
+		t.Errorf("full sync after the purge = %v, want [kept -recent]", got)
+	}
+	// A client that synced before the purged delete may have missed it.
+	if rec := doRequest(h, "GET", "/articles/changes?since=1", ""); rec.Code != http.StatusGone {
+		t.Errorf("sync from before the purge: status = %d, want 410", rec.Code)
+	}
+
+	if purged, _ := svc.PurgeDeleted(context.Background(), clock.Now().Add(-90*time.Minute)); purged != 0 {
+		t.Errorf("purging again removed %d markers, want 0", purged)
+	}
+}
+
+func TestPurgeDeletedEvery(t *testing.T) {
+	svc, clock := newPurgeSvc(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		purgeDeletedEvery(ctx, svc, time.Millisecond, 90*time.Minute, clock)
+	}()
+
+	waitFor(t, "the old delete marker to be purged", func() bool { return svc.changes.deleted() == 1 })
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the janitor kept running after its context was cancelled")
+	}
+	if n := svc.changes.deleted(); n != 1 {
+		t.Errorf("%d delete markers left, want the recent one", n)
+	}
+}
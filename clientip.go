@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// trustedProxies lists the networks of reverse proxies whose
+// X-Forwarded-For headers are believed.
+type trustedProxies []netip.Prefix
+
+// parseTrustedProxies parses CIDRs such as 10.0.0.0/8; a bare address
+// stands for itself alone.
+func parseTrustedProxies(entries []string) (trustedProxies, error) {
+	proxies := make(trustedProxies, 0, len(entries))
+	for _, entry := range entries {
+		if !strings.Contains(entry, "/") {
+			addr, err := netip.ParseAddr(entry)
+			if err != nil {
+				return nil, fmt.Errorf("-trusted-proxies: %w", err)
+			}
+			proxies = append(proxies, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(entry)
+		if err != nil {
+			return nil, fmt.Errorf("-trusted-proxies: %w", err)
+		}
+		proxies = append(proxies, prefix.Masked())
+	}
+	return proxies, nil
+}
+
+func (p trustedProxies) trusts(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	for _, prefix := range p {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// resolve returns the client address of r. X-Forwarded-For is only read
+// when the direct peer is a trusted proxy, and then from the right: the
+// first hop that isn't a trusted proxy is the client, since everything to
+// its left could have been made up by the client itself.
+func (p trustedProxies) resolve(r *http.Request) string {
+	peer := remoteHost(r.RemoteAddr)
+	addr, err := netip.ParseAddr(peer)
+	if err != nil || !p.trusts(addr) {
+		return peer
+	}
+
+	hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	client := peer
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil {
+			// A malformed hop can't be attributed to anyone further left.
+			break
+		}
+		client = hop.Unmap().String()
+		if !p.trusts(hop) {
+			break
+		}
+	}
+	return client
+}
+
+// remoteHost strips the port from a RemoteAddr.
+func remoteHost(remoteAddr string) string {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		return remoteAddr
+	}
+	return host
+}
+
+type clientIPKey struct{}
+
+// clientIPMiddleware resolves the client address of each request once, for
+// clientIP to return.
+func clientIPMiddleware(proxies trustedProxies, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), clientIPKey{}, proxies.resolve(r))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// clientIP returns the client address of r as resolved by
+// clientIPMiddleware, or the direct peer when it didn't run.
+func clientIP(r *http.Request) string {
+	if ip, ok := r.Context().Value(clientIPKey{}).(string); ok {
+		return ip
+	}
+	return remoteHost(r.RemoteAddr)
+}
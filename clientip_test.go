@@ -0,0 +1,84 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	proxies, err := parseTrustedProxies([]string{"10.0.0.0/8", "192.0.2.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		headers    []string
+		want       string
+	}{
+		{"direct client", "203.0.113.7:1234", nil, "203.0.113.7"},
+		{"untrusted peer spoofing X-Forwarded-For", "203.0.113.7:1234", []string{"X-Forwarded-For", "198.51.100.1"}, "203.0.113.7"},
+		{"untrusted peer spoofing X-Real-IP", "203.0.113.7:1234", []string{"X-Real-IP", "198.51.100.1"}, "203.0.113.7"},
+		{"trusted proxy", "10.1.2.3:80", []string{"X-Forwarded-For", "198.51.100.1"}, "198.51.100.1"},
+		{"trusted single address", "192.0.2.1:80", []string{"X-Forwarded-For", "198.51.100.1"}, "198.51.100.1"},
+		{"trusted proxy ignores X-Real-IP", "10.1.2.3:80", []string{"X-Real-IP", "198.51.100.1"}, "10.1.2.3"},
+		// The client prepended a hop; the proxy appended the real one.
+		{"client-supplied hops are skipped", "10.1.2.3:80", []string{"X-Forwarded-For", "1.2.3.4, 198.51.100.1"}, "198.51.100.1"},
+		{"chain of trusted proxies", "10.1.2.3:80", []string{"X-Forwarded-For", "198.51.100.1, 10.9.9.9"}, "198.51.100.1"},
+		{"malformed hop", "10.1.2.3:80", []string{"X-Forwarded-For", "198.51.100.1, bogus"}, "10.1.2.3"},
+		{"IPv4-mapped peer", "[::ffff:10.1.2.3]:80", []string{"X-Forwarded-For", "198.51.100.1"}, "198.51.100.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			for i := 0; i+1 < len(tt.headers); i += 2 {
+				req.Header.Set(tt.headers[i], tt.headers[i+1])
+			}
+			var got string
+			clientIPMiddleware(proxies, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = clientIP(r)
+			})).ServeHTTP(httptest.NewRecorder(), req)
+			if got != tt.want {
+				t.Errorf("clientIP = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClientIPInAuditLog(t *testing.T) {
+	proxies, err := parseTrustedProxies([]string{"10.0.0.0/8"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	svc := newTestSvc()
+	mustAdd(t, svc, Article{ID: "a1", Title: "A"})
+	s := newAdminTestServer(svc, true, nil)
+	h := clientIPMiddleware(proxies, s)
+
+	tests := []struct {
+		remoteAddr, forwardedFor, want string
+	}{
+		{"203.0.113.7:1234", "198.51.100.1", "from 203.0.113.7"},
+		{"10.1.2.3:80", "198.51.100.1", "from 198.51.100.1"},
+	}
+	for _, tt := range tests {
+		logs := captureLog(t)
+		req := httptest.NewRequest("POST", "/articles/a1/lock", nil)
+		req.RemoteAddr = tt.remoteAddr
+		req.Header.Set("X-Forwarded-For", tt.forwardedFor)
+		req.Header.Set("X-API-Key", testAdminKey)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("lock: status = %d", rec.Code)
+		}
+		if !strings.Contains(logs.String(), tt.want) {
+			t.Errorf("peer %s: audit log %q does not name the client %q", tt.remoteAddr, logs, tt.want)
+		}
+		doRequest(s, "POST", "/articles/a1/unlock", "", "X-API-Key", testAdminKey)
+	}
+}
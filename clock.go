@@ -0,0 +1,16 @@
+package main
+
+import "time"
+
+// Clock tells the current time. Everything that decides by the time of day,
+// such as publishing, scheduling, change feed retention, view windows and
+// cache expiry, reads it from one shared Clock, so the whole server can be
+// moved in time at once. Elapsed-time measurements keep using time.Now.
+type Clock interface {
+	Now() time.Time
+}
+
+// realClock is the wall clock.
+type realClock struct{}
+
+func (realClock) Now() time.Time { return time.Now() }
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock is a Clock for tests that only moves when told to.
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func newFakeClock(now time.Time) *fakeClock {
+	return &fakeClock{now: now}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Advance moves the clock forward by d.
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func TestScheduled(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		publishAt time.Time
+		want      bool
+	}{
+		{"past", now.Add(-time.Hour), false},
+		{"now", now, false},
+		{"future", now.Add(time.Second), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := newTestSvc(withClock(newFakeClock(now)))
+			mustAdd(t, svc, Article{ID: "a1", Title: "A", PublishAt: tt.publishAt})
+			h := newTestHandler(svc)
+
+			rec := doRequest(h, "GET", "/articles/a1", "")
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d", rec.Code)
+			}
+			if got := decodeArticle(t, rec).Scheduled; got != tt.want {
+				t.Errorf("GET: scheduled = %v, want %v", got, tt.want)
+			}
+
+			var listed []Article
+			decodeJSON(t, doRequest(h, "GET", "/articles", ""), &listed)
+			if len(listed) != 1 || listed[0].Scheduled != tt.want {
+				t.Errorf("listing: %+v, want one article with scheduled = %v", listed, tt.want)
+			}
+		})
+	}
+}
+
+// TestScheduledClearsAsTimePasses moves the service's clock past an
+// article's PublishAt and expects the flag, and with it the ETag, to change
+// without any write.
+func TestScheduledClearsAsTimePasses(t *testing.T) {
+	clock := newFakeClock(time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC))
+	svc := newTestSvc(withClock(clock))
+	mustAdd(t, svc, Article{ID: "a1", Title: "A", PublishAt: clock.Now().Add(time.Hour)})
+
+	before := mustGet(t, svc, "a1")
+	if !before.Scheduled {
+		t.Fatal("an article due in an hour is not scheduled")
+	}
+
+	clock.Advance(time.Hour)
+	after := mustGet(t, svc, "a1")
+	if after.Scheduled {
+		t.Error("still scheduled once PublishAt has come")
+	}
+	if after.ETag == before.ETag {
+		t.Error("ETag unchanged when the scheduled flag cleared")
+	}
+	if after.ModifiedAt != before.ModifiedAt {
+		t.Errorf("ModifiedAt moved from %v to %v without a write", before.ModifiedAt, after.ModifiedAt)
+	}
+}
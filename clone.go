@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// newArticleID returns a random 16 character hex ID.
+func newArticleID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// defaultIDRetries is how many times a taken server-generated ID is
+// replaced before the create fails, unless -id-retries says otherwise.
+const defaultIDRetries = 3
+
+// withIDGenerator replaces newArticleID as the source of server-generated
+// IDs.
+func withIDGenerator(generate func() (string, error)) svcOption {
+	return func(svc *articleSvc) {
+		svc.generateID = generate
+	}
+}
+
+// withIDRetries sets how many times a generated ID that is already taken is
+// replaced by a new one before the create fails.
+func withIDRetries(retries int) svcOption {
+	return func(svc *articleSvc) {
+		svc.idRetries = retries
+	}
+}
+
+// errIDCollisions is returned when every generated ID was already taken.
+var errIDCollisions = errors.New("generated article IDs kept colliding")
+
+// addWithGeneratedID adds article under a freshly generated ID and returns
+// that ID. A collision with an existing article is retried with a new ID up
+// to idRetries times.
+func (svc *articleSvc) addWithGeneratedID(ctx context.Context, article Article) (string, error) {
+	for attempt := 0; ; attempt++ {
+		id, err := svc.generateID()
+		if err != nil {
+			return "", err
+		}
+		article.ID = id
+		_, err = svc.AddArticle(ctx, article)
+		if !errors.Is(err, ErrArticleExists) {
+			return id, err
+		}
+		if attempt >= svc.idRetries {
+			return "", fmt.Errorf("%w: %d attempts", errIDCollisions, attempt+1)
+		}
+		log.Printf("generated article ID %s is taken, retrying", id)
+	}
+}
+
+// CloneArticle copies the article id into a new, unpinned draft with a fresh
+// ID, " (copy)" appended to its title and no PublishAt. The copy shares no
+// slices with the original.
+func (svc *articleSvc) CloneArticle(ctx context.Context, id string) (*Article, error) {
+	source, err := svc.repo.ArticleByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	clone := Article{
+		Title:       source.Title + " (copy)",
+		Tags:        append([]string(nil), source.Tags...),
+		Content:     source.Content,
+		Excerpt:     source.Excerpt,
+		PublishAt:   time.Time{},
+		Attachments: append([]Attachment(nil), source.Attachments...),
+		Status:      StatusDraft,
+	}
+	cloneID, err := svc.addWithGeneratedID(ctx, clone)
+	if err != nil {
+		return nil, err
+	}
+	return svc.repo.ArticleByID(ctx, cloneID)
+}
+
+// cloneArticle answers 404 for a source the caller may not read, so a hidden
+// draft can't be copied into view, and refuses restricted sources the way
+// articleByID does.
+func (t *articlesHttpTransport) cloneArticle(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	source, err := t.requestedArticle(r, id)
+	if err == nil && t.hidesDraft(r, *source) {
+		err = ErrArticleNotFound
+	}
+	if err == nil && restricted(*source, t.deniedTags(r)) {
+		t.writeRestricted(w, id)
+		return
+	}
+	var clone *Article
+	if err == nil {
+		clone, err = t.svc.CloneArticle(r.Context(), id)
+	}
+	if err != nil {
+		log.Println(err)
+		var verr *ValidationError
+		switch {
+		case errors.Is(err, ErrArticleNotFound):
+			w.WriteHeader(http.StatusNotFound)
+		case errors.As(err, &verr):
+			writeValidationError(w, verr)
+			return
+		case errors.Is(err, ErrSlugConflict):
+			w.WriteHeader(http.StatusConflict)
+		default:
+			w.WriteHeader(serverErrorStatus(err))
+		}
+		io.WriteString(w, errorBody(err))
+		return
+	}
+
+	w.Header().Set("Location", "/articles/"+clone.ID)
+	t.writeJSON(w, r, http.StatusCreated, clone)
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCloneArticle(t *testing.T) {
+	svc := newTestSvc()
+	source := Article{
+		ID:          "src",
+		Title:       "Original",
+		Tags:        []string{"go", "web"},
+		Content:     "Body",
+		Excerpt:     "Curated",
+		PublishAt:   time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC),
+		Attachments: []Attachment{{URL: "https://cdn.example.com/a.png", MIMEType: "image/png", Size: 10, Alt: "a"}},
+		Pinned:      true,
+	}
+	mustAdd(t, svc, source)
+	h := testAuth(newTestHandler(svc), testKeys)
+
+	rec := doRequest(h, "POST", "/articles/src/clone", "", "X-API-Key", testWriteKey)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+	}
+	var clone Article
+	decodeJSON(t, rec, &clone)
+
+	if clone.ID == "" || clone.ID == source.ID {
+		t.Fatalf("clone ID = %q, want a fresh one", clone.ID)
+	}
+	if got, want := rec.Header().Get("Location"), "/articles/"+clone.ID; got != want {
+		t.Errorf("Location = %q, want %q", got, want)
+	}
+	if clone.Title != "Original (copy)" || clone.Status != StatusDraft || !clone.PublishAt.IsZero() || clone.Pinned {
+		t.Errorf("clone = %+v, want an unpinned draft titled \"Original (copy)\" without PublishAt", clone)
+	}
+	if clone.Content != source.Content || clone.Excerpt != source.Excerpt ||
+		!slices.Equal(clone.Tags, source.Tags) || !slices.Equal(clone.Attachments, source.Attachments) {
+		t.Errorf("clone content, excerpt, tags or attachments differ: %+v", clone)
+	}
+	if clone.Slug == "" || clone.Slug == mustGet(t, svc, "src").Slug {
+		t.Errorf("clone slug = %q, want its own", clone.Slug)
+	}
+
+	if rec := doRequest(h, "GET", "/articles/"+clone.ID, "", "X-API-Key", testWriteKey); rec.Code != http.StatusOK {
+		t.Errorf("GET the clone: status = %d", rec.Code)
+	}
+	if got := mustGet(t, svc, "src"); got.Title != "Original" || got.Status != StatusPublished || !got.Pinned {
+		t.Errorf("the source changed: %+v", got)
+	}
+}
+
+// TestCloneIsIndependent edits the source and the clone after cloning and
+// expects neither edit to show up in the other.
+func TestCloneIsIndependent(t *testing.T) {
+	svc := newTestSvc()
+	mustAdd(t, svc, Article{ID: "src", Title: "Original", Tags: []string{"go"}, Content: "Body"})
+
+	clone, err := svc.CloneArticle(context.Background(), "src")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	source := mustGet(t, svc, "src")
+	source.Tags = append(source.Tags, "added")
+	source.Content = "Edited source"
+	if err := svc.UpdateArticle(context.Background(), source); err != nil {
+		t.Fatal(err)
+	}
+
+	stored := mustGet(t, svc, clone.ID)
+	if !slices.Equal(stored.Tags, []string{"go"}) || stored.Content != "Body" {
+		t.Errorf("clone = %+v, want the tags and content at clone time", stored)
+	}
+
+	stored.Title = "Edited clone"
+	if err := svc.UpdateArticle(context.Background(), stored); err != nil {
+		t.Fatal(err)
+	}
+	if got := mustGet(t, svc, "src"); got.Title != "Original" || !slices.Equal(got.Tags, []string{"go", "added"}) {
+		t.Errorf("source = %+v after editing the clone", got)
+	}
+
+	second, err := svc.CloneArticle(context.Background(), clone.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.Title != "Edited clone (copy)" || second.ID == clone.ID {
+		t.Errorf("clone of the clone = %+v", second)
+	}
+}
+
+func TestCloneUnknownArticle(t *testing.T) {
+	svc := newTestSvc()
+	h := testAuth(newTestHandler(svc), testKeys)
+
+	rec := doRequest(h, "POST", "/articles/missing/clone", "", "X-API-Key", testWriteKey)
+	if rec.Code != http.StatusNotFound || !strings.Contains(rec.Body.String(), "not found") {
+		t.Errorf("got %d %q, want 404", rec.Code, rec.Body)
+	}
+	if ids := storedIDs(t, svc.repo); len(ids) != 0 {
+		t.Errorf("stored %v", ids)
+	}
+}
+
+func TestCloneInvalidArticle(t *testing.T) {
+	svc := newTestSvc(withTagVocabulary([]string{"go"}))
+	// Stored before the vocabulary dropped its tag, so a copy is invalid.
+	if err := svc.repo.InsertArticle(context.Background(), Article{ID: "src", Title: "Original", Tags: []string{"legacy"}}); err != nil {
+		t.Fatal(err)
+	}
+	h := testAuth(newTestHandler(svc), testKeys)
+
+	rec := doRequest(h, "POST", "/articles/src/clone", "", "X-API-Key", testWriteKey)
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("status = %d, want 422: %s", rec.Code, rec.Body)
+	}
+	var verr ValidationError
+	decodeJSON(t, rec, &verr)
+	if len(verr.Fields) != 1 || verr.Fields[0].Field != "tags" {
+		t.Errorf("field errors = %+v, want one for tags", verr.Fields)
+	}
+	if ids := storedIDs(t, svc.repo); !slices.Equal(ids, []string{"src"}) {
+		t.Errorf("stored %v, want only src", ids)
+	}
+}
+
+// sequenceIDs returns a generator handing out ids in order, and the number
+// of IDs it handed out so far.
+func sequenceIDs(ids ...string) (func() (string, error), *int) {
+	calls := 0
+	return func() (string, error) {
+		id := ids[min(calls, len(ids)-1)]
+		calls++
+		return id, nil
+	}, &calls
+}
+
+func TestCloneRetriesTakenIDs(t *testing.T) {
+	generate, calls := sequenceIDs("taken", "fresh")
+	svc := newTestSvc(withIDGenerator(generate))
+	mustAdd(t, svc, Article{ID: "src", Title: "Original"}, Article{ID: "taken", Title: "Taken"})
+	h := testAuth(newTestHandler(svc), testKeys)
+
+	rec := doRequest(h, "POST", "/articles/src/clone", "", "X-API-Key", testWriteKey)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+	}
+	var clone Article
+	decodeJSON(t, rec, &clone)
+	if clone.ID != "fresh" || *calls != 2 {
+		t.Errorf("clone ID = %q after %d generated IDs, want fresh after 2", clone.ID, *calls)
+	}
+	if got := mustGet(t, svc, "taken"); got.Title != "Taken" {
+		t.Errorf("the article holding the taken ID changed: %+v", got)
+	}
+}
+
+func TestCloneGivesUpOnTakenIDs(t *testing.T) {
+	generate, calls := sequenceIDs("taken")
+	svc := newTestSvc(withIDGenerator(generate), withIDRetries(2))
+	mustAdd(t, svc, Article{ID: "src", Title: "Original"}, Article{ID: "taken", Title: "Taken"})
+	h := testAuth(newTestHandler(svc), testKeys)
+
+	rec := doRequest(h, "POST", "/articles/src/clone", "", "X-API-Key", testWriteKey)
+	if rec.Code != http.StatusInternalServerError || !strings.Contains(rec.Body.String(), errIDCollisions.Error()) {
+		t.Errorf("got %d %q, want 500 reporting the collisions", rec.Code, rec.Body)
+	}
+	if *calls != 3 {
+		t.Errorf("generated %d IDs, want 3: the first and 2 retries", *calls)
+	}
+	if ids := storedIDs(t, svc.repo); !slices.Equal(ids, []string{"src", "taken"}) {
+		t.Errorf("stored %v, want only src and taken", ids)
+	}
+}
+
+func TestNewArticleSvcRetriesByDefault(t *testing.T) {
+	if got := newTestSvc().idRetries; got != defaultIDRetries {
+		t.Errorf("idRetries = %d, want %d", got, defaultIDRetries)
+	}
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// config holds the command-line configurable settings of the server.
+type config struct {
+	addr              string
+	requireHTTPS      bool
+	tlsCert           string
+	tlsKey            string
+	acmeDomain        string
+	acmeCacheDir      string
+	shutdownTimeout   time.Duration
+	timeouts          serverTimeouts
+	http2             bool
+	maxArticles       int
+	evictionPolicy    evictionPolicy
+	pretty            bool
+	repoShards        int
+	maxBodyBytes      int64
+	maxBatchBodyBytes int64
+	apiKeysFile       string
+	readOnly          bool
+	cursorSecret      string
+	previewSecret     string
+	previewTokenTTL   time.Duration
+	enableAdmin       bool
+
+	accessLog           string
+	accessLogMaxSizeMB  int
+	accessLogMaxAgeDays int
+	accessLogMaxBackups int
+
+	cors            corsPolicy
+	trustedProxies  []string
+	requestIDHeader string
+	staticDir       string
+	seedFile        string
+
+	logLevel           slog.Level
+	metrics            bool
+	serverTiming       bool
+	matchedRouteHeader bool
+
+	hideDrafts        bool
+	idempotentDeletes bool
+	listCacheTTL      time.Duration
+	envelope          bool
+	maxUnpaginated    int
+
+	purgeInterval      time.Duration
+	tombstoneRetention time.Duration
+
+	warnDuplicateTitles bool
+	defaultPublishAt    bool
+	maxPinned           int
+	maxContentLength    int
+	idRetries           int
+	contentTransformers []string
+	tagVocabulary       string
+	featureFlags        []string
+	tagScopes           []string
+	logContentChars     int
+	logOmitFields       []string
+	emptyListNoContent  bool
+	maxConcurrent       int
+	strictSlugs         bool
+	hideRestrictedTags  bool
+}
+
+func parseConfig() config {
+	var cfg config
+	flag.StringVar(&cfg.addr, "addr", ":8888", "address to listen on")
+	flag.BoolVar(&cfg.requireHTTPS, "require-https", false, "redirect plaintext GET requests to HTTPS and reject other plaintext requests")
+	flag.StringVar(&cfg.tlsCert, "tls-cert", "", "path to a PEM encoded TLS certificate; requires -tls-key")
+	flag.StringVar(&cfg.tlsKey, "tls-key", "", "path to a PEM encoded TLS private key; requires -tls-cert")
+	flag.StringVar(&cfg.acmeDomain, "acme-domain", "", "obtain TLS certificates automatically via ACME for this domain (-addr must be reachable on port 443)")
+	flag.StringVar(&cfg.acmeCacheDir, "acme-cache-dir", "acme-certs", "directory used to cache ACME certificates")
+	flag.DurationVar(&cfg.shutdownTimeout, "shutdown-timeout", 10*time.Second, "how long to wait for in-flight requests on shutdown")
+	flag.DurationVar(&cfg.timeouts.readHeader, "read-header-timeout", 5*time.Second, "how long a client may take to send the request headers")
+	flag.DurationVar(&cfg.timeouts.read, "read-timeout", 30*time.Second, "how long a client may take to send the whole request, body included")
+	flag.DurationVar(&cfg.timeouts.write, "write-timeout", 60*time.Second, "how long writing a response may take; event streams are exempt")
+	flag.DurationVar(&cfg.timeouts.idle, "idle-timeout", 120*time.Second, "how long an idle keep-alive connection is kept open")
+	flag.BoolVar(&cfg.http2, "http2", true, "offer HTTP/2 to TLS clients")
+	flag.IntVar(&cfg.maxArticles, "max-articles", 0, "maximum number of articles kept in memory; 0 means unbounded")
+	flag.BoolVar(&cfg.pretty, "pretty", false, "indent all JSON responses (intended for development)")
+	flag.IntVar(&cfg.repoShards, "repo-shards", 1, "number of independently locked partitions of the in-memory store; -max-articles is split evenly across them")
+	flag.BoolVar(&cfg.warnDuplicateTitles, "warn-duplicate-titles", false, "warn in the create response when another article already has the same title")
+	flag.Int64Var(&cfg.maxBodyBytes, "max-body-bytes", 1<<20, "maximum accepted request body size in bytes; 0 disables the limit")
+	flag.Int64Var(&cfg.maxBatchBodyBytes, "max-batch-body-bytes", 32<<20, "maximum request body size in bytes for PATCH /articles/batch and POST /articles/import; 0 disables the limit")
+	flag.BoolVar(&cfg.defaultPublishAt, "default-publish-at", true, "set publishAt to the current time when a new published article omits it")
+	flag.StringVar(&cfg.apiKeysFile, "api-keys", "", "path to a JSON file of API keys: [{\"name\": ..., \"key\": ..., \"scopes\": [...]}]")
+	flag.BoolVar(&cfg.readOnly, "read-only", false, "start in read-only mode; admins can toggle it via POST /admin/readonly")
+	flag.IntVar(&cfg.maxPinned, "max-pinned", 5, "maximum number of pinned articles; 0 means unlimited")
+	flag.StringVar(&cfg.previewSecret, "preview-secret", "", "secret used to sign draft preview tokens; a random one is generated when empty, invalidating tokens on restart")
+	flag.DurationVar(&cfg.previewTokenTTL, "preview-token-ttl", 24*time.Hour, "how long a preview token minted with POST /articles/{id}/preview-token lets its holder read the article")
+	flag.StringVar(&cfg.cursorSecret, "cursor-secret", "", "secret used to sign pagination cursors; a random one is generated when empty, invalidating cursors on restart")
+	flag.BoolVar(&cfg.enableAdmin, "enable-admin", false, "enable destructive admin endpoints such as DELETE /admin/articles")
+	flag.StringVar(&cfg.accessLog, "access-log", "", "file to write JSON access logs to, rotated by size and age; stdout when empty")
+	flag.IntVar(&cfg.accessLogMaxSizeMB, "access-log-max-size", 100, "size in megabytes at which the -access-log file is rotated")
+	flag.IntVar(&cfg.accessLogMaxAgeDays, "access-log-max-age", 28, "days to keep rotated access log files; 0 keeps them regardless of age")
+	flag.IntVar(&cfg.accessLogMaxBackups, "access-log-max-backups", 0, "number of rotated access log files to keep; 0 keeps all of them")
+	flag.IntVar(&cfg.maxContentLength, "max-content-length", 0, "maximum article content length in characters; 0 means unlimited")
+	corsOrigins := flag.String("cors-origins", "", "comma separated origins allowed to call the API from a browser, or *; CORS is off when empty")
+	corsExpose := flag.String("cors-expose-headers", "ETag,Location,X-Next-Cursor,X-Truncated,X-Total-Count", "comma separated response headers browsers may expose to scripts")
+	flag.DurationVar(&cfg.cors.maxAge, "cors-max-age", 10*time.Minute, "how long browsers may cache a CORS preflight result")
+	flag.BoolVar(&cfg.cors.credentials, "cors-allow-credentials", false, "allow credentialed cross-origin requests; requires explicit -cors-origins")
+	flag.TextVar(&cfg.logLevel, "log-level", slog.LevelInfo, "initial log level: debug, info, warn or error; SIGHUP toggles between info and debug")
+	flag.BoolVar(&cfg.metrics, "metrics", true, "collect Prometheus metrics for requests and repository calls and serve them on /metrics")
+	flag.BoolVar(&cfg.matchedRouteHeader, "matched-route-header", false, "name the route template that handled each request, such as /articles/{id}, in an X-Matched-Route response header")
+	flag.BoolVar(&cfg.serverTiming, "server-timing", false, "report time spent in the repository and in total in a Server-Timing response header")
+	flag.BoolVar(&cfg.hideDrafts, "hide-drafts", true, "answer GET /articles/{id} for a draft with 404 unless the caller has write or admin scope")
+	flag.BoolVar(&cfg.idempotentDeletes, "idempotent-delete", false, "answer DELETE /articles/{id} for an unknown article with 204 instead of 404")
+	transformers := flag.String("content-transformers", "", "comma separated content transformers applied, in order, to every created or updated article: autolink")
+	flag.DurationVar(&cfg.listCacheTTL, "list-cache-ttl", 0, "how long rendered GET /articles responses are reused; any write invalidates them; 0 disables the cache")
+	proxies := flag.String("trusted-proxies", "", "comma separated CIDRs or addresses of reverse proxies whose X-Forwarded-For header is used to find the client address")
+	flag.DurationVar(&cfg.purgeInterval, "purge-interval", time.Hour, "how often delete markers older than -tombstone-retention are purged from the change feed; 0 keeps them forever")
+	flag.DurationVar(&cfg.tombstoneRetention, "tombstone-retention", 7*24*time.Hour, "how long the change feed keeps delete markers; clients that last synced earlier must sync again from scratch")
+	flag.StringVar(&cfg.requestIDHeader, "request-id-header", "X-Request-ID", "header carrying the request correlation ID, which is generated when missing, echoed on the response, logged and attached to published events; empty disables request IDs")
+	flag.StringVar(&cfg.staticDir, "static-dir", "", "directory whose files are served under /static/, such as a frontend or images; off when empty")
+	flag.StringVar(&cfg.tagVocabulary, "tag-vocabulary", "", "comma separated tags articles may use, or @file to read them one per line; any tag is allowed when empty")
+	flag.StringVar(&cfg.seedFile, "seed-file", "", "JSON array of articles added at startup; articles whose ID is already stored are skipped")
+	features := flag.String("feature-flags", "", "comma separated features clients may turn on per request with the X-Feature-Flags header: experimental-markdown; the header is ignored when empty")
+	flag.IntVar(&cfg.maxUnpaginated, "max-unpaginated", 1000, "most articles GET /articles returns without ?limit; longer listings are cut off with X-Truncated: true and clients should paginate; 0 removes the cap")
+	flag.IntVar(&cfg.idRetries, "id-retries", defaultIDRetries, "how many times a server-generated article ID that is already taken is regenerated before the create fails with 500")
+	tagScopes := flag.String("tag-scopes", "", "comma separated tag=scope entries, such as premium=premium:read; articles carrying the tag can only be read, and are only listed, with the scope")
+	flag.BoolVar(&cfg.hideRestrictedTags, "hide-restricted-tags", false, "answer GET /articles/{id} for an article restricted by -tag-scopes with 404 rather than 403, so callers can't tell it exists")
+	flag.IntVar(&cfg.logContentChars, "log-content-chars", 200, "how many characters of an article's content and excerpt debug logs show before cutting it short")
+	omitFields := flag.String("log-omit-fields", "", "comma separated article fields, by JSON name, left out of debug logs entirely, such as content,title")
+	flag.BoolVar(&cfg.emptyListNoContent, "empty-list-no-content", false, "answer GET /articles with 204 and no body instead of 200 and [] when there are no articles; clients must then handle a bodiless success, and the envelope's total is not sent")
+	flag.IntVar(&cfg.maxConcurrent, "max-concurrent", 0, "most requests served at once; more are answered with 503 and Retry-After instead of queueing; health, metrics and event streams are not counted; 0 removes the cap")
+	flag.BoolVar(&cfg.strictSlugs, "strict-slugs", false, "reject a new article with 409 when the slug generated from its title is taken, instead of appending a numeric suffix")
+	flag.BoolVar(&cfg.envelope, "envelope", false, "wrap GET /articles responses as {\"data\": [...], \"meta\": {...}} by default instead of a bare array")
+	policy := flag.String("eviction-policy", string(evictOldestPublished), "what to do when -max-articles is reached: oldest-published, oldest-inserted or reject")
+	flag.Parse()
+	cfg.evictionPolicy = evictionPolicy(*policy)
+	cfg.cors.origins = splitList(*corsOrigins)
+	cfg.cors.exposeHeaders = splitList(*corsExpose)
+	cfg.contentTransformers = splitList(*transformers)
+	cfg.trustedProxies = splitList(*proxies)
+	cfg.featureFlags = splitList(*features)
+	cfg.tagScopes = splitList(*tagScopes)
+	cfg.logOmitFields = splitList(*omitFields)
+	return cfg
+}
+
+func (cfg config) validate() error {
+	if (cfg.tlsCert == "") != (cfg.tlsKey == "") {
+		return errors.New("-tls-cert and -tls-key must be set together")
+	}
+	if cfg.acmeDomain != "" && cfg.tlsCert != "" {
+		return errors.New("-acme-domain cannot be combined with -tls-cert/-tls-key")
+	}
+	if cfg.repoShards < 1 {
+		return errors.New("-repo-shards must be at least 1")
+	}
+	if cfg.accessLogMaxSizeMB < 1 {
+		return errors.New("-access-log-max-size must be at least 1")
+	}
+	if err := cfg.cors.validate(); err != nil {
+		return err
+	}
+	if err := checkContentTransformers(cfg.contentTransformers); err != nil {
+		return err
+	}
+	if err := checkFeatureFlags(cfg.featureFlags); err != nil {
+		return err
+	}
+	if err := checkLogOmitFields(cfg.logOmitFields); err != nil {
+		return err
+	}
+	if !cfg.evictionPolicy.valid() {
+		return errors.New("-eviction-policy must be one of oldest-published, oldest-inserted or reject")
+	}
+	return nil
+}
+
+// splitList splits a comma separated flag value, dropping blank entries.
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// testConfig returns a config that passes validate, like the flag defaults.
+func testConfig() config {
+	return config{
+		addr:               ":0",
+		repoShards:         1,
+		accessLogMaxSizeMB: 1,
+		evictionPolicy:     evictOldestPublished,
+	}
+}
+
+func TestConfigValidateTLS(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(*config)
+		wantErr string
+	}{
+		{name: "plaintext", modify: func(*config) {}},
+		{name: "certificate pair", modify: func(c *config) { c.tlsCert, c.tlsKey = "cert.pem", "key.pem" }},
+		{name: "acme", modify: func(c *config) { c.acmeDomain = "example.com" }},
+		{name: "cert without key", modify: func(c *config) { c.tlsCert = "cert.pem" }, wantErr: "must be set together"},
+		{name: "key without cert", modify: func(c *config) { c.tlsKey = "key.pem" }, wantErr: "must be set together"},
+		{
+			name:    "acme with certificate",
+			modify:  func(c *config) { c.acmeDomain, c.tlsCert, c.tlsKey = "example.com", "cert.pem", "key.pem" },
+			wantErr: "cannot be combined",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig()
+			tt.modify(&cfg)
+			err := cfg.validate()
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("validate() = %v, want nil", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Errorf("validate() = %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
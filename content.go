@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// withMaxContentLength rejects articles whose content is longer than max
+// characters (runes). Zero or less means unlimited.
+func withMaxContentLength(max int) svcOption {
+	return func(svc *articleSvc) {
+		svc.maxContentLength = max
+	}
+}
+
+// checkContentLength enforces the configured content length limit.
+func (svc *articleSvc) checkContentLength(article Article) error {
+	if svc.maxContentLength <= 0 {
+		return nil
+	}
+	if n := utf8.RuneCountInString(article.Content); n > svc.maxContentLength {
+		return &ValidationError{Fields: []FieldError{{
+			Field:   "content",
+			Message: fmt.Sprintf("must be at most %d characters, got %d", svc.maxContentLength, n),
+		}}}
+	}
+	return nil
+}
+
+// excerpt returns the first n characters of content followed by an
+// ellipsis, or content unchanged when it is no longer than n. It counts
+// runes, so multi-byte characters are never split.
+func excerpt(content string, n int) string {
+	i := 0
+	for pos := range content {
+		if i == n {
+			return content[:pos] + "…"
+		}
+		i++
+	}
+	return content
+}
+
+// maxDerivedExcerpt caps, in characters, an excerpt derived from content.
+const maxDerivedExcerpt = 280
+
+var paragraphBreak = regexp.MustCompile(`\r?\n[ \t]*\r?\n`)
+
+// withExcerpt returns the article with Excerpt derived from its content
+// when the author didn't provide one. Derived excerpts are never stored.
+func (a Article) withExcerpt() Article {
+	if a.Excerpt == "" {
+		a.Excerpt = derivedExcerpt(a.Content)
+	}
+	return a
+}
+
+// derivedExcerpt returns the first paragraph of content that isn't a
+// Markdown heading, with its line breaks folded into spaces and shortened to
+// maxDerivedExcerpt characters. Content without a paragraph break counts as
+// one paragraph.
+func derivedExcerpt(content string) string {
+	paragraphs := paragraphBreak.Split(strings.TrimSpace(content), -1)
+	first := paragraphs[0]
+	for _, p := range paragraphs {
+		if !strings.HasPrefix(strings.TrimSpace(p), "#") {
+			first = p
+			break
+		}
+	}
+	return excerpt(strings.Join(strings.Fields(first), " "), maxDerivedExcerpt)
+}
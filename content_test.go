@@ -0,0 +1,117 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestExcerpt(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		n       int
+		want    string
+	}{
+		{"shorter", "Hello", 10, "Hello"},
+		{"exact length", "Hello", 5, "Hello"},
+		{"truncated", "Hello, world", 5, "Hello…"},
+		{"zero", "Hello", 0, "…"},
+		{"empty", "", 3, ""},
+		{"two-byte runes", "Crème brûlée", 4, "Crèm…"},
+		{"cut right after a multi-byte rune", "àéîõü", 2, "àé…"},
+		{"CJK", "東京の夜は静かだ", 3, "東京の…"},
+		{"four-byte runes", "😀😃😄😁", 2, "😀😃…"},
+		{"multi-byte at the limit", "日本語", 3, "日本語"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := excerpt(tt.content, tt.n)
+			if got != tt.want {
+				t.Errorf("excerpt(%q, %d) = %q, want %q", tt.content, tt.n, got, tt.want)
+			}
+			if !utf8.ValidString(got) {
+				t.Errorf("excerpt(%q, %d) split a rune: %q", tt.content, tt.n, got)
+			}
+		})
+	}
+}
+
+func TestMaxContentLength(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		wantCode int
+	}{
+		{"under the limit", "short", http.StatusCreated},
+		{"at the limit in ASCII", strings.Repeat("a", 10), http.StatusCreated},
+		{"at the limit in multi-byte runes", strings.Repeat("é", 10), http.StatusCreated},
+		{"at the limit in emoji", strings.Repeat("😀", 10), http.StatusCreated},
+		{"over the limit", strings.Repeat("a", 11), http.StatusUnprocessableEntity},
+		{"over the limit in multi-byte runes", strings.Repeat("é", 11), http.StatusUnprocessableEntity},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := newTestSvc(withMaxContentLength(10))
+			h := newTestHandler(svc)
+
+			rec := doRequest(h, "PUT", "/articles", `{"id":"a1","title":"T","content":"`+tt.content+`"}`)
+			if rec.Code != tt.wantCode {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantCode, rec.Body)
+			}
+			if rec.Code == http.StatusUnprocessableEntity && !strings.Contains(rec.Body.String(), "must be at most 10 characters, got 11") {
+				t.Errorf("body = %s, want the limit and the length", rec.Body)
+			}
+		})
+	}
+}
+
+func TestMaxContentLengthOnUpdate(t *testing.T) {
+	svc := newTestSvc(withMaxContentLength(10))
+	mustAdd(t, svc, Article{ID: "a1", Title: "T", Content: "short"})
+	h := newTestHandler(svc)
+
+	if rec := doRequest(h, "PUT", "/articles/a1", `{"title":"T","content":"`+strings.Repeat("a", 11)+`"}`); rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("PUT: status = %d, want 422", rec.Code)
+	}
+	if rec := doRequest(h, "PATCH", "/articles/a1", `{"content":"`+strings.Repeat("a", 11)+`"}`, "Content-Type", mergePatchContentType); rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("PATCH: status = %d, want 422", rec.Code)
+	}
+	if got := mustGet(t, svc, "a1"); got.Content != "short" {
+		t.Errorf("content = %q, want it unchanged", got.Content)
+	}
+}
+
+func TestListExcerpt(t *testing.T) {
+	svc := newTestSvc()
+	mustAdd(t, svc, Article{ID: "a1", Title: "T", Content: "Grüße aus München"})
+	h := newTestHandler(svc)
+
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"", "Grüße aus München"},
+		{"?excerpt=4", "Grüß…"},
+		{"?excerpt=100", "Grüße aus München"},
+		{"?excerpt=0", "…"},
+	}
+	for _, tt := range tests {
+		rec := doRequest(h, "GET", "/articles"+tt.query, "")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: status = %d: %s", tt.query, rec.Code, rec.Body)
+		}
+		var articles []Article
+		decodeJSON(t, rec, &articles)
+		if len(articles) != 1 || articles[0].Content != tt.want {
+			t.Errorf("%s: content = %+v, want %q", tt.query, articles, tt.want)
+		}
+	}
+	if rec := doRequest(h, "GET", "/articles?excerpt=-1", ""); rec.Code != http.StatusBadRequest {
+		t.Errorf("excerpt=-1: status = %d, want 400", rec.Code)
+	}
+	if got := mustGet(t, svc, "a1"); got.Content != "Grüße aus München" {
+		t.Errorf("stored content changed to %q", got.Content)
+	}
+}
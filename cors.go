@@ -0,0 +1,98 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	corsAllowMethods = "GET, HEAD, PUT, PATCH, POST, DELETE, OPTIONS"
+	corsAllowHeaders = "Authorization, Content-Type, X-API-Key, If-Match, If-None-Match, If-Unmodified-Since"
+)
+
+// corsPolicy describes which browser origins may call the API.
+type corsPolicy struct {
+	// origins lists the allowed origins; "*" allows any origin.
+	origins []string
+	// exposeHeaders are response headers scripts may read, such as ETag and
+	// Location.
+	exposeHeaders []string
+	// maxAge is how long browsers may cache a preflight result; zero leaves
+	// it to the browser.
+	maxAge time.Duration
+	// credentials allows cookies and Authorization on cross-origin requests.
+	// It requires explicit origins.
+	credentials bool
+}
+
+func (p corsPolicy) validate() error {
+	if p.credentials {
+		for _, origin := range p.origins {
+			if origin == "*" {
+				return errors.New("-cors-allow-credentials cannot be combined with the * origin")
+			}
+		}
+	}
+	return nil
+}
+
+// allowOrigin returns the Access-Control-Allow-Origin value for origin, or
+// "" when it isn't allowed.
+func (p corsPolicy) allowOrigin(origin string) string {
+	for _, allowed := range p.origins {
+		if allowed == "*" {
+			return "*"
+		}
+		if strings.EqualFold(allowed, origin) {
+			return origin
+		}
+	}
+	return ""
+}
+
+// corsMiddleware adds CORS headers for allowed origins and answers
+// preflight requests itself with 204. Requests from other origins pass
+// through without CORS headers, so browsers block them.
+func corsMiddleware(policy corsPolicy, next http.Handler) http.Handler {
+	exposeHeaders := strings.Join(policy.exposeHeaders, ", ")
+	maxAge := strconv.Itoa(int(policy.maxAge / time.Second))
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Add("Vary", "Origin")
+		allowed := policy.allowOrigin(origin)
+		if allowed == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Set("Access-Control-Allow-Origin", allowed)
+		if policy.credentials {
+			w.Header().Set("Access-Control-Allow-Credentials", "true")
+		}
+		// Browsers only read the exposed headers off actual responses;
+		// preflights repeat them so the policy can be inspected in one call.
+		if exposeHeaders != "" {
+			w.Header().Set("Access-Control-Expose-Headers", exposeHeaders)
+		}
+
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+			w.Header().Set("Access-Control-Allow-Methods", corsAllowMethods)
+			w.Header().Set("Access-Control-Allow-Headers", corsAllowHeaders)
+			if policy.maxAge > 0 {
+				w.Header().Set("Access-Control-Max-Age", maxAge)
+			}
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestCORSPreflight(t *testing.T) {
+	policy := corsPolicy{
+		origins:       []string{"https://app.example.com"},
+		exposeHeaders: []string{"ETag", "Location"},
+		maxAge:        10 * time.Minute,
+	}
+	credentialed := policy
+	credentialed.credentials = true
+	noMaxAge := policy
+	noMaxAge.maxAge = 0
+	anyOrigin := policy
+	anyOrigin.origins = []string{"*"}
+
+	tests := []struct {
+		name   string
+		policy corsPolicy
+		origin string
+		want   map[string]string
+	}{
+		{"allowed origin", policy, "https://app.example.com", map[string]string{
+			"Access-Control-Allow-Origin":      "https://app.example.com",
+			"Access-Control-Allow-Methods":     corsAllowMethods,
+			"Access-Control-Allow-Headers":     corsAllowHeaders,
+			"Access-Control-Expose-Headers":    "ETag, Location",
+			"Access-Control-Max-Age":           "600",
+			"Access-Control-Allow-Credentials": "",
+			"Vary":                             "Origin",
+		}},
+		{"origin matched case-insensitively", policy, "https://APP.example.com", map[string]string{
+			"Access-Control-Allow-Origin": "https://APP.example.com",
+		}},
+		{"credentials echo the origin", credentialed, "https://app.example.com", map[string]string{
+			"Access-Control-Allow-Origin":      "https://app.example.com",
+			"Access-Control-Allow-Credentials": "true",
+		}},
+		{"no max age", noMaxAge, "https://app.example.com", map[string]string{
+			"Access-Control-Max-Age": "",
+		}},
+		{"any origin", anyOrigin, "https://other.example.com", map[string]string{
+			"Access-Control-Allow-Origin": "*",
+			"Access-Control-Max-Age":      "600",
+		}},
+		{"other origin", policy, "https://evil.example.com", map[string]string{
+			"Access-Control-Allow-Origin":   "",
+			"Access-Control-Allow-Methods":  "",
+			"Access-Control-Expose-Headers": "",
+			"Vary":                          "Origin",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := corsMiddleware(tt.policy, newTestHandler(newTestSvc()))
+			rec := doRequest(h, "OPTIONS", "/articles", "",
+				"Origin", tt.origin,
+				"Access-Control-Request-Method", "PUT",
+				"Access-Control-Request-Headers", "Content-Type")
+
+			if tt.want["Access-Control-Allow-Origin"] != "" && rec.Code != http.StatusNoContent {
+				t.Errorf("status = %d, want 204", rec.Code)
+			}
+			for header, want := range tt.want {
+				if got := rec.Header().Get(header); got != want {
+					t.Errorf("%s = %q, want %q", header, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestCORSActualRequest(t *testing.T) {
+	svc := newTestSvc()
+	mustAdd(t, svc, Article{ID: "a1", Title: "T"})
+	policy := corsPolicy{origins: []string{"https://app.example.com"}, exposeHeaders: []string{"ETag", "Location"}, maxAge: time.Minute}
+	h := corsMiddleware(policy, newTestHandler(svc))
+
+	rec := doRequest(h, "GET", "/articles/a1", "", "Origin", "https://app.example.com")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d", rec.Code)
+	}
+	for header, want := range map[string]string{
+		"Access-Control-Allow-Origin":   "https://app.example.com",
+		"Access-Control-Expose-Headers": "ETag, Location",
+		"Access-Control-Max-Age":        "",
+		"Access-Control-Allow-Methods":  "",
+	} {
+		if got := rec.Header().Get(header); got != want {
+			t.Errorf("%s = %q, want %q", header, got, want)
+		}
+	}
+	if rec.Header().Get("ETag") == "" {
+		t.Error("the exposed ETag header is missing from the response")
+	}
+
+	// A plain OPTIONS without Access-Control-Request-Method isn't a
+	// preflight and reaches the router.
+	if rec := doRequest(h, "OPTIONS", "/articles", "", "Origin", "https://app.example.com"); rec.Code == http.StatusNoContent {
+		t.Error("a non-preflight OPTIONS was answered as a preflight")
+	}
+}
+
+func TestCORSPolicyValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  corsPolicy
+		wantErr bool
+	}{
+		{"explicit origins with credentials", corsPolicy{origins: []string{"https://app.example.com"}, credentials: true}, false},
+		{"any origin without credentials", corsPolicy{origins: []string{"*"}}, false},
+		{"any origin with credentials", corsPolicy{origins: []string{"https://app.example.com", "*"}, credentials: true}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.policy.validate(); (err != nil) != tt.wantErr {
+				t.Errorf("validate() = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestDeleteIfUnmodifiedSince(t *testing.T) {
+	// A sub-second modification time: HTTP dates only carry whole seconds.
+	modified := time.Date(2024, 5, 1, 10, 0, 0, 500_000_000, time.UTC)
+	httpDate := func(d time.Duration) string {
+		return modified.Add(d).Format(http.TimeFormat)
+	}
+
+	tests := []struct {
+		name   string
+		id     string
+		header string
+		want   int
+		kept   bool
+	}{
+		{"no precondition", "a1", "", http.StatusNoContent, false},
+		{"unmodified since a later time", "a1", httpDate(time.Hour), http.StatusNoContent, false},
+		{"unmodified since the same second", "a1", httpDate(0), http.StatusNoContent, false},
+		{"modified after the given time", "a1", httpDate(-time.Second), http.StatusPreconditionFailed, true},
+		{"modified long after", "a1", httpDate(-24 * time.Hour), http.StatusPreconditionFailed, true},
+		{"not an HTTP date", "a1", "yesterday", http.StatusBadRequest, true},
+		{"unknown article", "nope", httpDate(time.Hour), http.StatusNotFound, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := newTestSvc(withClock(newFakeClock(modified)))
+			mustAdd(t, svc, Article{ID: "a1", Title: "One"})
+			h := newTestHandler(svc)
+
+			var headers []string
+			if tt.header != "" {
+				headers = []string{"If-Unmodified-Since", tt.header}
+			}
+			rec := doRequest(h, "DELETE", "/articles/"+tt.id, "", headers...)
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d (%s)", rec.Code, tt.want, rec.Body)
+			}
+
+			_, err := svc.Article(context.Background(), "a1")
+			if kept := !errors.Is(err, ErrArticleNotFound); kept != tt.kept {
+				t.Errorf("article kept = %v, want %v", kept, tt.kept)
+			}
+		})
+	}
+}
+
+func TestDeleteIfUnmodifiedSinceAfterUpdate(t *testing.T) {
+	clock := newFakeClock(time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC))
+	svc := newTestSvc(withClock(clock))
+	mustAdd(t, svc, Article{ID: "a1", Title: "One"})
+	h := newTestHandler(svc)
+	seen := clock.Now().Format(http.TimeFormat)
+
+	// Someone else edits the article after the client last saw it.
+	clock.Advance(time.Minute)
+	if rec := doRequest(h, "PUT", "/articles/a1", `{"title":"Edited"}`); rec.Code != http.StatusOK {
+		t.Fatalf("update: status %d %s", rec.Code, rec.Body)
+	}
+
+	if rec := doRequest(h, "DELETE", "/articles/a1", "", "If-Unmodified-Since", seen); rec.Code != http.StatusPreconditionFailed {
+		t.Fatalf("stale delete: status = %d, want 412", rec.Code)
+	}
+	if got := mustGet(t, svc, "a1"); got.Title != "Edited" {
+		t.Errorf("title = %q after the refused delete, want Edited", got.Title)
+	}
+
+	fresh := clock.Now().Format(http.TimeFormat)
+	if rec := doRequest(h, "DELETE", "/articles/a1", "", "If-Unmodified-Since", fresh); rec.Code != http.StatusNoContent {
+		t.Errorf("fresh delete: status = %d, want 204", rec.Code)
+	}
+}
+
+func TestDeleteModes(t *testing.T) {
+	tests := []struct {
+		name       string
+		idempotent bool
+		// want holds the statuses of deleting a1 twice and then an ID that
+		// never existed.
+		want [3]int
+	}{
+		{"strict", false, [3]int{http.StatusNoContent, http.StatusNotFound, http.StatusNotFound}},
+		{"idempotent", true, [3]int{http.StatusNoContent, http.StatusNoContent, http.StatusNoContent}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := newTestSvc()
+			mustAdd(t, svc, Article{ID: "a1", Title: "One"}, Article{ID: "a2", Title: "Two"})
+			h := newTestHandler(svc, withIdempotentDeletes(tt.idempotent))
+
+			for i, target := range []string{"/articles/a1", "/articles/a1", "/articles/nope"} {
+				rec := doRequest(h, "DELETE", target, "")
+				if rec.Code != tt.want[i] {
+					t.Errorf("DELETE %s (#%d): status = %d, want %d", target, i+1, rec.Code, tt.want[i])
+				}
+				if rec.Code == http.StatusNoContent && rec.Body.Len() > 0 {
+					t.Errorf("DELETE %s (#%d): 204 with body %q", target, i+1, rec.Body)
+				}
+			}
+
+			if _, err := svc.Article(context.Background(), "a1"); !errors.Is(err, ErrArticleNotFound) {
+				t.Errorf("a1 still stored: %v", err)
+			}
+			mustGet(t, svc, "a2")
+			if n := svc.changes.deleted(); n != 1 {
+				t.Errorf("%d tombstones, want 1: repeated deletes must not add more", n)
+			}
+		})
+	}
+}
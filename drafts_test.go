@@ -0,0 +1,233 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// newDraftsHandler serves a published article p1 and a draft d1, with drafts
+// hidden and the usual test keys. The tag secret is only used by the draft.
+func newDraftsHandler(t *testing.T, opts ...transportOption) http.Handler {
+	t.Helper()
+	svc := newTestSvc()
+	mustAdd(t, svc,
+		Article{ID: "p1", Title: "Published", Tags: []string{"go"}, Status: StatusPublished},
+		Article{ID: "d1", Title: "Draft", Tags: []string{"go", "secret"}, Status: StatusDraft},
+	)
+	opts = append([]transportOption{withHiddenDrafts(true)}, opts...)
+	return testAuth(newTestHandler(svc, opts...), map[string][]string{
+		testWriteKey: {scopeWrite},
+		testAdminKey: {scopeAdmin},
+		testReadKey:  {"read"},
+	})
+}
+
+func TestHiddenDrafts(t *testing.T) {
+	h := newDraftsHandler(t)
+	mergePatch := []string{"Content-Type", mergePatchContentType}
+
+	tests := []struct {
+		method, target, body string
+		headers              []string
+		want                 int
+	}{
+		{"GET", "/articles/p1", "", nil, http.StatusOK},
+		{"GET", "/articles/d1", "", nil, http.StatusNotFound},
+		{"GET", "/articles/d1", "", []string{"X-API-Key", testReadKey}, http.StatusNotFound},
+		{"GET", "/articles/d1", "", []string{"X-API-Key", testWriteKey}, http.StatusOK},
+		{"GET", "/articles/d1", "", []string{"X-API-Key", testAdminKey}, http.StatusOK},
+		{"GET", "/articles/d1/attachments", "", nil, http.StatusNotFound},
+		{"GET", "/articles/d1/attachments", "", []string{"X-API-Key", testWriteKey}, http.StatusOK},
+		{"GET", "/articles/d1/export.md", "", nil, http.StatusNotFound},
+		{"GET", "/articles/d1/export.md", "", []string{"X-API-Key", testWriteKey}, http.StatusOK},
+		{"GET", "/articles/d1/siblings", "", nil, http.StatusNotFound},
+		{"GET", "/articles/d1/siblings", "", []string{"X-API-Key", testWriteKey}, http.StatusOK},
+		{"POST", "/articles/p1/clone", "", nil, http.StatusCreated},
+		{"POST", "/articles/d1/clone", "", nil, http.StatusNotFound},
+		{"POST", "/articles/d1/clone", "", []string{"X-API-Key", testWriteKey}, http.StatusCreated},
+		// An empty merge patch changes nothing and would echo the article.
+		{"PATCH", "/articles/d1", `{}`, mergePatch, http.StatusNotFound},
+		{"PATCH", "/articles/d1", `{"title":"Seen"}`, mergePatch, http.StatusNotFound},
+		{"PATCH", "/articles/d1", `{}`, append(mergePatch, "X-API-Key", testWriteKey), http.StatusOK},
+		{"PATCH", "/articles/p1", `{}`, mergePatch, http.StatusOK},
+		{"POST", "/articles/d1/pin", "", nil, http.StatusNotFound},
+		{"POST", "/articles/d1/unpin", "", nil, http.StatusNotFound},
+		{"POST", "/articles/d1/pin", "", []string{"X-API-Key", testWriteKey}, http.StatusOK},
+		{"POST", "/articles/p1/pin", "", nil, http.StatusOK},
+		{"HEAD", "/articles", "", nil, http.StatusOK},
+	}
+	for _, tt := range tests {
+		rec := doRequest(h, tt.method, tt.target, tt.body, tt.headers...)
+		if rec.Code != tt.want {
+			t.Errorf("%s %s %v: status = %d, want %d", tt.method, tt.target, tt.headers, rec.Code, tt.want)
+		}
+		if rec.Code == http.StatusNotFound && rec.Header().Get("X-Unchanged") != "" {
+			t.Errorf("%s %s %v: X-Unchanged set on a 404", tt.method, tt.target, tt.headers)
+		}
+	}
+
+	if rec := doRequest(h, "GET", "/articles/d1", "", "X-API-Key", testWriteKey); rec.Code != http.StatusOK {
+		t.Fatalf("draft gone after the rejected patch: status %d", rec.Code)
+	} else if got := decodeArticle(t, rec); got.Title != "Draft" {
+		t.Errorf("anonymous patch changed the draft's title to %q", got.Title)
+	}
+}
+
+func TestHiddenDraftsInListings(t *testing.T) {
+	handlers := map[string]http.Handler{
+		"uncached": newDraftsHandler(t),
+		"cached":   newDraftsHandler(t, withListCache(newListCache(time.Minute, realClock{}))),
+	}
+
+	// Writers list first, so a cache keyed only by the query would hand
+	// their page to the callers after them.
+	tests := []struct {
+		headers []string
+		want    []string
+	}{
+		{[]string{"X-API-Key", testWriteKey}, []string{"d1", "p1"}},
+		{[]string{"X-API-Key", testAdminKey}, []string{"d1", "p1"}},
+		{nil, []string{"p1"}},
+		{[]string{"X-API-Key", testReadKey}, []string{"p1"}},
+	}
+	for name, h := range handlers {
+		for _, method := range []string{"GET", "HEAD"} {
+			for _, tt := range tests {
+				rec := doRequest(h, method, "/articles", "", tt.headers...)
+				if rec.Code != http.StatusOK {
+					t.Fatalf("%s %s %v: status = %d", name, method, tt.headers, rec.Code)
+				}
+				if got, want := rec.Header().Get("X-Total-Count"), strconv.Itoa(len(tt.want)); got != want {
+					t.Errorf("%s %s %v: X-Total-Count = %s, want %s", name, method, tt.headers, got, want)
+				}
+				if method == "HEAD" {
+					continue
+				}
+				var articles []Article
+				decodeJSON(t, rec, &articles)
+				ids := articleIDs(articles)
+				slices.Sort(ids)
+				if !slices.Equal(ids, tt.want) {
+					t.Errorf("%s %v: listed %v, want %v", name, tt.headers, ids, tt.want)
+				}
+			}
+		}
+	}
+}
+
+func TestHiddenDraftsInTagSuggestions(t *testing.T) {
+	h := newDraftsHandler(t)
+
+	tests := []struct {
+		headers []string
+		want    []TagSuggestion
+	}{
+		{nil, []TagSuggestion{{Tag: "go", Count: 1}}},
+		{[]string{"X-API-Key", testReadKey}, []TagSuggestion{{Tag: "go", Count: 1}}},
+		{[]string{"X-API-Key", testWriteKey}, []TagSuggestion{{Tag: "go", Count: 2}, {Tag: "secret", Count: 1}}},
+	}
+	for _, tt := range tests {
+		rec := doRequest(h, "GET", "/tags/suggest", "", tt.headers...)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%v: status = %d", tt.headers, rec.Code)
+		}
+		var got []TagSuggestion
+		decodeJSON(t, rec, &got)
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%v: suggestions = %v, want %v", tt.headers, got, tt.want)
+		}
+	}
+}
+
+// decodeArticle decodes the article in a JSON response.
+func decodeArticle(t *testing.T, rec *httptest.ResponseRecorder) Article {
+	t.Helper()
+	var article Article
+	decodeJSON(t, rec, &article)
+	return article
+}
+
+func TestHiddenDraftsInChanges(t *testing.T) {
+	h := newDraftsHandler(t)
+
+	tests := []struct {
+		headers []string
+		want    []string
+	}{
+		{nil, []string{"p1"}},
+		{[]string{"X-API-Key", testReadKey}, []string{"p1"}},
+		{[]string{"X-API-Key", testWriteKey}, []string{"d1", "p1"}},
+	}
+	for _, tt := range tests {
+		rec := doRequest(h, "GET", "/articles/changes", "", tt.headers...)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%v: status = %d", tt.headers, rec.Code)
+		}
+		var resp changesResponse
+		decodeJSON(t, rec, &resp)
+		var ids []string
+		for _, change := range resp.Changes {
+			ids = append(ids, change.ID)
+		}
+		slices.Sort(ids)
+		if !slices.Equal(ids, tt.want) {
+			t.Errorf("%v: changes for %v, want %v", tt.headers, ids, tt.want)
+		}
+		if resp.Seq != 2 {
+			t.Errorf("%v: seq = %d, want 2", tt.headers, resp.Seq)
+		}
+	}
+}
+
+func TestHiddenDraftsInEvents(t *testing.T) {
+	bus := newEventBus()
+	svc := newTestSvc(withEventBus(bus))
+	srv := httptest.NewServer(testAuth(newTestHandler(svc, withEvents(bus), withHiddenDrafts(true)),
+		map[string][]string{testWriteKey: {scopeWrite}}))
+	t.Cleanup(func() {
+		bus.Close()
+		srv.Close()
+	})
+
+	anonSSE := openEventStream(t, srv)
+	writerSSE := openEventStream(t, srv, "X-API-Key", testWriteKey)
+	anonWS := dialArticlesWebSocket(t, srv, bus)
+	writerWS := dialArticlesWebSocket(t, srv, bus, "X-API-Key", testWriteKey)
+	waitFor(t, "all four streams to subscribe", func() bool {
+		bus.mu.RLock()
+		defer bus.mu.RUnlock()
+		return len(bus.subs) == 4
+	})
+
+	putArticle(t, srv, `{"id":"d1","title":"Draft","status":"draft"}`)
+	putArticle(t, srv, `{"id":"p1","title":"Published","status":"published"}`)
+
+	// Events arrive in order, so a caller that can't see drafts gets p1
+	// first.
+	for _, tt := range []struct {
+		name string
+		next func() string
+		want []string
+	}{
+		{"anonymous SSE", func() string { return sseArticleID(t, readEvent(t, anonSSE)) }, []string{"p1"}},
+		{"writer SSE", func() string { return sseArticleID(t, readEvent(t, writerSSE)) }, []string{"d1", "p1"}},
+		{"anonymous WS", func() string { return readWSEvent(t, anonWS).Article.ID }, []string{"p1"}},
+		{"writer WS", func() string { return readWSEvent(t, writerWS).Article.ID }, []string{"d1", "p1"}},
+	} {
+		for _, want := range tt.want {
+			if got := tt.next(); got != want {
+				t.Errorf("%s: got an event for %q, want %q", tt.name, got, want)
+			}
+		}
+	}
+}
+
+// sseArticleID returns the ID of the article an event carries.
+func sseArticleID(t *testing.T, ev sseEvent) string {
+	t.Helper()
+	return sseEnvelope(t, ev).Article.ID
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// encoder serializes a response body in one wire format. Article carries
+// json, yaml and toml struct tags so field names match across formats, and
+// time.Time values are written as RFC 3339 timestamps by all of them.
+//
+// Output must be byte-identical for identical input so that responses cache
+// and compare cleanly. Structs are written in field declaration order, and
+// all three encoders write map keys in sorted order; any map-based response
+// must rely on that and never on map iteration order.
+type encoder interface {
+	contentType() string
+	encode(w io.Writer, v interface{}) error
+}
+
+// jsonEncoding writes JSON, pretty printed as the transport and request ask.
+// The zero value writes compact JSON.
+type jsonEncoding struct {
+	t *articlesHttpTransport
+	r *http.Request
+}
+
+func (e jsonEncoding) contentType() string { return "application/json" }
+
+func (e jsonEncoding) encode(w io.Writer, v interface{}) error {
+	if e.t == nil {
+		return json.NewEncoder(w).Encode(v)
+	}
+	return e.t.jsonEncoder(w, e.r).Encode(v)
+}
+
+type yamlEncoding struct{}
+
+func (yamlEncoding) contentType() string { return "application/yaml" }
+
+func (yamlEncoding) encode(w io.Writer, v interface{}) error {
+	enc := yaml.NewEncoder(w)
+	if err := enc.Encode(v); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+type tomlEncoding struct{}
+
+func (tomlEncoding) contentType() string { return "application/toml" }
+
+// encode writes v as a TOML document. TOML documents must be tables, so
+// lists are wrapped in an "items" array of tables.
+func (tomlEncoding) encode(w io.Writer, v interface{}) error {
+	if rv := reflect.ValueOf(v); rv.Kind() == reflect.Slice {
+		v = map[string]interface{}{"items": v}
+	}
+	return toml.NewEncoder(w).Encode(v)
+}
+
+// writeEncoded answers with status and v in enc's format. The body is encoded
+// into a buffer before anything is written, so a value that fails to encode
+// still gets a clean 500 instead of a 200 followed by a truncated body.
+func writeEncoded(w http.ResponseWriter, enc encoder, status int, v interface{}) {
+	body, ok := encodeBody(w, enc, v)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", enc.contentType())
+	w.WriteHeader(status)
+	if _, err := w.Write(body); err != nil {
+		log.Println(err)
+	}
+}
+
+// serveEncoded is writeEncoded for a 200 response that honours Range and
+// If-Range, answering 206 with the requested bytes of the encoded body.
+// modtime is the stable Last-Modified of v; the zero time leaves If-Range to
+// the ETag alone.
+func serveEncoded(w http.ResponseWriter, r *http.Request, enc encoder, modtime time.Time, v interface{}) {
+	body, ok := encodeBody(w, enc, v)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", enc.contentType())
+	http.ServeContent(w, r, "", modtime, bytes.NewReader(body))
+}
+
+// encodeBody encodes v with enc. When that fails it answers 500 itself and
+// reports false.
+func encodeBody(w http.ResponseWriter, enc encoder, v interface{}) ([]byte, bool) {
+	var body bytes.Buffer
+	if err := enc.encode(&body, v); err != nil {
+		log.Println(err)
+		// These describe the response that could not be written.
+		for _, header := range []string{"ETag", "Last-Modified", "Location"} {
+			w.Header().Del(header)
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		io.WriteString(w, "failed to encode response")
+		return nil, false
+	}
+	return body.Bytes(), true
+}
+
+// writeJSON is writeEncoded in JSON, honouring -pretty and ?pretty=true.
+func (t *articlesHttpTransport) writeJSON(w http.ResponseWriter, r *http.Request, status int, v interface{}) {
+	writeEncoded(w, jsonEncoding{t: t, r: r}, status, v)
+}
+
+// negotiateEncoder picks the response format from the ?format= query
+// parameter, falling back to the Accept header and finally to JSON. It
+// reports false when ?format= names an unsupported format.
+func (t *articlesHttpTransport) negotiateEncoder(r *http.Request) (encoder, bool) {
+	switch strings.ToLower(r.URL.Query().Get("format")) {
+	case "json":
+		return jsonEncoding{t: t, r: r}, true
+	case "yaml", "yml":
+		return yamlEncoding{}, true
+	case "toml":
+		return tomlEncoding{}, true
+	case "":
+	default:
+		return nil, false
+	}
+
+	for _, accepted := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(accepted))
+		if err != nil {
+			continue
+		}
+		switch mediaType {
+		case "application/json", "*/*", "application/*":
+			return jsonEncoding{t: t, r: r}, true
+		case "application/yaml", "application/x-yaml", "text/yaml":
+			return yamlEncoding{}, true
+		case "application/toml":
+			return tomlEncoding{}, true
+		}
+	}
+	return jsonEncoding{t: t, r: r}, true
+}
@@ -0,0 +1,290 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+func TestPrettyJSON(t *testing.T) {
+	tests := []struct {
+		name   string
+		global bool
+		query  string
+		pretty bool
+	}{
+		{name: "compact by default"},
+		{name: "requested", query: "?pretty=true", pretty: true},
+		{name: "declined", query: "?pretty=false"},
+		{name: "enabled globally", global: true, pretty: true},
+		{name: "global wins over the query", global: true, query: "?pretty=false", pretty: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := newTestSvc()
+			mustAdd(t, svc, Article{ID: "a1", Title: "T", Tags: []string{"x"}})
+			h := newTestHandler(svc, withPrettyJSON(tt.global))
+
+			rec := doRequest(h, "GET", "/articles/a1"+tt.query, "")
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, want 200", rec.Code)
+			}
+			body := rec.Body.String()
+			indented := strings.HasPrefix(body, "{\n  \"id\": \"a1\",\n  \"title\": \"T\",\n  \"tags\": [\n    \"x\"\n  ],")
+			if indented != tt.pretty {
+				t.Errorf("indented = %v, want %v:\n%s", indented, tt.pretty, body)
+			}
+			if !tt.pretty && strings.Count(body, "\n") != 1 {
+				t.Errorf("compact body spans several lines:\n%s", body)
+			}
+		})
+	}
+}
+
+// decodeFormat decodes body in the named format, unwrapping the "items"
+// table TOML lists are wrapped in.
+func decodeFormat(t *testing.T, format string, body []byte, v any) {
+	t.Helper()
+	var err error
+	switch format {
+	case "json":
+		err = json.Unmarshal(body, v)
+	case "yaml":
+		err = yaml.Unmarshal(body, v)
+	case "toml":
+		if _, isList := v.(*[]Article); isList {
+			var doc struct {
+				Items []Article `toml:"items"`
+			}
+			_, err = toml.Decode(string(body), &doc)
+			*v.(*[]Article) = doc.Items
+		} else {
+			_, err = toml.Decode(string(body), v)
+		}
+	}
+	if err != nil {
+		t.Fatalf("decoding %s %q: %v", format, body, err)
+	}
+}
+
+func TestOutputFormats(t *testing.T) {
+	published := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	stored := Article{ID: "a1", Title: "Formats", Tags: []string{"go", "yaml"}, Content: "Body", PublishAt: published}
+
+	tests := []struct {
+		format      string
+		query       string
+		accept      string
+		contentType string
+	}{
+		{format: "json", contentType: "application/json"},
+		{format: "json", query: "format=json", contentType: "application/json"},
+		{format: "json", accept: "application/json", contentType: "application/json"},
+		{format: "yaml", query: "format=yaml", contentType: "application/yaml"},
+		{format: "yaml", query: "format=yml", contentType: "application/yaml"},
+		{format: "yaml", accept: "text/yaml", contentType: "application/yaml"},
+		{format: "toml", query: "format=toml", contentType: "application/toml"},
+		{format: "toml", accept: "application/toml, application/json;q=0.5", contentType: "application/toml"},
+		{format: "yaml", query: "format=yaml", accept: "application/toml", contentType: "application/yaml"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format+"/"+tt.query+tt.accept, func(t *testing.T) {
+			svc := newTestSvc()
+			mustAdd(t, svc, stored)
+			h := newTestHandler(svc)
+
+			for _, path := range []string{"/articles/a1", "/articles"} {
+				target := path
+				if tt.query != "" {
+					target += "?" + tt.query
+				}
+				rec := doRequest(h, "GET", target, "", "Accept", tt.accept)
+				if rec.Code != http.StatusOK {
+					t.Fatalf("%s: status = %d, want 200", path, rec.Code)
+				}
+				if got := rec.Header().Get("Content-Type"); got != tt.contentType {
+					t.Errorf("%s: Content-Type = %q, want %q", path, got, tt.contentType)
+				}
+
+				var got Article
+				if path == "/articles" {
+					var list []Article
+					decodeFormat(t, tt.format, rec.Body.Bytes(), &list)
+					if len(list) != 1 {
+						t.Fatalf("%s: got %d articles, want 1", path, len(list))
+					}
+					got = list[0]
+				} else {
+					decodeFormat(t, tt.format, rec.Body.Bytes(), &got)
+				}
+				if got.ID != stored.ID || got.Title != stored.Title || !slices.Equal(got.Tags, stored.Tags) || !got.PublishAt.Equal(published) {
+					t.Errorf("%s: decoded %+v, want the stored article", path, got)
+				}
+			}
+		})
+	}
+}
+
+func TestUnsupportedFormat(t *testing.T) {
+	svc := newTestSvc()
+	mustAdd(t, svc, Article{ID: "a1", Title: "T"})
+	h := newTestHandler(svc)
+	for _, target := range []string{"/articles/a1?format=xml", "/articles?format=xml"} {
+		if rec := doRequest(h, "GET", target, ""); rec.Code != http.StatusNotAcceptable {
+			t.Errorf("%s: status = %d, want 406", target, rec.Code)
+		}
+	}
+}
+
+// TestEncodersSortMapKeys pins down what the encoder doc promises for
+// map-based values: keys in sorted order, at every level, every time.
+func TestEncodersSortMapKeys(t *testing.T) {
+	keys := make([]string, 30)
+	v := make(map[string]interface{}, len(keys))
+	nested := make(map[string]int, len(keys))
+	for i := range keys {
+		keys[i] = fmt.Sprintf("k%02d", i)
+		v[keys[i]] = i
+		nested[keys[i]] = i
+	}
+	v["zz"] = nested
+
+	for _, enc := range []encoder{jsonEncoding{}, yamlEncoding{}, tomlEncoding{}} {
+		t.Run(enc.contentType(), func(t *testing.T) {
+			var first []byte
+			for i := 0; i < 20; i++ {
+				var buf bytes.Buffer
+				if err := enc.encode(&buf, v); err != nil {
+					t.Fatal(err)
+				}
+				if first == nil {
+					first = buf.Bytes()
+				} else if !bytes.Equal(buf.Bytes(), first) {
+					t.Fatalf("encoding %d differs:\n%s\nfirst:\n%s", i, buf.Bytes(), first)
+				}
+			}
+
+			// Top-level keys, then the nested map's, each in sorted order.
+			pos := 0
+			for _, key := range append(append(slices.Clone(keys), "zz"), keys...) {
+				i := bytes.Index(first[pos:], []byte(key))
+				if i < 0 {
+					t.Fatalf("key %s missing or out of order in:\n%s", key, first)
+				}
+				pos += i + len(key)
+			}
+		})
+	}
+}
+
+// TestRepeatedResponsesIdentical requests the same resources repeatedly in
+// every format and expects byte-identical bodies and ETags.
+func TestRepeatedResponsesIdentical(t *testing.T) {
+	svc := newTestSvc()
+	for i := 0; i < 10; i++ {
+		mustAdd(t, svc, Article{
+			ID:        fmt.Sprintf("a%d", i),
+			Title:     fmt.Sprintf("Article %d", i),
+			Tags:      []string{"go", fmt.Sprintf("tag%d", i), "web"},
+			Content:   "Body",
+			PublishAt: time.Date(2024, 5, 1+i, 12, 0, 0, 0, time.UTC),
+			Attachments: []Attachment{
+				{URL: "https://cdn.example.com/a.png", MIMEType: "image/png", Size: 10, Alt: "a"},
+			},
+		})
+	}
+	signer, err := newCursorSigner("test-secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := newTestHandler(svc, withCursorSigner(signer))
+
+	targets := []string{"/articles", "/articles?envelope=true&limit=3", "/articles/a3"}
+	for _, format := range []string{"json", "yaml", "toml"} {
+		for _, target := range targets {
+			sep := "?"
+			if strings.Contains(target, "?") {
+				sep = "&"
+			}
+			target += sep + "format=" + format
+			t.Run(target, func(t *testing.T) {
+				first := doRequest(h, "GET", target, "")
+				if first.Code != http.StatusOK {
+					t.Fatalf("status = %d: %s", first.Code, first.Body)
+				}
+				for i := 0; i < 20; i++ {
+					rec := doRequest(h, "GET", target, "")
+					if !bytes.Equal(rec.Body.Bytes(), first.Body.Bytes()) {
+						t.Fatalf("response %d differs:\n%s\nfirst:\n%s", i, rec.Body, first.Body)
+					}
+					if got, want := rec.Header().Get("ETag"), first.Header().Get("ETag"); got != want {
+						t.Fatalf("response %d ETag = %q, first was %q", i, got, want)
+					}
+				}
+			})
+		}
+	}
+
+	schema := doRequest(h, "GET", "/articles/schema", "")
+	for i := 0; i < 20; i++ {
+		if rec := doRequest(h, "GET", "/articles/schema", ""); !bytes.Equal(rec.Body.Bytes(), schema.Body.Bytes()) {
+			t.Fatalf("schema response %d differs", i)
+		}
+	}
+}
+
+func TestArticleRange(t *testing.T) {
+	svc := newTestSvc()
+	mustAdd(t, svc, Article{ID: "a1", Title: "A", Content: strings.Repeat("long content ", 100)})
+	h := newTestHandler(svc)
+
+	full := doRequest(h, "GET", "/articles/a1", "")
+	if full.Code != http.StatusOK || full.Header().Get("Accept-Ranges") != "bytes" {
+		t.Fatalf("full fetch: status %d, Accept-Ranges %q", full.Code, full.Header().Get("Accept-Ranges"))
+	}
+	body := full.Body.String()
+	size := strconv.Itoa(len(body))
+	etag, lastModified := full.Header().Get("ETag"), full.Header().Get("Last-Modified")
+
+	tests := []struct {
+		name         string
+		headers      []string
+		want         int
+		wantBody     string
+		contentRange string
+	}{
+		{"first bytes", []string{"Range", "bytes=0-9"}, http.StatusPartialContent, body[:10], "bytes 0-9/" + size},
+		{"resume", []string{"Range", "bytes=100-"}, http.StatusPartialContent, body[100:], "bytes 100-" + strconv.Itoa(len(body)-1) + "/" + size},
+		{"suffix", []string{"Range", "bytes=-5"}, http.StatusPartialContent, body[len(body)-5:], "bytes " + strconv.Itoa(len(body)-5) + "-" + strconv.Itoa(len(body)-1) + "/" + size},
+		{"unsatisfiable", []string{"Range", "bytes=" + size + "-"}, http.StatusRequestedRangeNotSatisfiable, "", "bytes */" + size},
+		// If-Range compares strongly, and article ETags are weak.
+		{"If-Range with the ETag gets everything", []string{"Range", "bytes=0-9", "If-Range", etag}, http.StatusOK, body, ""},
+		{"If-Range with Last-Modified", []string{"Range", "bytes=0-9", "If-Range", lastModified}, http.StatusPartialContent, body[:10], "bytes 0-9/" + size},
+		{"stale If-Range gets everything", []string{"Range", "bytes=0-9", "If-Range", `"stale"`}, http.StatusOK, body, ""},
+		{"no Range", nil, http.StatusOK, body, ""},
+	}
+	for _, tt := range tests {
+		rec := doRequest(h, "GET", "/articles/a1", "", tt.headers...)
+		if rec.Code != tt.want {
+			t.Errorf("%s: status = %d, want %d", tt.name, rec.Code, tt.want)
+			continue
+		}
+		if tt.wantBody != "" && rec.Body.String() != tt.wantBody {
+			t.Errorf("%s: body = %q, want %q", tt.name, rec.Body, tt.wantBody)
+		}
+		if got := rec.Header().Get("Content-Range"); got != tt.contentRange {
+			t.Errorf("%s: Content-Range = %q, want %q", tt.name, got, tt.contentRange)
+		}
+	}
+}
@@ -0,0 +1,29 @@
+package main
+
+// listEnvelope wraps a listing with pagination metadata. It is sent instead
+// of the bare array when envelopes are enabled or ?envelope=true is given.
+type listEnvelope struct {
+	Data []Article `json:"data" yaml:"data" toml:"data"`
+	Meta listMeta  `json:"meta" yaml:"meta" toml:"meta"`
+}
+
+type listMeta struct {
+	// Total is how many articles match the filters, before any cursor,
+	// offset or limit is applied.
+	Total int `json:"total" yaml:"total" toml:"total"`
+	// Limit is the page size asked for; 0 means no limit.
+	Limit      int    `json:"limit" yaml:"limit" toml:"limit"`
+	Offset     int    `json:"offset" yaml:"offset" toml:"offset"`
+	NextCursor string `json:"nextCursor,omitempty" yaml:"nextCursor,omitempty" toml:"nextCursor,omitempty"`
+	// Truncated is set when a listing without a limit was cut off at the
+	// server's cap; see withMaxUnpaginated.
+	Truncated bool `json:"truncated,omitempty" yaml:"truncated,omitempty" toml:"truncated,omitempty"`
+}
+
+// withEnvelope makes listings enveloped by default; ?envelope=false still
+// asks for the bare array.
+func withEnvelope(enabled bool) transportOption {
+	return func(t *articlesHttpTransport) {
+		t.envelope = enabled
+	}
+}
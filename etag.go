@@ -0,0 +1,62 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// contentETag hashes the article's content into a weak entity tag. Seq,
+// ModifiedAt and the stored ETag itself are left out, so rewriting an
+// article unchanged keeps its tag. The tag is weak because the same article
+// is served in equivalent but not byte-identical forms, such as pretty and
+// compact JSON; formatETag tells the formats apart.
+func (a Article) contentETag() string {
+	a.Seq = 0
+	a.ModifiedAt = time.Time{}
+	a.ETag = ""
+	data, err := json.Marshal(a)
+	if err != nil {
+		// Article always marshals; an empty tag just disables caching.
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return `W/"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// formatETag is the entity tag of the article with etag as served by enc.
+// JSON keeps the stored tag; other formats append their name, so a cache or
+// a conditional GET never takes one format's response for another's.
+func formatETag(etag string, enc encoder) string {
+	if _, ok := enc.(jsonEncoding); ok || etag == "" {
+		return etag
+	}
+	_, format, _ := strings.Cut(enc.contentType(), "/")
+	return strings.TrimSuffix(etag, `"`) + "-" + format + `"`
+}
+
+// etagMatches reports whether an If-None-Match header value matches etag,
+// using the weak comparison RFC 9110 prescribes for If-None-Match.
+func etagMatches(header, etag string) bool {
+	if header == "" || etag == "" {
+		return false
+	}
+	if strings.TrimSpace(header) == "*" {
+		return true
+	}
+	for _, candidate := range strings.Split(header, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(candidate), "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
+
+// writeNotModified answers a conditional GET whose If-None-Match matched.
+func writeNotModified(w http.ResponseWriter, etag string) {
+	w.Header().Set("ETag", etag)
+	w.WriteHeader(http.StatusNotModified)
+}
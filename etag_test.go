@@ -0,0 +1,227 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"sync"
+	"testing"
+)
+
+func TestStoredETag(t *testing.T) {
+	svc := newTestSvc()
+	h := newTestHandler(svc)
+
+	// served fetches a1 and checks that the ETag header is the stored one.
+	served := func() string {
+		t.Helper()
+		rec := doRequest(h, "GET", "/articles/a1", "")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("GET status = %d: %s", rec.Code, rec.Body)
+		}
+		stored := mustGet(t, svc, "a1").ETag
+		if stored == "" {
+			t.Fatal("no ETag stored")
+		}
+		if got := rec.Header().Get("ETag"); got != stored {
+			t.Fatalf("served ETag %q, stored %q", got, stored)
+		}
+		return stored
+	}
+
+	if rec := doRequest(h, "PUT", "/articles", `{"id":"a1","title":"T","content":"one","publishAt":"2024-03-05"}`); rec.Code != http.StatusCreated {
+		t.Fatalf("create: status = %d: %s", rec.Code, rec.Body)
+	}
+	created := served()
+
+	if rec := doRequest(h, "PUT", "/articles/a1", `{"title":"T","content":"one","publishAt":"2024-03-05"}`); rec.Code != http.StatusOK {
+		t.Fatalf("rewrite: status = %d: %s", rec.Code, rec.Body)
+	}
+	if got := served(); got != created {
+		t.Errorf("rewriting unchanged content changed the ETag from %s to %s", created, got)
+	}
+
+	if rec := doRequest(h, "PUT", "/articles/a1", `{"title":"T","content":"two","publishAt":"2024-03-05"}`); rec.Code != http.StatusOK {
+		t.Fatalf("update: status = %d: %s", rec.Code, rec.Body)
+	}
+	updated := served()
+	if updated == created {
+		t.Error("updating the content kept the ETag")
+	}
+
+	patch := doRequest(h, "PATCH", "/articles/a1", `{"tags":["go"]}`, "Content-Type", mergePatchContentType)
+	if patch.Code != http.StatusOK {
+		t.Fatalf("patch: status = %d: %s", patch.Code, patch.Body)
+	}
+	if got := served(); got == updated {
+		t.Error("patching the tags kept the ETag")
+	}
+}
+
+// TestStoredETagServedAsIs stores an article with a tag no hash would
+// produce and expects reads to serve and compare it unchanged, so nothing
+// is hashed on the read path.
+func TestStoredETagServedAsIs(t *testing.T) {
+	repo := newInMemoryRepo()
+	const sentinel = `"stored-tag"`
+	if err := repo.InsertArticle(context.Background(), Article{ID: "a1", Title: "T", ETag: sentinel}); err != nil {
+		t.Fatal(err)
+	}
+	h := newTestHandler(newArticleSvc(repo))
+
+	if rec := doRequest(h, "GET", "/articles/a1", ""); rec.Header().Get("ETag") != sentinel {
+		t.Errorf("ETag = %q, want the stored %q", rec.Header().Get("ETag"), sentinel)
+	}
+
+	tests := []struct {
+		ifNoneMatch string
+		wantCode    int
+	}{
+		{sentinel, http.StatusNotModified},
+		{`W/` + sentinel, http.StatusNotModified},
+		{`"other", ` + sentinel, http.StatusNotModified},
+		{`*`, http.StatusNotModified},
+		{`"other"`, http.StatusOK},
+	}
+	for _, tt := range tests {
+		rec := doRequest(h, "GET", "/articles/a1", "", "If-None-Match", tt.ifNoneMatch)
+		if rec.Code != tt.wantCode {
+			t.Errorf("If-None-Match %s: status = %d, want %d", tt.ifNoneMatch, rec.Code, tt.wantCode)
+		}
+		if rec.Code == http.StatusNotModified && (rec.Header().Get("ETag") != sentinel || rec.Body.Len() != 0) {
+			t.Errorf("If-None-Match %s: 304 with ETag %q and %d body bytes", tt.ifNoneMatch, rec.Header().Get("ETag"), rec.Body.Len())
+		}
+	}
+}
+
+func TestContentETagIgnoresVolatileFields(t *testing.T) {
+	base := Article{ID: "a1", Title: "T", Content: "Body", Tags: []string{"go"}}
+	volatile := base
+	volatile.Seq = 42
+	volatile.ModifiedAt = volatile.ModifiedAt.AddDate(1, 0, 0)
+	volatile.ETag = `"previous"`
+	if base.contentETag() != volatile.contentETag() {
+		t.Error("Seq, ModifiedAt or the old ETag changed the content ETag")
+	}
+
+	changed := base
+	changed.Title = "Other"
+	if base.contentETag() == changed.contentETag() {
+		t.Error("a title change kept the content ETag")
+	}
+}
+
+func TestPutIfNoneMatchStar(t *testing.T) {
+	svc := newTestSvc()
+	mustAdd(t, svc, Article{ID: "a1", Title: "Original"})
+	h := newTestHandler(svc)
+
+	tests := []struct {
+		name, target, ifNoneMatch string
+		want                      int
+		wantTitle                 string
+	}{
+		{"absent is created", "/articles/a2", "*", http.StatusCreated, "New"},
+		{"present is refused", "/articles/a1", "*", http.StatusPreconditionFailed, "Original"},
+		{"padded star", "/articles/a1", " * ", http.StatusPreconditionFailed, "Original"},
+		{"without the header it updates", "/articles/a1", "", http.StatusOK, "New"},
+		{"without the header an absent ID is 404", "/articles/a3", "", http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		var headers []string
+		if tt.ifNoneMatch != "" {
+			headers = []string{"If-None-Match", tt.ifNoneMatch}
+		}
+		if rec := doRequest(h, "PUT", tt.target, `{"title":"New"}`, headers...); rec.Code != tt.want {
+			t.Errorf("%s: status = %d, want %d", tt.name, rec.Code, tt.want)
+		}
+		id := tt.target[len("/articles/"):]
+		article, err := svc.Article(context.Background(), id)
+		switch {
+		case tt.wantTitle == "" && err == nil:
+			t.Errorf("%s: %s was stored", tt.name, id)
+		case tt.wantTitle != "" && (err != nil || article.Title != tt.wantTitle):
+			t.Errorf("%s: got %v, %v; want title %q", tt.name, article, err, tt.wantTitle)
+		}
+	}
+}
+
+func TestPutIfNoneMatchStarRace(t *testing.T) {
+	h := newTestHandler(newTestSvc())
+
+	const writers = 20
+	codes := make(chan int, writers)
+	var wg sync.WaitGroup
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			codes <- doRequest(h, "PUT", "/articles/a1", `{"title":"T"}`, "If-None-Match", "*").Code
+		}()
+	}
+	wg.Wait()
+	close(codes)
+
+	created := 0
+	for code := range codes {
+		switch code {
+		case http.StatusCreated:
+			created++
+		case http.StatusPreconditionFailed:
+		default:
+			t.Errorf("status = %d, want 201 or 412", code)
+		}
+	}
+	if created != 1 {
+		t.Errorf("%d concurrent create-if-absent requests succeeded, want 1", created)
+	}
+}
+
+func TestETagPerFormat(t *testing.T) {
+	svc := newTestSvc()
+	mustAdd(t, svc, Article{ID: "a1", Title: "T", Content: "Body"})
+	h := newTestHandler(svc)
+	stored := mustGet(t, svc, "a1").ETag
+
+	tests := []struct {
+		name    string
+		target  string
+		headers []string
+		want    string
+	}{
+		{"default", "/articles/a1", nil, stored},
+		{"accept json", "/articles/a1", []string{"Accept", "application/json"}, stored},
+		{"accept yaml", "/articles/a1", []string{"Accept", "application/yaml"}, stored[:len(stored)-1] + `-yaml"`},
+		{"format toml", "/articles/a1?format=toml", nil, stored[:len(stored)-1] + `-toml"`},
+	}
+	for _, tt := range tests {
+		rec := doRequest(h, "GET", tt.target, "", tt.headers...)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: status = %d", tt.name, rec.Code)
+		}
+		if got := rec.Header().Get("ETag"); got != tt.want {
+			t.Errorf("%s: ETag = %s, want %s", tt.name, got, tt.want)
+		}
+		if vary := rec.Header().Values("Vary"); !slices.Contains(vary, "Accept") {
+			t.Errorf("%s: Vary = %v, want Accept", tt.name, vary)
+		}
+
+		// Revalidating in the same format is a 304; with another format's
+		// tag the full response comes back.
+		same := append(slices.Clone(tt.headers), "If-None-Match", tt.want)
+		if rec := doRequest(h, "GET", tt.target, "", same...); rec.Code != http.StatusNotModified || !slices.Contains(rec.Header().Values("Vary"), "Accept") {
+			t.Errorf("%s: revalidating got %d, Vary %v; want 304 varying on Accept", tt.name, rec.Code, rec.Header().Values("Vary"))
+		}
+		if tt.want != stored {
+			other := append(slices.Clone(tt.headers), "If-None-Match", stored)
+			if rec := doRequest(h, "GET", tt.target, "", other...); rec.Code != http.StatusOK {
+				t.Errorf("%s: the JSON tag got %d, want 200", tt.name, rec.Code)
+			}
+		}
+	}
+
+	rec := doRequest(h, "GET", "/articles", "", "Accept", "application/yaml")
+	if vary := rec.Header().Values("Vary"); !slices.Contains(vary, "Accept") {
+		t.Errorf("listing: Vary = %v, want Accept", vary)
+	}
+}
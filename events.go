@@ -0,0 +1,153 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	eventArticleCreated = "article.created"
+	eventArticleUpdated = "article.updated"
+	eventArticleDeleted = "article.deleted"
+	// eventArticlesCleared carries an empty article.
+	eventArticlesCleared = "articles.cleared"
+)
+
+// sseHeartbeatInterval is how often an idle event stream receives a comment
+// line so proxies don't close the connection.
+const sseHeartbeatInterval = 15 * time.Second
+
+// articleEvent describes a change to a stored article. RequestID is the
+// correlation ID of the API request that made the change, when there was one.
+type articleEvent struct {
+	Type      string  `json:"type"`
+	Article   Article `json:"article"`
+	RequestID string  `json:"requestId,omitempty"`
+}
+
+// eventBus fans article events out to every subscriber. Publishing never
+// blocks: a subscriber that falls behind misses events instead of stalling
+// writes.
+type eventBus struct {
+	mu     sync.RWMutex
+	subs   map[chan articleEvent]struct{}
+	closed bool
+}
+
+func newEventBus() *eventBus {
+	return &eventBus{subs: make(map[chan articleEvent]struct{})}
+}
+
+// Subscribe registers a new subscriber. The returned function must be called
+// to release it. The channel is closed when the bus is closed.
+func (b *eventBus) Subscribe() (<-chan articleEvent, func()) {
+	ch := make(chan articleEvent, 16)
+
+	b.mu.Lock()
+	if b.closed {
+		close(ch)
+	} else {
+		b.subs[ch] = struct{}{}
+	}
+	b.mu.Unlock()
+
+	return ch, func() {
+		b.mu.Lock()
+		if _, ok := b.subs[ch]; ok {
+			delete(b.subs, ch)
+			close(ch)
+		}
+		b.mu.Unlock()
+	}
+}
+
+// Close ends every subscription, letting long-lived streams finish so the
+// server can shut down.
+func (b *eventBus) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+	for ch := range b.subs {
+		delete(b.subs, ch)
+		close(ch)
+	}
+}
+
+func (b *eventBus) Publish(ev articleEvent) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for ch := range b.subs {
+		select {
+		case ch <- ev:
+		default:
+			log.Printf("dropping %s event for a slow subscriber", ev.Type)
+		}
+	}
+}
+
+// articleEvents streams article changes as server-sent events until the
+// client disconnects. Each event's data is the same articleEvent envelope the
+// WebSocket endpoint sends.
+func (t *articlesHttpTransport) articleEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok || t.events == nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		io.WriteString(w, "streaming unsupported")
+		return
+	}
+
+	// The stream outlives any -write-timeout.
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		log.Println(err)
+	}
+
+	events, unsubscribe := t.events.Subscribe()
+	defer unsubscribe()
+	denied := t.deniedTags(r)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(sseHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case ev, ok := <-events:
+			if !ok {
+				return
+			}
+			if t.hidesDraft(r, ev.Article) || restricted(ev.Article, denied) {
+				continue
+			}
+			data, err := json.Marshal(ev)
+			if err != nil {
+				log.Println(err)
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", ev.Type, data); err != nil {
+				log.Println(err)
+				return
+			}
+		case <-heartbeat.C:
+			if _, err := io.WriteString(w, ": heartbeat\n\n"); err != nil {
+				log.Println(err)
+				return
+			}
+		}
+		flusher.Flush()
+	}
+}
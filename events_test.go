@@ -0,0 +1,207 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newEventServer starts a server whose service publishes to a fresh event
+// bus that the transport streams from.
+func newEventServer(t *testing.T, opts ...transportOption) (*httptest.Server, *articleSvc, *eventBus) {
+	t.Helper()
+	bus := newEventBus()
+	svc := newTestSvc(withEventBus(bus))
+	srv := httptest.NewServer(newTestHandler(svc, append([]transportOption{withEvents(bus)}, opts...)...))
+	t.Cleanup(func() {
+		bus.Close()
+		srv.Close()
+	})
+	return srv, svc, bus
+}
+
+// sseEvent is one server-sent event read off a stream.
+type sseEvent struct {
+	Type string
+	Data string
+}
+
+// openEventStream connects to /articles/events. Once it returns, the stream
+// is subscribed and sees every later change.
+func openEventStream(t *testing.T, srv *httptest.Server, headers ...string) *bufio.Reader {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	req, err := http.NewRequestWithContext(ctx, "GET", srv.URL+"/articles/events", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	resp, err := srv.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("got %d %s, want a 200 event stream", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	return bufio.NewReader(resp.Body)
+}
+
+// readEvent returns the next event on stream, failing the test if none
+// arrives within a few seconds.
+func readEvent(t *testing.T, stream *bufio.Reader) sseEvent {
+	t.Helper()
+	got := make(chan sseEvent, 1)
+	errc := make(chan error, 1)
+	go func() {
+		var ev sseEvent
+		for {
+			line, err := stream.ReadString('\n')
+			if err != nil {
+				errc <- err
+				return
+			}
+			line = strings.TrimSuffix(line, "\n")
+			switch {
+			case strings.HasPrefix(line, "event: "):
+				ev.Type = strings.TrimPrefix(line, "event: ")
+			case strings.HasPrefix(line, "data: "):
+				ev.Data = strings.TrimPrefix(line, "data: ")
+			case line == "" && ev.Type != "":
+				got <- ev
+				return
+			}
+		}
+	}()
+
+	select {
+	case ev := <-got:
+		return ev
+	case err := <-errc:
+		t.Fatalf("reading the event stream: %v", err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("no event within 5s")
+	}
+	return sseEvent{}
+}
+
+// sseEnvelope decodes the articleEvent a server-sent event carries.
+func sseEnvelope(t *testing.T, ev sseEvent) articleEvent {
+	t.Helper()
+	var env articleEvent
+	if err := json.Unmarshal([]byte(ev.Data), &env); err != nil {
+		t.Fatalf("data %q: %v", ev.Data, err)
+	}
+	if env.Type != ev.Type {
+		t.Errorf("envelope type %q on a %q event", env.Type, ev.Type)
+	}
+	return env
+}
+
+// putArticle creates an article through the server.
+func putArticle(t *testing.T, srv *httptest.Server, body string, headers ...string) {
+	t.Helper()
+	req, err := http.NewRequest("PUT", srv.URL+"/articles", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	resp, err := srv.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("creating %s: status %d", body, resp.StatusCode)
+	}
+}
+
+func TestArticleEventsStream(t *testing.T) {
+	srv, svc, _ := newEventServer(t)
+	stream := openEventStream(t, srv)
+
+	putArticle(t, srv, `{"id":"a1","title":"First"}`)
+	ev := readEvent(t, stream)
+	if ev.Type != eventArticleCreated {
+		t.Errorf("type = %q, want %q", ev.Type, eventArticleCreated)
+	}
+	article := sseEnvelope(t, ev).Article
+	if article.ID != "a1" || article.Title != "First" {
+		t.Errorf("got article %+v, want a1 First", article)
+	}
+
+	if err := svc.DeleteArticle(context.Background(), "a1"); err != nil {
+		t.Fatal(err)
+	}
+	if ev := readEvent(t, stream); ev.Type != eventArticleDeleted {
+		t.Errorf("type = %q, want %q", ev.Type, eventArticleDeleted)
+	}
+}
+
+func TestArticleEventsEndWhenBusCloses(t *testing.T) {
+	srv, _, bus := newEventServer(t)
+	stream := openEventStream(t, srv)
+
+	bus.Close()
+	done := make(chan error, 1)
+	go func() {
+		_, err := stream.ReadString('\n')
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Error("stream kept going after the bus closed")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("stream still open 5s after the bus closed")
+	}
+}
+
+func TestArticleEventsCarryRequestID(t *testing.T) {
+	bus := newEventBus()
+	svc := newTestSvc(withEventBus(bus))
+	h := requestIDMiddleware("X-Request-ID", newTestHandler(svc, withEvents(bus)))
+	srv := httptest.NewServer(h)
+	t.Cleanup(func() {
+		bus.Close()
+		srv.Close()
+	})
+
+	stream := openEventStream(t, srv)
+	conn := dialArticlesWebSocket(t, srv, bus)
+	waitFor(t, "both streams to subscribe", func() bool {
+		bus.mu.RLock()
+		defer bus.mu.RUnlock()
+		return len(bus.subs) == 2
+	})
+
+	putArticle(t, srv, `{"id":"a1","title":"First"}`, "X-Request-ID", "trace-123")
+	if got := sseEnvelope(t, readEvent(t, stream)); got.RequestID != "trace-123" || got.Article.ID != "a1" {
+		t.Errorf("SSE event = %+v, want a1 with requestId trace-123", got)
+	}
+	if got := readWSEvent(t, conn); got.RequestID != "trace-123" || got.Article.ID != "a1" {
+		t.Errorf("WS event = %+v, want a1 with requestId trace-123", got)
+	}
+
+	// Changes made outside a request carry no ID.
+	if err := svc.DeleteArticle(context.Background(), "a1"); err != nil {
+		t.Fatal(err)
+	}
+	if got := sseEnvelope(t, readEvent(t, stream)); got.RequestID != "" {
+		t.Errorf("SSE event for a direct delete has requestId %q", got.RequestID)
+	}
+	if got := readWSEvent(t, conn); got.RequestID != "" {
+		t.Errorf("WS event for a direct delete has requestId %q", got.RequestID)
+	}
+}
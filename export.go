@@ -0,0 +1,191 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/gorilla/mux"
+	"gopkg.in/yaml.v3"
+)
+
+// exportSlug is the slug an article is exported under: its stored slug
+// made safe for file names, or else its slugified title or ID. It is safe to
+// use as a file name.
+func exportSlug(article Article) string {
+	if slug := fileNameSafe(article.Slug); slug != "" {
+		return slug
+	}
+	if slug := slugify(article.Title); slug != "" {
+		return slug
+	}
+	if slug := slugify(article.ID); slug != "" {
+		return slug
+	}
+	return "article"
+}
+
+// fileNameSafe replaces every rune other than a letter, digit, dash or
+// underscore with a dash, so custom slug strategies can't produce paths.
+func fileNameSafe(slug string) string {
+	return strings.Trim(strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '_' {
+			return r
+		}
+		return '-'
+	}, slug), "-")
+}
+
+type frontMatter struct {
+	Title string   `yaml:"title"`
+	Tags  []string `yaml:"tags,omitempty"`
+	Date  string   `yaml:"date,omitempty"`
+	Slug  string   `yaml:"slug"`
+	Draft bool     `yaml:"draft,omitempty"`
+}
+
+// markdownExport renders an article as Markdown with YAML front matter, as
+// read by Hugo and Jekyll.
+func markdownExport(article Article, slug string) ([]byte, error) {
+	fm := frontMatter{
+		Title: article.Title,
+		Tags:  article.Tags,
+		Slug:  slug,
+		Draft: article.Status == StatusDraft,
+	}
+	if !article.PublishAt.IsZero() {
+		fm.Date = article.PublishAt.UTC().Format(time.RFC3339)
+	}
+
+	header, err := yaml.Marshal(fm)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("---\n")
+	buf.Write(header)
+	buf.WriteString("---\n\n")
+	buf.WriteString(article.Content)
+	if !strings.HasSuffix(article.Content, "\n") {
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes(), nil
+}
+
+func (t *articlesHttpTransport) exportMarkdown(w http.ResponseWriter, r *http.Request) {
+	articleID := mux.Vars(r)["id"]
+	article, err := t.requestedArticle(r, articleID)
+	if err == nil && t.hidesDraft(r, *article) {
+		err = ErrArticleNotFound
+	}
+	if err != nil {
+		log.Println(err)
+		if errors.Is(err, ErrArticleNotFound) {
+			w.WriteHeader(http.StatusNotFound)
+		} else {
+			w.WriteHeader(serverErrorStatus(err))
+		}
+		io.WriteString(w, errorBody(err))
+		return
+	}
+	if restricted(*article, t.deniedTags(r)) {
+		t.writeRestricted(w, articleID)
+		return
+	}
+
+	slug := exportSlug(*article)
+	data, err := markdownExport(*article, slug)
+	if err != nil {
+		log.Println(err)
+		w.WriteHeader(serverErrorStatus(err))
+		io.WriteString(w, errorBody(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.md"`, slug))
+	w.Write(data)
+}
+
+// exportFlushEvery is how many zip entries are written between flushes of
+// the export stream.
+const exportFlushEvery = 100
+
+// exportZip streams every article as a Markdown file in a zip archive. The
+// archive is written straight to the response while the repo is iterated,
+// so memory stays flat however many articles there are; only the used file
+// names are kept. Articles whose slugs collide get a numeric suffix, in
+// iteration order. Drafts and restricted articles are left out for callers
+// who can't see them.
+//
+// Once the first entry is sent the status can't change, so a later failure
+// is only logged and leaves the client with a truncated archive.
+func (t *articlesHttpTransport) exportZip(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", `attachment; filename="articles.zip"`)
+
+	rc := http.NewResponseController(w)
+	denied := t.deniedTags(r)
+	zw := zip.NewWriter(w)
+	used := make(map[string]bool)
+	written := 0
+	err := t.svc.EachArticle(r.Context(), func(article Article) error {
+		if t.hidesDraft(r, article) {
+			return nil
+		}
+		if restricted(article, denied) {
+			return nil
+		}
+
+		slug := exportSlug(article)
+		name := slug
+		for i := 2; used[name]; i++ {
+			name = fmt.Sprintf("%s-%d", slug, i)
+		}
+		used[name] = true
+
+		data, err := markdownExport(article, name)
+		if err != nil {
+			return err
+		}
+		f, err := zw.CreateHeader(&zip.FileHeader{Name: name + ".md", Method: zip.Deflate, Modified: article.ModifiedAt})
+		if err != nil {
+			return err
+		}
+		if _, err := f.Write(data); err != nil {
+			return err
+		}
+
+		written++
+		if written%exportFlushEvery == 0 {
+			if err := zw.Flush(); err != nil {
+				return err
+			}
+			if err := rc.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		log.Println(err)
+		if written == 0 {
+			w.Header().Del("Content-Type")
+			w.Header().Del("Content-Disposition")
+			w.WriteHeader(serverErrorStatus(err))
+			io.WriteString(w, errorBody(err))
+		}
+		return
+	}
+	if err := zw.Close(); err != nil {
+		log.Println(err)
+	}
+}
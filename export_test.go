@@ -0,0 +1,189 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// splitFrontMatter parses a Markdown export into its front matter and body.
+func splitFrontMatter(t *testing.T, data []byte) (frontMatter, string) {
+	t.Helper()
+	rest, ok := strings.CutPrefix(string(data), "---\n")
+	if !ok {
+		t.Fatalf("export does not open with front matter:\n%s", data)
+	}
+	header, body, ok := strings.Cut(rest, "---\n\n")
+	if !ok {
+		t.Fatalf("front matter is not closed:\n%s", data)
+	}
+	var fm frontMatter
+	if err := yaml.Unmarshal([]byte(header), &fm); err != nil {
+		t.Fatalf("front matter %q: %v", header, err)
+	}
+	return fm, body
+}
+
+func TestExportMarkdown(t *testing.T) {
+	publishAt := time.Date(2024, 3, 1, 9, 30, 0, 0, time.FixedZone("CET", 3600))
+	// Published articles without a PublishAt get the time they were added.
+	now := time.Date(2024, 4, 2, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		article Article
+		want    frontMatter
+		body    string
+	}{
+		{
+			"every field",
+			Article{ID: "a1", Title: "Hello: World", Tags: []string{"go", "yaml"}, Content: "Some *text*.\n", PublishAt: publishAt},
+			frontMatter{Title: "Hello: World", Tags: []string{"go", "yaml"}, Date: "2024-03-01T08:30:00Z", Slug: "hello-world"},
+			"Some *text*.\n",
+		},
+		{
+			"no tags or PublishAt",
+			Article{ID: "a2", Title: "Bare", Content: "no newline"},
+			frontMatter{Title: "Bare", Date: "2024-04-02T12:00:00Z", Slug: "bare"},
+			"no newline\n",
+		},
+		{
+			"draft",
+			Article{ID: "a3", Title: "Later", Status: StatusDraft},
+			frontMatter{Title: "Later", Slug: "later", Draft: true},
+			"\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := newTestSvc(withClock(newFakeClock(now)))
+			mustAdd(t, svc, tt.article)
+			rec := doRequest(newTestHandler(svc), "GET", "/articles/"+tt.article.ID+"/export.md", "")
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d %s", rec.Code, rec.Body)
+			}
+			if got := rec.Header().Get("Content-Type"); got != "text/markdown; charset=utf-8" {
+				t.Errorf("Content-Type = %q", got)
+			}
+			if got, want := rec.Header().Get("Content-Disposition"), `attachment; filename="`+tt.want.Slug+`.md"`; got != want {
+				t.Errorf("Content-Disposition = %q, want %q", got, want)
+			}
+
+			fm, body := splitFrontMatter(t, rec.Body.Bytes())
+			if fm.Title != tt.want.Title || fm.Date != tt.want.Date || fm.Slug != tt.want.Slug ||
+				fm.Draft != tt.want.Draft || !slices.Equal(fm.Tags, tt.want.Tags) {
+				t.Errorf("front matter = %+v, want %+v", fm, tt.want)
+			}
+			if body != tt.body {
+				t.Errorf("body = %q, want %q", body, tt.body)
+			}
+		})
+	}
+
+	if rec := doRequest(newTestHandler(newTestSvc()), "GET", "/articles/nope/export.md", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown article: status = %d, want 404", rec.Code)
+	}
+}
+
+func TestExportSlug(t *testing.T) {
+	tests := []struct {
+		article Article
+		want    string
+	}{
+		{Article{ID: "a1", Slug: "hello-world", Title: "Ignored"}, "hello-world"},
+		{Article{ID: "a1", Slug: "../../etc/passwd"}, "etc-passwd"},
+		{Article{ID: "a1", Slug: `C:\temp\x`}, "C--temp-x"},
+		{Article{ID: "a1", Slug: "café_1"}, "café_1"},
+		{Article{ID: "a1", Slug: "///", Title: "From the Title"}, "from-the-title"},
+		{Article{ID: "Weird ID!", Title: "???"}, "weird-id"},
+		{Article{ID: "..."}, "article"},
+	}
+	for _, tt := range tests {
+		if got := exportSlug(tt.article); got != tt.want {
+			t.Errorf("exportSlug(%+v) = %q, want %q", tt.article, got, tt.want)
+		}
+	}
+}
+
+func TestExportZip(t *testing.T) {
+	// A custom strategy whose distinct slugs collide once made safe for
+	// file names.
+	svc := newTestSvc(withSlugFunc(func(title string) string { return title }))
+	mustAdd(t, svc,
+		Article{ID: "a1", Title: "a/b", Content: "first"},
+		Article{ID: "a2", Title: "a.b", Content: "second"},
+		Article{ID: "a3", Title: "../up", Content: "third", Tags: []string{"go"}},
+	)
+
+	rec := doRequest(newTestHandler(svc), "GET", "/articles/export.zip", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d %s", rec.Code, rec.Body)
+	}
+	if got := rec.Header().Get("Content-Type"); got != "application/zip" {
+		t.Errorf("Content-Type = %q", got)
+	}
+	if got := rec.Header().Get("Content-Disposition"); got != `attachment; filename="articles.zip"` {
+		t.Errorf("Content-Disposition = %q", got)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(rec.Body.Bytes()), int64(rec.Body.Len()))
+	if err != nil {
+		t.Fatalf("not a well-formed zip: %v", err)
+	}
+	contents := make(map[string]string)
+	for _, f := range zr.File {
+		if strings.ContainsAny(f.Name, `/\`) || !strings.HasSuffix(f.Name, ".md") {
+			t.Errorf("entry %q is not a plain .md file name", f.Name)
+		}
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatalf("%s: %v", f.Name, err)
+		}
+		fm, body := splitFrontMatter(t, data)
+		if fm.Slug+".md" != f.Name {
+			t.Errorf("%s carries slug %q", f.Name, fm.Slug)
+		}
+		contents[f.Name] = strings.TrimSpace(body)
+	}
+
+	var names []string
+	for name := range contents {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	if want := []string{"a-b-2.md", "a-b.md", "up.md"}; !slices.Equal(names, want) {
+		t.Fatalf("entries = %v, want %v", names, want)
+	}
+	if contents["up.md"] != "third" {
+		t.Errorf("up.md holds %q, want third", contents["up.md"])
+	}
+	if bodies := []string{contents["a-b.md"], contents["a-b-2.md"]}; !slices.Contains(bodies, "first") || !slices.Contains(bodies, "second") {
+		t.Errorf("colliding entries hold %q, want first and second", bodies)
+	}
+}
+
+func TestExportZipEmpty(t *testing.T) {
+	rec := doRequest(newTestHandler(newTestSvc()), "GET", "/articles/export.zip", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d", rec.Code)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(rec.Body.Bytes()), int64(rec.Body.Len()))
+	if err != nil {
+		t.Fatalf("not a well-formed zip: %v", err)
+	}
+	if len(zr.File) != 0 {
+		t.Errorf("%d entries, want none", len(zr.File))
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// featureExperimentalMarkdown renders previews with footnotes and
+// typographic punctuation on top of GFM.
+const featureExperimentalMarkdown = "experimental-markdown"
+
+// knownFeatures lists the feature flags handlers consult.
+var knownFeatures = map[string]bool{
+	featureExperimentalMarkdown: true,
+}
+
+// checkFeatureFlags reports the first name that isn't a known feature flag.
+func checkFeatureFlags(names []string) error {
+	for _, name := range names {
+		if !knownFeatures[name] {
+			return fmt.Errorf("-feature-flags: unknown feature %q", name)
+		}
+	}
+	return nil
+}
+
+type featureFlagsKey struct{}
+
+// featureFlagsMiddleware stores the features a request turns on with the
+// comma separated X-Feature-Flags header in its context. Only features in
+// allowed can be turned on this way; the header is ignored entirely when
+// allowed is empty.
+func featureFlagsMiddleware(allowed []string, next http.Handler) http.Handler {
+	permitted := make(map[string]bool, len(allowed))
+	for _, name := range allowed {
+		permitted[name] = true
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var enabled map[string]bool
+		for _, name := range splitList(r.Header.Get("X-Feature-Flags")) {
+			if name = strings.ToLower(name); permitted[name] {
+				if enabled == nil {
+					enabled = make(map[string]bool)
+				}
+				enabled[name] = true
+			}
+		}
+		if enabled != nil {
+			r = r.WithContext(context.WithValue(r.Context(), featureFlagsKey{}, enabled))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// featureEnabled reports whether the request in ctx turned feature name on.
+func featureEnabled(ctx context.Context, name string) bool {
+	enabled, _ := ctx.Value(featureFlagsKey{}).(map[string]bool)
+	return enabled[name]
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestFeatureFlagsMiddleware(t *testing.T) {
+	tests := []struct {
+		name    string
+		allowed []string
+		header  string
+		want    bool
+	}{
+		{"allowed and requested", []string{featureExperimentalMarkdown}, featureExperimentalMarkdown, true},
+		{"among others", []string{featureExperimentalMarkdown}, "other, Experimental-Markdown ,", true},
+		{"not requested", []string{featureExperimentalMarkdown}, "", false},
+		{"requested but not allowed", nil, featureExperimentalMarkdown, false},
+		{"unknown flag", []string{featureExperimentalMarkdown}, "experimental", false},
+	}
+	for _, tt := range tests {
+		var got bool
+		h := featureFlagsMiddleware(tt.allowed, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			got = featureEnabled(r.Context(), featureExperimentalMarkdown)
+		}))
+		doRequest(h, "GET", "/", "", "X-Feature-Flags", tt.header)
+		if got != tt.want {
+			t.Errorf("%s: enabled = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestFeatureFlagGatesPreview(t *testing.T) {
+	body, _ := json.Marshal(previewRequest{Content: `"quoted"`})
+	preview := http.HandlerFunc(newArticlesHttpTransport(newTestSvc()).previewMarkdown)
+
+	tests := []struct {
+		name    string
+		allowed []string
+		header  string
+		// want is whether the typographer of experimental Markdown ran.
+		want bool
+	}{
+		{"flag on", []string{featureExperimentalMarkdown}, featureExperimentalMarkdown, true},
+		{"no header", []string{featureExperimentalMarkdown}, "", false},
+		{"flags not allowed", nil, featureExperimentalMarkdown, false},
+	}
+	for _, tt := range tests {
+		rec := doRequest(featureFlagsMiddleware(tt.allowed, preview), "POST", "/preview", string(body), "X-Feature-Flags", tt.header)
+		var resp previewResponse
+		decodeJSON(t, rec, &resp)
+		if got := strings.Contains(resp.HTML, "&ldquo;"); got != tt.want {
+			t.Errorf("%s: html = %q, want typographic quotes = %v", tt.name, resp.HTML, tt.want)
+		}
+	}
+}
+
+func TestCheckFeatureFlags(t *testing.T) {
+	if err := checkFeatureFlags([]string{featureExperimentalMarkdown}); err != nil {
+		t.Errorf("known flag: %v", err)
+	}
+	if err := checkFeatureFlags([]string{featureExperimentalMarkdown, "bogus"}); err == nil || !strings.Contains(err.Error(), "bogus") {
+		t.Errorf("unknown flag: err = %v, want it named", err)
+	}
+}
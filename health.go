@@ -0,0 +1,20 @@
+package main
+
+import (
+	"net/http"
+	"sync/atomic"
+)
+
+type healthStatus struct {
+	Status string `json:"status"`
+	// InFlight counts the requests being served, this one included.
+	InFlight int64 `json:"inFlight"`
+}
+
+// healthz reports that the server is up along with its in-flight request
+// count.
+func healthz(inFlight *atomic.Int64) http.HandlerFunc {
+	return func(w http.ResponseWriter, _ *http.Request) {
+		writeEncoded(w, jsonEncoding{}, http.StatusOK, healthStatus{Status: "ok", InFlight: inFlight.Load()})
+	}
+}
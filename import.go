@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+)
+
+const (
+	ndjsonContentType = "application/x-ndjson"
+	// maxImportLineBytes caps the size of a single NDJSON line.
+	maxImportLineBytes = 1 << 20
+	// maxImportErrors caps how many line errors an import reports, so a
+	// bad million-line file doesn't turn into a million-entry response.
+	maxImportErrors = 100
+)
+
+type importLineError struct {
+	Line  int    `json:"line"`
+	Error string `json:"error"`
+}
+
+// importSummary is the response of POST /articles/import. Errors holds at
+// most maxImportErrors entries; Failed always has the full count. Aborted is
+// set when the body could not be read to the end, in which case the counts
+// cover the lines processed so far.
+type importSummary struct {
+	Inserted int               `json:"inserted"`
+	Failed   int               `json:"failed"`
+	Errors   []importLineError `json:"errors"`
+	Aborted  string            `json:"aborted,omitempty"`
+}
+
+func (s *importSummary) fail(line int, err error) {
+	s.Failed++
+	if len(s.Errors) < maxImportErrors {
+		s.Errors = append(s.Errors, importLineError{Line: line, Error: errorBody(err)})
+	}
+}
+
+// importArticles creates articles from an NDJSON body, one article per
+// line. Lines are decoded and stored as they stream in, so memory use does
+// not grow with the size of the import. A bad line is recorded and skipped;
+// blank lines are ignored. The body is capped by -max-batch-body-bytes, not
+// -max-body-bytes, so large imports need that raised or disabled.
+func (t *articlesHttpTransport) importArticles(w http.ResponseWriter, r *http.Request) {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || mediaType != ndjsonContentType {
+		w.WriteHeader(http.StatusUnsupportedMediaType)
+		io.WriteString(w, "import expects "+ndjsonContentType)
+		return
+	}
+
+	summary := importSummary{Errors: []importLineError{}}
+	scanner := bufio.NewScanner(r.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxImportLineBytes)
+
+	line := 0
+	for scanner.Scan() {
+		line++
+		data := bytes.TrimSpace(scanner.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+
+		var article Article
+		if err := json.Unmarshal(data, &article); err != nil {
+			summary.fail(line, err)
+			continue
+		}
+		if _, err := t.svc.AddArticle(r.Context(), article); err != nil {
+			summary.fail(line, err)
+			continue
+		}
+		summary.Inserted++
+	}
+
+	status := http.StatusOK
+	if err := scanner.Err(); err != nil {
+		log.Println(err)
+		var tooLarge *http.MaxBytesError
+		switch {
+		case errors.As(err, &tooLarge):
+			status = http.StatusRequestEntityTooLarge
+			summary.Aborted = "request body too large"
+		case errors.Is(err, bufio.ErrTooLong):
+			status = http.StatusRequestEntityTooLarge
+			summary.Aborted = "line exceeds 1 MiB"
+		default:
+			status = http.StatusBadRequest
+			summary.Aborted = err.Error()
+		}
+	}
+
+	t.writeJSON(w, r, status, summary)
+}
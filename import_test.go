@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestImportArticles(t *testing.T) {
+	svc := newTestSvc()
+	mustAdd(t, svc, Article{ID: "taken", Title: "Taken"})
+	h := newTestHandler(svc)
+
+	body := strings.Join([]string{
+		`{"id":"a1","title":"One"}`,
+		`{"id":"a2","title":"Two"`,
+		``,
+		`{"id":"a3","title":""}`,
+		`{"id":"taken","title":"Again"}`,
+		`   `,
+		`{"id":"a4","title":"Four","tags":["go"]}`,
+	}, "\n")
+
+	rec := doRequest(h, "POST", "/articles/import", body, "Content-Type", ndjsonContentType)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+	}
+	var summary importSummary
+	decodeJSON(t, rec, &summary)
+
+	if summary.Inserted != 2 || summary.Failed != 3 || summary.Aborted != "" {
+		t.Errorf("summary = %+v, want 2 inserted and 3 failed", summary)
+	}
+	var lines []int
+	for _, e := range summary.Errors {
+		lines = append(lines, e.Line)
+		if e.Error == "" {
+			t.Errorf("line %d has no error message", e.Line)
+		}
+	}
+	if !slices.Equal(lines, []int{2, 4, 5}) {
+		t.Errorf("error lines = %v, want [2 4 5]", lines)
+	}
+	if ids := storedIDs(t, svc.repo); !slices.Equal(ids, []string{"a1", "a4", "taken"}) {
+		t.Errorf("stored %v, want [a1 a4 taken]", ids)
+	}
+	if got := mustGet(t, svc, "taken"); got.Title != "Taken" {
+		t.Errorf("import overwrote an existing article: %+v", got)
+	}
+}
+
+// TestImportArticlesStreams checks that lines are stored as they arrive,
+// before the body is complete.
+func TestImportArticlesStreams(t *testing.T) {
+	svc := newTestSvc()
+	h := newTestHandler(svc)
+
+	body, writer := io.Pipe()
+	req := httptest.NewRequest("POST", "/articles/import", body)
+	req.Header.Set("Content-Type", ndjsonContentType)
+	rec := httptest.NewRecorder()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		h.ServeHTTP(rec, req)
+	}()
+
+	io.WriteString(writer, `{"id":"a1","title":"One"}`+"\n")
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, err := svc.repo.ArticleByID(context.Background(), "a1"); err == nil {
+			break
+		} else if !errors.Is(err, ErrArticleNotFound) {
+			t.Fatal(err)
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("the first line was not stored before the body ended")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	io.WriteString(writer, `{"id":"a2","title":"Two"}`+"\n")
+	writer.Close()
+	<-done
+
+	var summary importSummary
+	decodeJSON(t, rec, &summary)
+	if summary.Inserted != 2 || summary.Failed != 0 {
+		t.Errorf("summary = %+v, want 2 inserted", summary)
+	}
+}
+
+func TestImportArticlesRejects(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		wantCode    int
+		wantAborted string
+	}{
+		{"wrong content type", "application/json", `{"id":"a1","title":"T"}`, http.StatusUnsupportedMediaType, ""},
+		{"line too long", ndjsonContentType, `{"id":"a1","title":"T"}` + "\n" + strings.Repeat("x", maxImportLineBytes+1), http.StatusRequestEntityTooLarge, "line exceeds 1 MiB"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := newTestSvc()
+			rec := doRequest(newTestHandler(svc), "POST", "/articles/import", tt.body, "Content-Type", tt.contentType)
+			if rec.Code != tt.wantCode {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantCode, rec.Body)
+			}
+			if tt.wantAborted == "" {
+				return
+			}
+			var summary importSummary
+			decodeJSON(t, rec, &summary)
+			if summary.Aborted != tt.wantAborted || summary.Inserted != 1 {
+				t.Errorf("summary = %+v, want 1 inserted and aborted %q", summary, tt.wantAborted)
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// maxListCacheEntries bounds how many distinct listings are cached at once.
+// Listings for new query strings are not cached while it is full.
+const maxListCacheEntries = 64
+
+// cachedList is a rendered GET /articles response.
+type cachedList struct {
+	body       []byte
+	nextCursor string
+	truncated  bool
+	empty      bool
+	// total counts the matching articles before pagination, and
+	// lastModified is the latest ModifiedAt among them.
+	total        int
+	lastModified time.Time
+	expires      time.Time
+}
+
+// listCache keeps rendered article listings for a short TTL, keyed by
+// response format and query string, so heavy polling doesn't rebuild the
+// list on every request. Concurrent misses for the same key share one
+// build, unless a write lands between them. The service empties the cache on
+// every write; see withListInvalidation.
+type listCache struct {
+	ttl    time.Duration
+	clock  Clock
+	builds singleflight.Group
+
+	mu sync.Mutex
+	// gen counts invalidations, so a build that raced with a write
+	// doesn't store its stale result.
+	gen     uint64
+	entries map[string]cachedList
+}
+
+func newListCache(ttl time.Duration, clock Clock) *listCache {
+	return &listCache{
+		ttl:     ttl,
+		clock:   clock,
+		entries: make(map[string]cachedList),
+	}
+}
+
+// withListCache serves GET /articles from c; nil disables caching.
+func withListCache(c *listCache) transportOption {
+	return func(t *articlesHttpTransport) {
+		t.lists = c
+	}
+}
+
+// withListInvalidation makes the service empty c after every write, before
+// the write returns. Invalidating through the event bus instead would let
+// a dropped or late event keep a stale listing around for the whole TTL.
+func withListInvalidation(c *listCache) svcOption {
+	return func(svc *articleSvc) {
+		svc.lists = c
+	}
+}
+
+func (c *listCache) invalidate() {
+	c.mu.Lock()
+	c.gen++
+	c.entries = make(map[string]cachedList)
+	c.mu.Unlock()
+}
+
+// get returns the cached listing for key, calling build on a miss.
+// Failed builds are not cached. Misses only share a build started in the
+// same generation, so a read that follows a write never receives a listing
+// built before it.
+func (c *listCache) get(key string, build func() (cachedList, error)) (cachedList, error) {
+	c.mu.Lock()
+	if list, ok := c.entries[key]; ok && c.clock.Now().Before(list.expires) {
+		c.mu.Unlock()
+		return list, nil
+	}
+	gen := c.gen
+	c.mu.Unlock()
+
+	v, err, _ := c.builds.Do(strconv.FormatUint(gen, 10)+" "+key, func() (interface{}, error) {
+		list, err := build()
+		if err != nil {
+			return nil, err
+		}
+		c.store(key, gen, list)
+		return list, nil
+	})
+	if err != nil {
+		return cachedList{}, err
+	}
+	return v.(cachedList), nil
+}
+
+// store caches list unless the cache was invalidated since gen.
+func (c *listCache) store(key string, gen uint64, list cachedList) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.gen != gen {
+		return
+	}
+	now := c.clock.Now()
+	if len(c.entries) >= maxListCacheEntries {
+		for k, cached := range c.entries {
+			if !now.Before(cached.expires) {
+				delete(c.entries, k)
+			}
+		}
+		if len(c.entries) >= maxListCacheEntries {
+			return
+		}
+	}
+	list.expires = now.Add(c.ttl)
+	c.entries[key] = list
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// listCountingRepo is an inMemoryRepo that counts AllArticles calls and,
+// while gate is non-nil, holds each of them until gate is closed.
+type listCountingRepo struct {
+	*inMemoryRepo
+	all     atomic.Int64
+	gate    chan struct{}
+	entered chan struct{}
+}
+
+func newListCountingRepo() *listCountingRepo {
+	return &listCountingRepo{inMemoryRepo: newInMemoryRepo(), entered: make(chan struct{}, 1024)}
+}
+
+func (repo *listCountingRepo) AllArticles(ctx context.Context) ([]Article, error) {
+	repo.all.Add(1)
+	repo.entered <- struct{}{}
+	if repo.gate != nil {
+		<-repo.gate
+	}
+	return repo.inMemoryRepo.AllArticles(ctx)
+}
+
+// newCachedListHandler serves svc's listings from a cache with ttl that the
+// service invalidates.
+func newCachedListHandler(repo ArticlesRepo, ttl time.Duration, opts ...svcOption) (http.Handler, *articleSvc) {
+	lists := newListCache(ttl, realClock{})
+	svc := newArticleSvc(repo, append([]svcOption{withListInvalidation(lists)}, opts...)...)
+	return newTestHandler(svc, withListCache(lists)), svc
+}
+
+func TestListCacheHitsRepoOnce(t *testing.T) {
+	repo := newListCountingRepo()
+	h, svc := newCachedListHandler(repo, time.Minute)
+	mustAdd(t, svc, Article{ID: "a1", Title: "A"}, Article{ID: "a2", Title: "B"})
+
+	for i := 0; i < 5; i++ {
+		if rec := doRequest(h, "GET", "/articles", ""); rec.Code != http.StatusOK {
+			t.Fatalf("status = %d", rec.Code)
+		}
+	}
+	if n := repo.all.Load(); n != 1 {
+		t.Errorf("5 identical listings hit the repo %d times, want 1", n)
+	}
+
+	// Another query is another listing.
+	doRequest(h, "GET", "/articles?pinned=false", "")
+	doRequest(h, "GET", "/articles?pinned=false", "")
+	if n := repo.all.Load(); n != 2 {
+		t.Errorf("after a second query the repo was hit %d times, want 2", n)
+	}
+}
+
+func TestListCacheFreshAfterWrite(t *testing.T) {
+	// A subscriber that never reads fills its buffer, so the bus drops
+	// events; invalidation must not depend on them.
+	bus := newEventBus()
+	bus.Subscribe()
+	t.Cleanup(bus.Close)
+	h, _ := newCachedListHandler(newInMemoryRepo(), time.Minute, withEventBus(bus))
+
+	listed := func() []Article {
+		t.Helper()
+		rec := doRequest(h, "GET", "/articles", "")
+		var articles []Article
+		decodeJSON(t, rec, &articles)
+		return articles
+	}
+
+	for i := 0; i < 50; i++ {
+		listed()
+		id := "a" + strconv.Itoa(i)
+		if rec := doRequest(h, "PUT", "/articles", `{"id":"`+id+`","title":"T"}`); rec.Code != http.StatusCreated {
+			t.Fatalf("creating %s: status %d", id, rec.Code)
+		}
+		if got := listed(); len(got) != i+1 {
+			t.Fatalf("right after creating %s the listing has %d articles, want %d", id, len(got), i+1)
+		}
+	}
+
+	if rec := doRequest(h, "PUT", "/articles/a0", `{"title":"Renamed"}`); rec.Code != http.StatusOK {
+		t.Fatalf("updating a0: status %d", rec.Code)
+	}
+	for _, article := range listed() {
+		if article.ID == "a0" && article.Title != "Renamed" {
+			t.Errorf("right after the update a0 is listed as %q", article.Title)
+		}
+	}
+
+	if rec := doRequest(h, "DELETE", "/articles/a0", ""); rec.Code != http.StatusNoContent {
+		t.Fatalf("deleting a0: status %d", rec.Code)
+	}
+	if got := listed(); len(got) != 49 {
+		t.Errorf("right after the delete the listing has %d articles, want 49", len(got))
+	}
+}
+
+func TestListCacheCoalescesConcurrentBuilds(t *testing.T) {
+	repo := newListCountingRepo()
+	// A zero TTL caches nothing, so only the shared build can spare the
+	// repo.
+	h, svc := newCachedListHandler(repo, 0)
+	mustAdd(t, svc, Article{ID: "a1", Title: "A"})
+	repo.gate = make(chan struct{})
+
+	const readers = 50
+	var (
+		started sync.WaitGroup
+		done    sync.WaitGroup
+		bodies  = make(chan string, readers)
+	)
+	started.Add(readers)
+	done.Add(readers)
+	for i := 0; i < readers; i++ {
+		go func() {
+			defer done.Done()
+			started.Done()
+			rec := doRequest(h, "GET", "/articles", "")
+			if rec.Code != http.StatusOK {
+				t.Errorf("a reader got %d, want 200", rec.Code)
+			}
+			bodies <- rec.Body.String()
+		}()
+	}
+
+	<-repo.entered
+	started.Wait()
+	// Give the remaining readers time to join the build in flight.
+	time.Sleep(50 * time.Millisecond)
+	close(repo.gate)
+	done.Wait()
+	close(bodies)
+
+	first := <-bodies
+	for body := range bodies {
+		if body != first {
+			t.Errorf("readers got different listings: %q and %q", first, body)
+		}
+	}
+	if n := repo.all.Load(); n != 1 {
+		t.Errorf("repo was hit %d times by %d concurrent listings, want 1", n, readers)
+	}
+}
+
+func TestListCacheWriteDuringBuild(t *testing.T) {
+	repo := newListCountingRepo()
+	h, svc := newCachedListHandler(repo, time.Minute)
+	mustAdd(t, svc, Article{ID: "a1", Title: "A"})
+	repo.gate = make(chan struct{})
+
+	list := func(ids chan<- []string) {
+		rec := doRequest(h, "GET", "/articles", "")
+		var articles []Article
+		decodeJSON(t, rec, &articles)
+		ids <- articleIDs(articles)
+	}
+
+	before := make(chan []string, 1)
+	go list(before)
+	<-repo.entered
+	// The write lands while the first build is still reading the repo.
+	mustAdd(t, svc, Article{ID: "a2", Title: "B"})
+
+	after := make(chan []string, 1)
+	go list(after)
+	select {
+	case <-repo.entered:
+	case <-time.After(time.Second):
+		t.Error("the read after the write joined the build started before it")
+	}
+	close(repo.gate)
+
+	<-before
+	if got := <-after; !slices.Contains(got, "a2") {
+		t.Errorf("the read after the write listed %v, want a2 among them", got)
+	}
+}
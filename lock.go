@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// ErrArticleLocked is returned for updates and deletes of a locked article.
+var ErrArticleLocked = errors.New("article is locked")
+
+// SetLocked locks or unlocks an article, recording by as the locker.
+// Repeating the current state is a no-op.
+func (svc *articleSvc) SetLocked(ctx context.Context, id string, locked bool, by string) (*Article, error) {
+	article, err := svc.repo.ArticleByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if article.Locked == locked {
+		return article, nil
+	}
+
+	article.Locked = locked
+	article.LockedBy = ""
+	if locked {
+		article.LockedBy = by
+	}
+	if err := svc.updateArticle(ctx, *article, true); err != nil {
+		return nil, err
+	}
+	return svc.repo.ArticleByID(ctx, id)
+}
+
+func (t *articlesHttpTransport) lockArticle(w http.ResponseWriter, r *http.Request) {
+	t.setLocked(w, r, true)
+}
+
+func (t *articlesHttpTransport) unlockArticle(w http.ResponseWriter, r *http.Request) {
+	t.setLocked(w, r, false)
+}
+
+func (t *articlesHttpTransport) setLocked(w http.ResponseWriter, r *http.Request, locked bool) {
+	by := principalFromContext(r.Context()).Name
+	article, err := t.svc.SetLocked(r.Context(), mux.Vars(r)["id"], locked, by)
+	if err != nil {
+		log.Println(err)
+		if errors.Is(err, ErrArticleNotFound) {
+			w.WriteHeader(http.StatusNotFound)
+		} else {
+			w.WriteHeader(serverErrorStatus(err))
+		}
+		io.WriteString(w, errorBody(err))
+		return
+	}
+
+	log.Printf("article %s locked=%v by %s from %s", article.ID, locked, by, clientIP(r))
+	t.writeJSON(w, r, http.StatusOK, article)
+}
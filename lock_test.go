@@ -0,0 +1,101 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestLockedArticleRejectsMutations(t *testing.T) {
+	mergePatch := []string{"Content-Type", mergePatchContentType}
+
+	// Each mutation runs against a fresh article, locked and then unlocked.
+	tests := []struct {
+		name, method, target, body string
+		headers                    []string
+		unlocked                   int
+	}{
+		{"update", "PUT", "/articles/a1", `{"title":"Changed"}`, nil, http.StatusOK},
+		{"merge patch", "PATCH", "/articles/a1", `{"title":"Changed"}`, mergePatch, http.StatusOK},
+		{"json patch", "PATCH", "/articles/a1", `[{"op":"replace","path":"/title","value":"Changed"}]`, []string{"Content-Type", jsonPatchContentType}, http.StatusOK},
+		{"pin", "POST", "/articles/a1/pin", "", nil, http.StatusOK},
+		{"delete", "DELETE", "/articles/a1", "", nil, http.StatusNoContent},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := newTestSvc()
+			mustAdd(t, svc, Article{ID: "a1", Title: "T"})
+			s := newAdminTestServer(svc, false, nil)
+			headers := append([]string{"X-API-Key", testWriteKey}, tt.headers...)
+
+			if rec := doRequest(s, "POST", "/articles/a1/lock", "", "X-API-Key", testAdminKey); rec.Code != http.StatusOK {
+				t.Fatalf("lock: status = %d", rec.Code)
+			}
+			if rec := doRequest(s, tt.method, tt.target, tt.body, headers...); rec.Code != http.StatusLocked {
+				t.Errorf("while locked: status = %d, want 423", rec.Code)
+			}
+			if got := mustGet(t, svc, "a1"); got.Title != "T" || got.Pinned {
+				t.Errorf("while locked the article changed to %+v", got)
+			}
+
+			if rec := doRequest(s, "POST", "/articles/a1/unlock", "", "X-API-Key", testAdminKey); rec.Code != http.StatusOK {
+				t.Fatalf("unlock: status = %d", rec.Code)
+			}
+			if rec := doRequest(s, tt.method, tt.target, tt.body, headers...); rec.Code != tt.unlocked {
+				t.Errorf("after unlock: status = %d, want %d", rec.Code, tt.unlocked)
+			}
+		})
+	}
+}
+
+func TestLockedArticleInBatch(t *testing.T) {
+	svc := newTestSvc()
+	mustAdd(t, svc, Article{ID: "a1", Title: "T"}, Article{ID: "a2", Title: "T"})
+	s := newAdminTestServer(svc, false, nil)
+	doRequest(s, "POST", "/articles/a1/lock", "", "X-API-Key", testAdminKey)
+
+	rec := doRequest(s, "PATCH", "/articles/batch", `[{"id":"a1","patch":{"title":"X"}},{"id":"a2","patch":{"title":"X"}}]`, "X-API-Key", testWriteKey)
+	var results []batchPatchResult
+	decodeJSON(t, rec, &results)
+	want := []int{http.StatusLocked, http.StatusOK}
+	if len(results) != len(want) {
+		t.Fatalf("results = %+v", results)
+	}
+	for i, result := range results {
+		if result.Status != want[i] {
+			t.Errorf("%s: status = %d, want %d", result.ID, result.Status, want[i])
+		}
+	}
+}
+
+func TestLockRecordsLocker(t *testing.T) {
+	svc := newTestSvc()
+	mustAdd(t, svc, Article{ID: "a1", Title: "T"})
+	s := newAdminTestServer(svc, false, nil)
+
+	tests := []struct {
+		name, target, key string
+		want              int
+		locked            bool
+		lockedBy          string
+	}{
+		{"anonymous", "/articles/a1/lock", "", http.StatusUnauthorized, false, ""},
+		{"writer", "/articles/a1/lock", testWriteKey, http.StatusForbidden, false, ""},
+		{"admin", "/articles/a1/lock", testAdminKey, http.StatusOK, true, testAdminKey},
+		{"relock is a no-op", "/articles/a1/lock", testAdminKey, http.StatusOK, true, testAdminKey},
+		{"writer unlock", "/articles/a1/unlock", testWriteKey, http.StatusForbidden, true, testAdminKey},
+		{"admin unlock", "/articles/a1/unlock", testAdminKey, http.StatusOK, false, ""},
+		{"missing", "/articles/missing/lock", testAdminKey, http.StatusNotFound, false, ""},
+	}
+	for _, tt := range tests {
+		var headers []string
+		if tt.key != "" {
+			headers = []string{"X-API-Key", tt.key}
+		}
+		if rec := doRequest(s, "POST", tt.target, "", headers...); rec.Code != tt.want {
+			t.Errorf("%s: status = %d, want %d", tt.name, rec.Code, tt.want)
+		}
+		if got := mustGet(t, svc, "a1"); got.Locked != tt.locked || got.LockedBy != tt.lockedBy {
+			t.Errorf("%s: locked = %v by %q, want %v by %q", tt.name, got.Locked, got.LockedBy, tt.locked, tt.lockedBy)
+		}
+	}
+}
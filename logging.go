@@ -0,0 +1,34 @@
+package main
+
+import (
+	"io"
+	"log"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// setupLogging makes a text slog handler on w, filtered by level, the
+// default logger. The standard log package writes through it too, at info
+// level, so every log line honours level.
+func setupLogging(w io.Writer, level *slog.LevelVar) {
+	slog.SetDefault(slog.New(slog.NewTextHandler(w, &slog.HandlerOptions{Level: level})))
+}
+
+// toggleLogLevelOnSIGHUP switches level between info and debug every time
+// the process receives SIGHUP.
+func toggleLogLevelOnSIGHUP(level *slog.LevelVar) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			if level.Level() == slog.LevelDebug {
+				level.Set(slog.LevelInfo)
+			} else {
+				level.Set(slog.LevelDebug)
+			}
+			log.Printf("log level set to %s", level.Level())
+		}
+	}()
+}
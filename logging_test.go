@@ -0,0 +1,104 @@
+package main
+
+import (
+	"log"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"testing"
+)
+
+// captureSlog routes the default logger, and with it the log package,
+// through setupLogging into a buffer for the rest of the test.
+func captureSlog(t *testing.T, level *slog.LevelVar) *syncBuffer {
+	buf := new(syncBuffer)
+	prev, prevOutput, prevFlags := slog.Default(), log.Writer(), log.Flags()
+	setupLogging(buf, level)
+	t.Cleanup(func() {
+		// Restoring the default slog logger leaves the log package
+		// writing through the replaced handler, so put it back too.
+		slog.SetDefault(prev)
+		log.SetOutput(prevOutput)
+		log.SetFlags(prevFlags)
+	})
+	return buf
+}
+
+func TestLogLevelFlip(t *testing.T) {
+	s := newAdminTestServer(newTestSvc(), false, nil)
+	logs := captureSlog(t, s.logLevel)
+	var inFlight atomic.Int64
+	h := inFlightMiddleware(&inFlight, s)
+
+	// debugLogged serves a request and reports whether it wrote the debug
+	// line inFlightMiddleware logs for every request.
+	debugLogged := func(target string) bool {
+		t.Helper()
+		before := strings.Count(logs.String(), `level=DEBUG msg="request started"`)
+		if rec := doRequest(h, "GET", target, ""); rec.Code != http.StatusOK {
+			t.Fatalf("GET %s: status = %d", target, rec.Code)
+		}
+		return strings.Count(logs.String(), `level=DEBUG msg="request started"`) > before
+	}
+
+	if debugLogged("/articles") {
+		t.Error("debug line written at the default info level")
+	}
+
+	rec := doRequest(h, "POST", "/admin/loglevel", `{"level":"debug"}`, "X-API-Key", testAdminKey)
+	if rec.Code != http.StatusOK || rec.Body.String() != "{\"level\":\"DEBUG\"}\n" {
+		t.Fatalf("setting debug: got %d %s", rec.Code, rec.Body)
+	}
+	if !strings.Contains(logs.String(), "log level set to DEBUG by "+testAdminKey) {
+		t.Errorf("the change was not logged:\n%s", logs)
+	}
+	if !debugLogged("/articles") {
+		t.Errorf("no debug line for the next request:\n%s", logs)
+	}
+
+	if rec := doRequest(h, "POST", "/admin/loglevel", `{"level":"info"}`, "X-API-Key", testAdminKey); rec.Code != http.StatusOK {
+		t.Fatalf("setting info: got %d %s", rec.Code, rec.Body)
+	}
+	if debugLogged("/articles") {
+		t.Error("debug line written after switching back to info")
+	}
+
+	for _, body := range []string{`{"level":"loud"}`, `{"level":`} {
+		if rec := doRequest(h, "POST", "/admin/loglevel", body, "X-API-Key", testAdminKey); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", body, rec.Code)
+		}
+	}
+	if s.logLevel.Level() != slog.LevelInfo {
+		t.Errorf("level = %s after rejected changes, want INFO", s.logLevel.Level())
+	}
+	if rec := doRequest(h, "POST", "/admin/loglevel", `{"level":"debug"}`, "X-API-Key", testWriteKey); rec.Code != http.StatusForbidden {
+		t.Errorf("write key: status = %d, want 403", rec.Code)
+	}
+}
+
+func TestLogLevelSIGHUP(t *testing.T) {
+	level := new(slog.LevelVar)
+	logs := captureSlog(t, level)
+	toggleLogLevelOnSIGHUP(level)
+
+	for _, want := range []slog.Level{slog.LevelDebug, slog.LevelInfo} {
+		if err := syscall.Kill(os.Getpid(), syscall.SIGHUP); err != nil {
+			t.Fatal(err)
+		}
+		waitFor(t, "the level to become "+want.String(), func() bool { return level.Level() == want })
+		waitFor(t, "the change to be logged", func() bool {
+			return strings.Contains(logs.String(), "log level set to "+want.String())
+		})
+	}
+
+	level.Set(slog.LevelDebug)
+	slog.Debug("visible")
+	level.Set(slog.LevelInfo)
+	slog.Debug("hidden")
+	if !strings.Contains(logs.String(), "msg=visible") || strings.Contains(logs.String(), "msg=hidden") {
+		t.Errorf("level changes did not apply to the next line:\n%s", logs)
+	}
+}
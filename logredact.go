@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+	"unicode/utf8"
+)
+
+// articleLogFields are the fields, by JSON name, that article payloads are
+// logged with, and that -log-omit-fields may name.
+var articleLogFields = []string{"id", "title", "tags", "content", "publishAt", "attachments", "pinned", "status", "slug", "excerpt"}
+
+// logRedaction shapes article payloads written to the debug log, so large or
+// sensitive content doesn't end up there verbatim.
+type logRedaction struct {
+	// contentChars is how much of Content and Excerpt is logged; the rest
+	// is replaced by its length. Zero logs only the length.
+	contentChars int
+	// omit holds the JSON names of fields left out altogether.
+	omit map[string]bool
+}
+
+func newLogRedaction(contentChars int, omit []string) logRedaction {
+	p := logRedaction{contentChars: contentChars, omit: make(map[string]bool, len(omit))}
+	for _, field := range omit {
+		p.omit[field] = true
+	}
+	return p
+}
+
+// checkLogOmitFields reports the first name that isn't a logged field.
+func checkLogOmitFields(names []string) error {
+	for _, name := range names {
+		if !slices.Contains(articleLogFields, name) {
+			return fmt.Errorf("-log-omit-fields: unknown field %q", name)
+		}
+	}
+	return nil
+}
+
+// article returns the log value of a, with long text cut short and omitted
+// fields dropped.
+func (p logRedaction) article(a Article) slog.Value {
+	values := map[string]slog.Value{
+		"id":          slog.StringValue(a.ID),
+		"title":       slog.StringValue(a.Title),
+		"tags":        slog.AnyValue(a.Tags),
+		"content":     slog.StringValue(p.truncate(a.Content)),
+		"publishAt":   slog.TimeValue(a.PublishAt),
+		"attachments": slog.IntValue(len(a.Attachments)),
+		"pinned":      slog.BoolValue(a.Pinned),
+		"status":      slog.StringValue(string(a.Status)),
+		"slug":        slog.StringValue(a.Slug),
+		"excerpt":     slog.StringValue(p.truncate(a.Excerpt)),
+	}
+
+	attrs := make([]slog.Attr, 0, len(articleLogFields))
+	for _, field := range articleLogFields {
+		if !p.omit[field] {
+			attrs = append(attrs, slog.Attr{Key: field, Value: values[field]})
+		}
+	}
+	return slog.GroupValue(attrs...)
+}
+
+// truncate keeps the first contentChars characters of s and notes how long
+// it was.
+func (p logRedaction) truncate(s string) string {
+	n := utf8.RuneCountInString(s)
+	if n <= p.contentChars {
+		return s
+	}
+	kept := []rune(s)[:max(p.contentChars, 0)]
+	return fmt.Sprintf("%s… (%d chars)", string(kept), n)
+}
+
+// withLogRedaction sets how decoded article bodies appear in debug logs.
+func withLogRedaction(p logRedaction) transportOption {
+	return func(t *articlesHttpTransport) {
+		t.logRedaction = p
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestLogRedactionTruncate(t *testing.T) {
+	tests := []struct {
+		name  string
+		chars int
+		in    string
+		want  string
+	}{
+		{"short", 10, "hello", "hello"},
+		{"exact", 5, "hello", "hello"},
+		{"long", 5, "hello world", "hello… (11 chars)"},
+		{"length only", 0, "hello", "… (5 chars)"},
+		{"counts characters", 2, "héllo", "hé… (5 chars)"},
+		{"empty", 0, "", ""},
+	}
+	for _, tt := range tests {
+		if got := newLogRedaction(tt.chars, nil).truncate(tt.in); got != tt.want {
+			t.Errorf("%s: truncate(%q) = %q, want %q", tt.name, tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestArticleBodiesRedactedInLog(t *testing.T) {
+	level := new(slog.LevelVar)
+	level.Set(slog.LevelDebug)
+	logs := captureSlog(t, level)
+
+	secret := strings.Repeat("s3cret ", 1000)
+	h := newTestHandler(newTestSvc(), withLogRedaction(newLogRedaction(20, []string{"slug"})))
+	mergePatch := []string{"Content-Type", mergePatchContentType}
+
+	tests := []struct {
+		name, method, target, body string
+		headers                    []string
+	}{
+		{"create", "PUT", "/articles", `{"id":"a1","title":"Title","slug":"hidden-slug","content":"` + secret + `"}`, nil},
+		{"update", "PUT", "/articles/a1", `{"title":"Title","slug":"hidden-slug","content":"` + secret + `"}`, nil},
+		{"patch", "PATCH", "/articles/a1", `{"content":"` + secret + `x"}`, mergePatch},
+		// Invalid bodies are logged too, and their errors name fields only.
+		{"invalid", "PUT", "/articles", `{"id":"a2","content":"` + secret + `"}`, nil},
+	}
+	for _, tt := range tests {
+		before := len(logs.String())
+		doRequest(h, tt.method, tt.target, tt.body, tt.headers...)
+		got := logs.String()[before:]
+
+		if !strings.Contains(got, "article.content=") || !strings.Contains(got, "chars)") {
+			t.Errorf("%s: log has no truncated content: %q", tt.name, got)
+		}
+		if strings.Contains(got, strings.Repeat("s3cret ", 4)) {
+			t.Errorf("%s: log carries the full content", tt.name)
+		}
+		if strings.Contains(got, "hidden-slug") || strings.Contains(got, "article.slug") {
+			t.Errorf("%s: log carries the omitted slug: %q", tt.name, got)
+		}
+	}
+}
+
+func TestCheckLogOmitFields(t *testing.T) {
+	if err := checkLogOmitFields([]string{"content", "title"}); err != nil {
+		t.Errorf("known fields: %v", err)
+	}
+	if err := checkLogOmitFields([]string{"content", "Content"}); err == nil {
+		t.Error("field names are JSON names and case sensitive")
+	}
+}
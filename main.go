@@ -1,65 +1,629 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	jsonpatch "github.com/evanphx/json-patch/v5"
 	"github.com/gorilla/mux"
+	"golang.org/x/sync/singleflight"
 	"io"
+	"iter"
 	"log"
+	"log/slog"
+	"mime"
 	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
+const (
+	mergePatchContentType = "application/merge-patch+json"
+	jsonPatchContentType  = "application/json-patch+json"
+	// maxPatchBytes caps PATCH bodies, which are read whole before they are
+	// applied, even when -max-body-bytes is off.
+	maxPatchBytes = 1 << 20
+)
+
+// maxTagsPerArticle bounds the number of tags an article may carry.
+const maxTagsPerArticle = 10
+
 var ErrArticleNotFound = errors.New("article not found")
 
+// ErrArticleExists is returned when adding an article whose ID is taken.
+var ErrArticleExists = errors.New("article already exists")
+
+// ErrPreconditionFailed is returned when a conditional write finds the
+// article changed since the time the caller supplied.
+var ErrPreconditionFailed = errors.New("article was modified since the given time")
+
+// FieldError describes a single article field that failed validation.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationError is returned by Article.Validate and lists every invalid field.
+type ValidationError struct {
+	Fields []FieldError `json:"errors"`
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, 0, len(e.Fields))
+	for _, f := range e.Fields {
+		msgs = append(msgs, f.Field+": "+f.Message)
+	}
+	return "invalid article: " + strings.Join(msgs, "; ")
+}
+
 type Article struct {
-	ID        string    `json:"id"`
-	Title     string    `json:"title"`
-	Tags      []string  `json:"tags"`
-	Content   string    `json:"content"`
-	PublishAt time.Time `json:"publishAt"`
+	ID          string       `json:"id" yaml:"id" toml:"id"`
+	Title       string       `json:"title" yaml:"title" toml:"title"`
+	Tags        []string     `json:"tags" yaml:"tags" toml:"tags"`
+	Content     string       `json:"content" yaml:"content" toml:"content"`
+	PublishAt   time.Time    `json:"publishAt" yaml:"publishAt" toml:"publishAt"`
+	Attachments []Attachment `json:"attachments" yaml:"attachments" toml:"attachments"`
+	Pinned      bool         `json:"pinned" yaml:"pinned" toml:"pinned"`
+	Status      Status       `json:"status" yaml:"status" toml:"status"`
+	// Seq is the global sequence number of the article's latest change. It
+	// is assigned by the service; values sent by clients are ignored.
+	Seq int64 `json:"seq" yaml:"seq" toml:"seq"`
+	// ModifiedAt is when the article was last written. Like Seq, it is set
+	// by the service.
+	ModifiedAt time.Time `json:"modifiedAt" yaml:"modifiedAt" toml:"modifiedAt"`
+	// Slug is unique across articles. The service generates it from the
+	// title when a new article doesn't set one.
+	Slug string `json:"slug" yaml:"slug" toml:"slug"`
+	// Locked freezes the article against updates and deletes until it is
+	// unlocked; LockedBy names who locked it. Both are only changed through
+	// the lock endpoints.
+	Locked   bool   `json:"locked" yaml:"locked" toml:"locked"`
+	LockedBy string `json:"lockedBy,omitempty" yaml:"lockedBy,omitempty" toml:"lockedBy,omitempty"`
+	// Excerpt is a curated summary. When it is empty, responses carry one
+	// derived from the first paragraph of Content instead.
+	Excerpt string `json:"excerpt" yaml:"excerpt" toml:"excerpt"`
+	// Scheduled reports whether PublishAt is still in the future. The
+	// service computes it on every read; it is never stored.
+	Scheduled bool `json:"scheduled" yaml:"scheduled" toml:"scheduled"`
+	// ETag is a hash of the article's content, computed by the service on
+	// every write so reads can serve it without re-hashing.
+	ETag string `json:"-" yaml:"-" toml:"-"`
+}
+
+// MarshalJSON encodes an article with missing tags and attachments as empty
+// arrays rather than null, which some clients can't handle. List fields
+// added to Article should be treated the same way.
+func (a Article) MarshalJSON() ([]byte, error) {
+	if a.Tags == nil {
+		a.Tags = []string{}
+	}
+	if a.Attachments == nil {
+		a.Attachments = []Attachment{}
+	}
+	type article Article
+	return json.Marshal(article(a))
+}
+
+// Status is the editorial state of an article.
+type Status string
+
+const (
+	StatusDraft     Status = "draft"
+	StatusPublished Status = "published"
+)
+
+// Attachment references media hosted elsewhere, such as an image embedded in
+// the article. Only the metadata is stored, never the binary data itself.
+type Attachment struct {
+	URL      string `json:"url" yaml:"url" toml:"url"`
+	MIMEType string `json:"mimeType" yaml:"mimeType" toml:"mimeType"`
+	Size     int64  `json:"size" yaml:"size" toml:"size"`
+	Alt      string `json:"alt" yaml:"alt" toml:"alt"`
+}
+
+// attachmentMIMETypes lists the media types an attachment may declare.
+var attachmentMIMETypes = map[string]bool{
+	"image/png":       true,
+	"image/jpeg":      true,
+	"image/gif":       true,
+	"image/webp":      true,
+	"image/svg+xml":   true,
+	"video/mp4":       true,
+	"audio/mpeg":      true,
+	"application/pdf": true,
+	"application/zip": true,
+	"text/plain":      true,
+}
+
+// Validate checks the article against the business rules applied on write.
+func (a Article) Validate() error {
+	var fields []FieldError
+	if strings.TrimSpace(a.ID) == "" {
+		fields = append(fields, FieldError{Field: "id", Message: "is required"})
+	}
+	if strings.TrimSpace(a.Title) == "" {
+		fields = append(fields, FieldError{Field: "title", Message: "is required"})
+	}
+	if len(a.Tags) > maxTagsPerArticle {
+		fields = append(fields, FieldError{Field: "tags", Message: fmt.Sprintf("must have at most %d entries", maxTagsPerArticle)})
+	}
+	for _, tag := range a.Tags {
+		if strings.TrimSpace(tag) == "" {
+			fields = append(fields, FieldError{Field: "tags", Message: "must not contain empty tags"})
+			break
+		}
+	}
+
+	for i, attachment := range a.Attachments {
+		field := fmt.Sprintf("attachments[%d]", i)
+		if u, err := url.Parse(attachment.URL); err != nil || !u.IsAbs() || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			fields = append(fields, FieldError{Field: field + ".url", Message: "must be an absolute http or https URL"})
+		}
+		if !attachmentMIMETypes[attachment.MIMEType] {
+			fields = append(fields, FieldError{Field: field + ".mimeType", Message: "is not a supported media type"})
+		}
+		if attachment.Size < 0 {
+			fields = append(fields, FieldError{Field: field + ".size", Message: "must not be negative"})
+		}
+	}
+
+	if !validSlug(a.Slug) {
+		fields = append(fields, FieldError{Field: "slug", Message: "must not contain whitespace, /, ? or #"})
+	}
+
+	switch a.Status {
+	case "", StatusDraft, StatusPublished:
+	default:
+		fields = append(fields, FieldError{Field: "status", Message: "must be draft or published"})
+	}
+
+	if len(fields) > 0 {
+		return &ValidationError{Fields: fields}
+	}
+	return nil
 }
 
+// ArticlesRepo stores articles. Every implementation must behave the same
+// way, so services and decorators can be composed over any of them:
+//
+//   - InsertArticle stores the article, replacing any article with the same ID.
+//   - UpdateArticle replaces an existing article and returns
+//     ErrArticleNotFound when the ID is unknown.
+//   - DeleteArticle removes the article and returns ErrArticleNotFound when
+//     the ID is unknown. A deleted ID can be inserted again.
+//   - ArticleByID returns ErrArticleNotFound when the ID is unknown.
+//   - AllArticles returns an empty, non-nil slice when nothing is stored.
+//   - Clear removes every article and reports how many were removed.
 type ArticlesRepo interface {
 	InsertArticle(ctx context.Context, article Article) error
 	UpdateArticle(ctx context.Context, article Article) error
 	DeleteArticle(ctx context.Context, id string) error
 	ArticleByID(ctx context.Context, id string) (*Article, error)
+	// ArticleBySlug returns the article stored under slug, or
+	// ErrArticleNotFound when no article has it.
+	ArticleBySlug(ctx context.Context, slug string) (*Article, error)
 	AllArticles(ctx context.Context) ([]Article, error)
+	// ArticlesByTitle returns the articles whose title matches title after
+	// normalization (see normalizeTitle).
+	ArticlesByTitle(ctx context.Context, title string) ([]Article, error)
+	// ArticlesInRange returns the articles whose PublishAt lies within
+	// [from, to]. A zero bound leaves that side open; articles without a
+	// PublishAt never match.
+	ArticlesInRange(ctx context.Context, from, to time.Time) ([]Article, error)
+	// ArticlesByTags returns the articles carrying every one of tags, or
+	// every article when tags is empty. Tags match exactly.
+	ArticlesByTags(ctx context.Context, tags []string) ([]Article, error)
+	// ArticlesByAnyTag returns the articles carrying at least one of tags,
+	// each once, or none when tags is empty. Tags match exactly.
+	ArticlesByAnyTag(ctx context.Context, tags []string) ([]Article, error)
+	// TagFrequency returns how many articles carry each tag in use.
+	TagFrequency(ctx context.Context) (map[string]int, error)
+	// SuggestTags returns up to limit tags in use that start with prefix,
+	// ignoring case, most used first. A limit of zero returns them all.
+	SuggestTags(ctx context.Context, prefix string, limit int) ([]TagSuggestion, error)
+	// EachArticle calls fn for every stored article, one at a time, so
+	// callers can process large datasets without materializing them. It
+	// stops at the first error returned by fn or when ctx is cancelled and
+	// returns that error.
+	EachArticle(ctx context.Context, fn func(Article) error) error
+	Clear(ctx context.Context) (removed int, err error)
+}
+
+// articleSeq adapts repo.EachArticle to a range-over-func iterator. Iteration
+// errors, including context cancellation, are yielded as a final pair with a
+// zero Article.
+func articleSeq(ctx context.Context, repo ArticlesRepo) iter.Seq2[Article, error] {
+	return func(yield func(Article, error) bool) {
+		errStop := errors.New("iteration stopped")
+		err := repo.EachArticle(ctx, func(article Article) error {
+			if !yield(article, nil) {
+				return errStop
+			}
+			return nil
+		})
+		if err != nil && err != errStop {
+			yield(Article{}, err)
+		}
+	}
+}
+
+// normalizeTitle folds case and collapses whitespace so titles that only
+// differ in formatting compare equal.
+func normalizeTitle(title string) string {
+	return strings.Join(strings.Fields(strings.ToLower(title)), " ")
 }
 
-func newInMemoryRepo() *inMemoryRepo {
-	return &inMemoryRepo{
-		articles: make(map[string]Article),
+// evictionPolicy decides what a bounded inMemoryRepo does when an insert
+// would exceed its capacity.
+type evictionPolicy string
+
+const (
+	// evictOldestPublished drops the article with the earliest PublishAt.
+	evictOldestPublished evictionPolicy = "oldest-published"
+	// evictOldestInserted drops the article that was inserted first.
+	evictOldestInserted evictionPolicy = "oldest-inserted"
+	// rejectWhenFull refuses the insert with ErrRepoFull.
+	rejectWhenFull evictionPolicy = "reject"
+)
+
+var ErrRepoFull = errors.New("article store is full")
+
+func (p evictionPolicy) valid() bool {
+	switch p {
+	case evictOldestPublished, evictOldestInserted, rejectWhenFull:
+		return true
+	}
+	return false
+}
+
+type inMemoryRepoOption func(*inMemoryRepo)
+
+// withCapacity bounds the repo to max articles, applying policy once full.
+// A max of zero or less leaves the repo unbounded.
+func withCapacity(max int, policy evictionPolicy) inMemoryRepoOption {
+	return func(repo *inMemoryRepo) {
+		repo.capacity = max
+		repo.policy = policy
 	}
 }
 
+func newInMemoryRepo(opts ...inMemoryRepoOption) *inMemoryRepo {
+	repo := &inMemoryRepo{
+		articles:   make(map[string]Article),
+		insertedAt: make(map[string]uint64),
+		tags:       make(map[string]map[string]struct{}),
+		slugs:      make(map[string]string),
+		policy:     evictOldestPublished,
+	}
+	for _, opt := range opts {
+		opt(repo)
+	}
+	return repo
+}
+
 type inMemoryRepo struct {
+	mu       sync.RWMutex
 	articles map[string]Article
+
+	// insertedAt records the insertion sequence of each article so the
+	// oldest-inserted eviction policy can find its victim.
+	insertedAt map[string]uint64
+	insertSeq  uint64
+
+	// tags maps each tag to the IDs of the articles carrying it, so tag
+	// queries don't have to scan every article.
+	tags map[string]map[string]struct{}
+
+	// slugs maps each slug to the ID of the article carrying it, so slug
+	// lookups don't have to scan every article.
+	slugs map[string]string
+
+	capacity int
+	policy   evictionPolicy
 }
 
 func (repo *inMemoryRepo) InsertArticle(_ context.Context, article Article) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	if old, found := repo.articles[article.ID]; found {
+		repo.unindex(old)
+	} else {
+		if repo.capacity > 0 && len(repo.articles) >= repo.capacity {
+			if repo.policy == rejectWhenFull {
+				return ErrRepoFull
+			}
+			repo.remove(repo.evictionVictim())
+		}
+
+		repo.insertSeq++
+		repo.insertedAt[article.ID] = repo.insertSeq
+	}
+
 	repo.articles[article.ID] = article
+	repo.index(article)
+	return nil
+}
+
+// evictionVictim returns the ID of the article to drop according to the
+// configured policy. The caller must hold the write lock.
+func (repo *inMemoryRepo) evictionVictim() string {
+	var victim string
+	for id, article := range repo.articles {
+		if victim == "" {
+			victim = id
+			continue
+		}
+
+		if repo.policy == evictOldestInserted {
+			if repo.insertedAt[id] < repo.insertedAt[victim] {
+				victim = id
+			}
+			continue
+		}
+
+		oldest := repo.articles[victim]
+		if article.PublishAt.Before(oldest.PublishAt) ||
+			(article.PublishAt.Equal(oldest.PublishAt) && repo.insertedAt[id] < repo.insertedAt[victim]) {
+			victim = id
+		}
+	}
+	return victim
+}
+
+// remove deletes the article and its bookkeeping. The caller must hold the
+// write lock.
+func (repo *inMemoryRepo) remove(id string) {
+	repo.unindex(repo.articles[id])
+	delete(repo.articles, id)
+	delete(repo.insertedAt, id)
+}
+
+// index adds article to the tag and slug indexes. The caller must hold the
+// write lock.
+func (repo *inMemoryRepo) index(article Article) {
+	repo.indexTags(article)
+	if article.Slug != "" {
+		repo.slugs[article.Slug] = article.ID
+	}
+}
+
+// unindex removes article from the tag and slug indexes. The caller must
+// hold the write lock.
+func (repo *inMemoryRepo) unindex(article Article) {
+	repo.unindexTags(article)
+	if repo.slugs[article.Slug] == article.ID {
+		delete(repo.slugs, article.Slug)
+	}
+}
+
+// indexTags adds article to the tag index. The caller must hold the write
+// lock.
+func (repo *inMemoryRepo) indexTags(article Article) {
+	for _, tag := range article.Tags {
+		ids := repo.tags[tag]
+		if ids == nil {
+			ids = make(map[string]struct{})
+			repo.tags[tag] = ids
+		}
+		ids[article.ID] = struct{}{}
+	}
+}
+
+// unindexTags removes article from the tag index, dropping tags no article
+// carries any more. The caller must hold the write lock.
+func (repo *inMemoryRepo) unindexTags(article Article) {
+	for _, tag := range article.Tags {
+		ids := repo.tags[tag]
+		delete(ids, article.ID)
+		if len(ids) == 0 {
+			delete(repo.tags, tag)
+		}
+	}
+}
+
+// TagFrequency reads the counts off the tag index, in time proportional to
+// the number of tags rather than articles.
+func (repo *inMemoryRepo) TagFrequency(_ context.Context) (map[string]int, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	counts := make(map[string]int, len(repo.tags))
+	for tag, ids := range repo.tags {
+		counts[tag] = len(ids)
+	}
+	return counts, nil
+}
+
+// SuggestTags scans the tag index, so it costs time proportional to the
+// number of tags rather than articles.
+func (repo *inMemoryRepo) SuggestTags(_ context.Context, prefix string, limit int) ([]TagSuggestion, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	counts := make(map[string]int)
+	for tag, ids := range repo.tags {
+		if hasPrefixFold(tag, prefix) {
+			counts[tag] = len(ids)
+		}
+	}
+	return rankTagSuggestions(counts, limit), nil
+}
+
+// EachArticle iterates over a snapshot of the stored IDs without holding the
+// lock while fn runs, so fn may safely call back into the repo. Articles
+// deleted during iteration are skipped.
+func (repo *inMemoryRepo) EachArticle(ctx context.Context, fn func(Article) error) error {
+	repo.mu.RLock()
+	ids := make([]string, 0, len(repo.articles))
+	for id := range repo.articles {
+		ids = append(ids, id)
+	}
+	repo.mu.RUnlock()
+
+	for _, id := range ids {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		repo.mu.RLock()
+		article, found := repo.articles[id]
+		repo.mu.RUnlock()
+		if !found {
+			continue
+		}
+
+		if err := fn(article); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
+func (repo *inMemoryRepo) ArticlesByTitle(_ context.Context, title string) ([]Article, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	title = normalizeTitle(title)
+	var articles []Article
+	for _, article := range repo.articles {
+		if normalizeTitle(article.Title) == title {
+			articles = append(articles, article)
+		}
+	}
+	return articles, nil
+}
+
+// ArticlesByTags intersects the index entries of tags, starting from the
+// rarest tag, instead of scanning every article.
+func (repo *inMemoryRepo) ArticlesByTags(_ context.Context, tags []string) ([]Article, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	if len(tags) == 0 {
+		articles := make([]Article, 0, len(repo.articles))
+		for _, article := range repo.articles {
+			articles = append(articles, article)
+		}
+		return articles, nil
+	}
+
+	rarest := repo.tags[tags[0]]
+	for _, tag := range tags[1:] {
+		if ids := repo.tags[tag]; len(ids) < len(rarest) {
+			rarest = ids
+		}
+	}
+
+	var articles []Article
+candidates:
+	for id := range rarest {
+		for _, tag := range tags {
+			if _, ok := repo.tags[tag][id]; !ok {
+				continue candidates
+			}
+		}
+		articles = append(articles, repo.articles[id])
+	}
+	return articles, nil
+}
+
+// ArticlesByAnyTag unions the index entries of tags.
+func (repo *inMemoryRepo) ArticlesByAnyTag(_ context.Context, tags []string) ([]Article, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	seen := make(map[string]bool)
+	var articles []Article
+	for _, tag := range tags {
+		for id := range repo.tags[tag] {
+			if !seen[id] {
+				seen[id] = true
+				articles = append(articles, repo.articles[id])
+			}
+		}
+	}
+	return articles, nil
+}
+
+func (repo *inMemoryRepo) ArticlesInRange(_ context.Context, from, to time.Time) ([]Article, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	var articles []Article
+	for _, article := range repo.articles {
+		if publishedWithin(article, from, to) {
+			articles = append(articles, article)
+		}
+	}
+	return articles, nil
+}
+
+// publishedWithin reports whether article's PublishAt lies within the
+// inclusive range [from, to], where a zero bound is open.
+func publishedWithin(article Article, from, to time.Time) bool {
+	if article.PublishAt.IsZero() {
+		return false
+	}
+	if !from.IsZero() && article.PublishAt.Before(from) {
+		return false
+	}
+	if !to.IsZero() && article.PublishAt.After(to) {
+		return false
+	}
+	return true
+}
+
 func (repo *inMemoryRepo) UpdateArticle(_ context.Context, article Article) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
 	if _, found := repo.articles[article.ID]; !found {
 		return ErrArticleNotFound
 	}
 
+	repo.unindex(repo.articles[article.ID])
 	repo.articles[article.ID] = article
+	repo.index(article)
 	return nil
 }
 
 func (repo *inMemoryRepo) DeleteArticle(_ context.Context, id string) error {
-	delete(repo.articles, id)
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	if _, ok := repo.articles[id]; !ok {
+		return ErrArticleNotFound
+	}
+	repo.remove(id)
 	return nil
 }
 
+func (repo *inMemoryRepo) Clear(_ context.Context) (int, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	removed := len(repo.articles)
+	repo.articles = make(map[string]Article)
+	repo.insertedAt = make(map[string]uint64)
+	repo.tags = make(map[string]map[string]struct{})
+	repo.slugs = make(map[string]string)
+	return removed, nil
+}
+
 func (repo *inMemoryRepo) ArticleByID(_ context.Context, id string) (*Article, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
 	article, found := repo.articles[id]
 	if !found {
 		return nil, ErrArticleNotFound
@@ -68,8 +632,28 @@ func (repo *inMemoryRepo) ArticleByID(_ context.Context, id string) (*Article, e
 	return &article, nil
 }
 
+// ArticleBySlug looks slug up in the slug index.
+func (repo *inMemoryRepo) ArticleBySlug(_ context.Context, slug string) (*Article, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	id, found := repo.slugs[slug]
+	if !found {
+		return nil, ErrArticleNotFound
+	}
+	article := repo.articles[id]
+	return &article, nil
+}
+
+// AllArticles returns a snapshot of every stored article. Copying an Article
+// only copies its string and slice headers, so Content is shared with the
+// stored value rather than duplicated; callers must not mutate Tags or
+// Attachments in place.
 func (repo *inMemoryRepo) AllArticles(_ context.Context) ([]Article, error) {
-	articles := make([]Article, 0)
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	articles := make([]Article, 0, len(repo.articles))
 	for _, article := range repo.articles {
 		articles = append(articles, article)
 	}
@@ -77,160 +661,1435 @@ func (repo *inMemoryRepo) AllArticles(_ context.Context) ([]Article, error) {
 }
 
 type ArticlesService interface {
-	AddArticle(ctx context.Context, article Article) error
+	// AddArticle stores a new article, or returns ErrArticleExists if the ID
+	// is taken. The returned warnings describe non-fatal problems, such as a
+	// duplicate title; the article is stored regardless.
+	AddArticle(ctx context.Context, article Article) (warnings []string, err error)
 	UpdateArticle(ctx context.Context, article Article) error
 	Article(ctx context.Context, id string) (*Article, error)
-	Articles(ctx context.Context) ([]Article, error)
+	// Articles lists the articles matching filter, pinned articles first and
+	// then newest first by PublishAt.
+	Articles(ctx context.Context, filter ArticleFilter) ([]Article, error)
+	// DeleteArticle removes the article and returns ErrArticleNotFound when
+	// the ID is unknown.
 	DeleteArticle(ctx context.Context, id string) error
+	// DeleteArticleIfUnmodifiedSince deletes the article unless it was
+	// modified after since, in which case it returns ErrPreconditionFailed.
+	DeleteArticleIfUnmodifiedSince(ctx context.Context, id string, since time.Time) error
+	// Clear removes every article and reports how many were removed.
+	Clear(ctx context.Context) (removed int, err error)
+	// RenameTag renames a tag across all articles and reports how many
+	// articles changed.
+	RenameTag(ctx context.Context, from, to string) (affected int, err error)
+	// BulkTags adds and removes tags on every article matching filter and
+	// reports how many articles changed.
+	BulkTags(ctx context.Context, filter ArticleFilter, add, remove []string) (affected int, err error)
+	// SuggestTags autocompletes tags from a prefix; see
+	// ArticlesRepo.SuggestTags.
+	SuggestTags(ctx context.Context, prefix string, limit int) ([]TagSuggestion, error)
+	// ValidateArticle prepares an article the way a create does, defaults
+	// and content transformers included, and applies the rules checked on
+	// write without storing anything.
+	ValidateArticle(ctx context.Context, article Article) error
+	// EachArticle calls fn for every article, one at a time, so large
+	// exports don't hold them all in memory; see ArticlesRepo.EachArticle.
+	EachArticle(ctx context.Context, fn func(Article) error) error
+	// CloneArticle copies an article into a new draft with a fresh ID.
+	CloneArticle(ctx context.Context, id string) (*Article, error)
+	// Stats summarizes the stored articles.
+	Stats(ctx context.Context) (Stats, error)
+	// SetPinned pins or unpins an article.
+	SetPinned(ctx context.Context, id string, pinned bool) (*Article, error)
+	// Changes lists the changes made after sequence number since, and the
+	// latest sequence number.
+	Changes(ctx context.Context, since int64) (changes []ArticleChange, seq int64, err error)
+	// PurgeDeleted forgets the delete markers of articles deleted before
+	// olderThan and reports how many were purged.
+	PurgeDeleted(ctx context.Context, olderThan time.Time) (purged int, err error)
+	// Reindex rebuilds the repo's secondary indexes from the articles.
+	Reindex(ctx context.Context) (ReindexSummary, error)
+	// SelfCheck reports where the repo's secondary indexes disagree with
+	// the articles, without changing anything.
+	SelfCheck(ctx context.Context) (SelfCheckReport, error)
+	// SetLocked locks or unlocks an article on behalf of by.
+	SetLocked(ctx context.Context, id string, locked bool, by string) (*Article, error)
+	// PublishArticle publishes a draft and reports whether it was one.
+	PublishArticle(ctx context.Context, id string) (article *Article, changed bool, err error)
+	// PreviousArticle and NextArticle return the published articles just
+	// before and after an article by PublishAt, or nil at either end.
+	// Articles carrying any of the exclude tags are skipped over.
+	PreviousArticle(ctx context.Context, id string, exclude []string) (*Article, error)
+	NextArticle(ctx context.Context, id string, exclude []string) (*Article, error)
 }
 
-func newArticleSvc(repo ArticlesRepo) *articleSvc {
-	return &articleSvc{repo: repo}
+// ArticleFilter narrows an article listing. The zero value matches every
+// article.
+type ArticleFilter struct {
+	PinnedOnly bool
+	// Tags keeps the articles carrying every one of these tags (AND), as
+	// ?tag= does.
+	Tags []string
+	// AnyTags keeps the articles carrying at least one of these tags (OR),
+	// as ?anyTag= does. Combined with Tags, both must hold.
+	AnyTags []string
+	// From and To bound PublishAt inclusively; see
+	// ArticlesRepo.ArticlesInRange.
+	From, To time.Time
 }
 
-type articleSvc struct {
-	repo ArticlesRepo
-}
-
-func (svc *articleSvc) AddArticle(ctx context.Context, article Article) error {
-	if a, err := svc.repo.ArticleByID(ctx, article.ID); err == nil && a != nil {
-		return errors.New("article already exists")
+func (f ArticleFilter) matches(article Article) bool {
+	if f.PinnedOnly && !article.Pinned {
+		return false
 	}
-
-	return svc.repo.InsertArticle(ctx, article)
+	if (!f.From.IsZero() || !f.To.IsZero()) && !publishedWithin(article, f.From, f.To) {
+		return false
+	}
+	for _, tag := range f.Tags {
+		if !slices.Contains(article.Tags, tag) {
+			return false
+		}
+	}
+	if len(f.AnyTags) > 0 && !slices.ContainsFunc(f.AnyTags, func(tag string) bool {
+		return slices.Contains(article.Tags, tag)
+	}) {
+		return false
+	}
+	return true
 }
 
-func (svc *articleSvc) UpdateArticle(ctx context.Context, article Article) error {
-	return svc.repo.UpdateArticle(ctx, article)
+// sortArticles orders articles for listing: pinned articles first, then by
+// PublishAt, newest first, with the ID as a tie breaker.
+func sortArticles(articles []Article) {
+	sort.Slice(articles, func(i, j int) bool {
+		return articleLess(articles[i], articles[j])
+	})
 }
 
-func (svc *articleSvc) Article(ctx context.Context, id string) (*Article, error) {
-	return svc.repo.ArticleByID(ctx, id)
+// articleLess reports whether a is listed before b.
+func articleLess(a, b Article) bool {
+	if a.Pinned != b.Pinned {
+		return a.Pinned
+	}
+	if !a.PublishAt.Equal(b.PublishAt) {
+		return a.PublishAt.After(b.PublishAt)
+	}
+	return a.ID < b.ID
 }
 
-func (svc *articleSvc) DeleteArticle(ctx context.Context, id string) error {
-	return svc.repo.DeleteArticle(ctx, id)
-}
+type svcOption func(*articleSvc)
 
-func (svc *articleSvc) Articles(ctx context.Context) ([]Article, error) {
-	return svc.repo.AllArticles(ctx)
+// withClock replaces the wall clock as the service's source of the current
+// time.
+func withClock(clock Clock) svcOption {
+	return func(svc *articleSvc) {
+		svc.clock = clock
+	}
 }
 
-func newArticlesHttpTransport(svc ArticlesService) *articlesHttpTransport {
-	return &articlesHttpTransport{svc: svc}
+// withDefaultPublishAt controls whether AddArticle stamps articles created
+// without a PublishAt with the current time instead of storing the zero time.
+// Drafts are never stamped.
+func withDefaultPublishAt(enabled bool) svcOption {
+	return func(svc *articleSvc) {
+		svc.defaultPublishAt = enabled
+	}
 }
 
-type articlesHttpTransport struct {
-	svc ArticlesService
+// withDuplicateTitleWarnings makes AddArticle warn when another article
+// already has the same normalized title.
+func withDuplicateTitleWarnings(enabled bool) svcOption {
+	return func(svc *articleSvc) {
+		svc.warnDuplicateTitles = enabled
+	}
 }
 
-func (t *articlesHttpTransport) setupRoutes(r *mux.Router) *mux.Router {
-	r.HandleFunc("", t.addArticle).Methods("PUT")
-	r.HandleFunc("", t.articles).Methods("GET")
-	r.HandleFunc("/{id}", t.updateArticle).Methods("PUT")
-	r.HandleFunc("/{id}", t.articleByID).Methods("GET")
-	r.HandleFunc("/{id}", t.deleteArticle).Methods("DELETE")
-	return r
+// withEventBus publishes an event on bus after every successful mutation.
+func withEventBus(bus *eventBus) svcOption {
+	return func(svc *articleSvc) {
+		svc.events = bus
+	}
 }
 
-func (t *articlesHttpTransport) addArticle(w http.ResponseWriter, r *http.Request) {
-	var article Article
-	if err := json.NewDecoder(r.Body).Decode(&article); err != nil {
-		log.Println(err)
-		w.WriteHeader(http.StatusBadRequest)
-
-		if _, err := io.WriteString(w, "bad request"); err != nil {
-			log.Println(err)
-		}
+func newArticleSvc(repo ArticlesRepo, opts ...svcOption) *articleSvc {
+	svc := &articleSvc{repo: repo, clock: realClock{}, slugFunc: slugify, generateID: newArticleID, idRetries: defaultIDRetries, defaultPublishAt: true}
+	for _, opt := range opts {
+		opt(svc)
 	}
+	svc.changes.clock = svc.clock
+	return svc
+}
 
-	if err := t.svc.AddArticle(r.Context(), article); err != nil {
-		log.Println(err)
-		w.WriteHeader(http.StatusInternalServerError)
-		io.WriteString(w, err.Error())
-		return
-	}
+type articleSvc struct {
+	repo   ArticlesRepo
+	events *eventBus
+	lists  *listCache
+	clock  Clock
+	// slugFunc turns a title into the base of a generated slug.
+	slugFunc func(title string) string
+	// strictSlugs rejects generated slugs that collide instead of
+	// suffixing them.
+	strictSlugs bool
+	// generateID returns IDs for articles the server creates itself;
+	// idRetries bounds how often a taken one is replaced.
+	generateID func() (string, error)
+	idRetries  int
 
-	w.WriteHeader(http.StatusOK)
-	io.WriteString(w, "ok")
-}
+	// reads coalesces concurrent Article lookups of the same ID into a
+	// single repo call.
+	reads singleflight.Group
+	// changes stamps every write with a sequence number for the change feed.
+	changes changeLog
 
-func (t *articlesHttpTransport) updateArticle(w http.ResponseWriter, r *http.Request) {
-	var article Article
-	if err := json.NewDecoder(r.Body).Decode(&article); err != nil {
-		log.Println(err)
-		w.WriteHeader(http.StatusBadRequest)
+	warnDuplicateTitles bool
+	defaultPublishAt    bool
+	maxPinned           int
+	maxContentLength    int
+	transformers        []ContentTransformer
+	// tagVocabulary lists the allowed tags; nil allows any tag.
+	tagVocabulary map[string]bool
+}
 
-		if _, err := io.WriteString(w, "bad request"); err != nil {
-			log.Println(err)
-		}
+// publish announces a change made on behalf of the request in ctx. Cached
+// listings are dropped first, so a read that follows the write can't be
+// served the listing from before it.
+func (svc *articleSvc) publish(ctx context.Context, eventType string, article Article) {
+	if svc.lists != nil {
+		svc.lists.invalidate()
+	}
+	if svc.events != nil {
+		svc.events.Publish(articleEvent{Type: eventType, Article: article, RequestID: requestIDFromContext(ctx)})
 	}
+}
 
-	vars := mux.Vars(r)
-	articleID := vars["id"]
-	article.ID = articleID
+// prepareNewArticle fills in the defaults of a new article, runs its content
+// through the transformers and validates the result, storing nothing.
+func (svc *articleSvc) prepareNewArticle(ctx context.Context, article Article) (Article, error) {
+	if article.Status == "" {
+		article.Status = StatusPublished
+	}
+	article.Locked, article.LockedBy = false, ""
+	article.Scheduled = false
+	if svc.defaultPublishAt && article.PublishAt.IsZero() && article.Status == StatusPublished {
+		article.PublishAt = svc.clock.Now().UTC()
+	}
 
-	if err := t.svc.UpdateArticle(r.Context(), article); err != nil {
-		log.Println(err)
-		w.WriteHeader(http.StatusInternalServerError)
-		io.WriteString(w, err.Error())
-		return
+	content, err := svc.transformContent(ctx, article.Content)
+	if err != nil {
+		return Article{}, err
 	}
+	article.Content = content
 
-	w.WriteHeader(http.StatusOK)
-	io.WriteString(w, "ok")
+	if err := svc.checkArticle(article); err != nil {
+		return Article{}, err
+	}
+	return article, nil
 }
 
-func (t *articlesHttpTransport) articles(w http.ResponseWriter, r *http.Request) {
-	articles, err := t.svc.Articles(r.Context())
+func (svc *articleSvc) AddArticle(ctx context.Context, article Article) ([]string, error) {
+	if a, err := svc.repo.ArticleByID(ctx, article.ID); err == nil && a != nil {
+		return nil, ErrArticleExists
+	}
+
+	article, err := svc.prepareNewArticle(ctx, article)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		log.Println(err)
-		if _, err := io.WriteString(w, err.Error()); err != nil {
-			log.Println(err)
-		}
-		return
+		return nil, err
 	}
 
-	if err := json.NewEncoder(w).Encode(articles); err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		log.Println(err)
-		if _, err := io.WriteString(w, err.Error()); err != nil {
-			log.Println(err)
+	var warnings []string
+	if svc.warnDuplicateTitles {
+		duplicates, err := svc.repo.ArticlesByTitle(ctx, article.Title)
+		if err != nil {
+			return nil, err
+		}
+		for _, duplicate := range duplicates {
+			warnings = append(warnings, fmt.Sprintf("article %q already has this title", duplicate.ID))
 		}
-		return
 	}
-}
 
-func (t *articlesHttpTransport) articleByID(w http.ResponseWriter, r *http.Request) {
+	err = svc.changes.write(article.ID, false, func(seq int64) error {
+		// Checked again under the change log lock, so two concurrent adds
+		// of the same ID can't both succeed.
+		if _, err := svc.repo.ArticleByID(ctx, article.ID); err == nil {
+			return ErrArticleExists
+		} else if !errors.Is(err, ErrArticleNotFound) {
+			return err
+		}
+		if article.Pinned {
+			if err := svc.checkPinLimit(ctx); err != nil {
+				return err
+			}
+		}
+		slug, err := svc.assignSlug(ctx, article)
+		if err != nil {
+			return err
+		}
+		article.Slug = slug
+		article.Seq = seq
+		article.ModifiedAt = svc.clock.Now().UTC()
+		article.ETag = article.contentETag()
+		return svc.repo.InsertArticle(ctx, article)
+	})
+	if err != nil {
+		return nil, err
+	}
 
+	svc.publish(ctx, eventArticleCreated, article)
+	return warnings, nil
 }
 
-func (t *articlesHttpTransport) deleteArticle(w http.ResponseWriter, r *http.Request) {
-
+// UpdateArticle replaces an article. It returns ErrArticleLocked while the
+// article is locked; the lock itself can only be changed through SetLocked.
+func (svc *articleSvc) UpdateArticle(ctx context.Context, article Article) error {
+	return svc.updateArticle(ctx, article, false)
+}
+
+// updateArticle implements UpdateArticle. With setLock, article's lock
+// fields are stored as given and a locked article may be written.
+func (svc *articleSvc) updateArticle(ctx context.Context, article Article, setLock bool) error {
+	article.Scheduled = false
+	content, err := svc.transformContent(ctx, article.Content)
+	if err != nil {
+		return err
+	}
+	article.Content = content
+
+	if err := svc.checkArticle(article); err != nil {
+		return err
+	}
+
+	current, err := svc.repo.ArticleByID(ctx, article.ID)
+	if err != nil {
+		return err
+	}
+	if article.Status == "" {
+		article.Status = current.Status
+	}
+	if article.Slug == "" {
+		article.Slug = current.Slug
+	}
+
+	err = svc.changes.write(article.ID, false, func(seq int64) error {
+		// The lock and the pin limit are checked under the change log lock,
+		// so a concurrent lock can't be overtaken and concurrent pins can't
+		// all see room for one more.
+		latest, err := svc.repo.ArticleByID(ctx, article.ID)
+		if err != nil {
+			return err
+		}
+		if !setLock {
+			if latest.Locked {
+				return ErrArticleLocked
+			}
+			article.Locked, article.LockedBy = false, ""
+		}
+		if article.Pinned && !latest.Pinned {
+			if err := svc.checkPinLimit(ctx); err != nil {
+				return err
+			}
+		}
+		if article.Slug != current.Slug {
+			slug, err := svc.assignSlug(ctx, article)
+			if err != nil {
+				return err
+			}
+			article.Slug = slug
+		}
+		article.Seq = seq
+		article.ModifiedAt = svc.clock.Now().UTC()
+		article.ETag = article.contentETag()
+		return svc.repo.UpdateArticle(ctx, article)
+	})
+	if err != nil {
+		return err
+	}
+
+	svc.publish(ctx, eventArticleUpdated, article)
+	return nil
+}
+
+// Article looks up a single article. Concurrent lookups of the same ID share
+// one repo call and all receive its result, including ErrArticleNotFound;
+// each caller gets its own copy of the article. The shared call is detached
+// from any single caller's cancellation, while each caller still stops
+// waiting when its own context is done.
+func (svc *articleSvc) Article(ctx context.Context, id string) (*Article, error) {
+	shared := context.WithoutCancel(ctx)
+	ch := svc.reads.DoChan(id, func() (interface{}, error) {
+		return svc.repo.ArticleByID(shared, id)
+	})
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case res := <-ch:
+		if res.Err != nil {
+			return nil, res.Err
+		}
+		article := svc.withScheduled(*res.Val.(*Article))
+		return &article, nil
+	}
+}
+
+// withScheduled sets the article's Scheduled flag for the current time.
+// Stored ETags are computed unscheduled, so scheduled articles get a tag of
+// their own and caches notice when the flag clears.
+func (svc *articleSvc) withScheduled(article Article) Article {
+	if article.PublishAt.After(svc.clock.Now()) {
+		article.Scheduled = true
+		article.ETag = article.contentETag()
+	}
+	return article
+}
+
+func (svc *articleSvc) DeleteArticle(ctx context.Context, id string) error {
+	return svc.deleteArticle(ctx, id, time.Time{})
+}
+
+// DeleteArticleIfUnmodifiedSince deletes the article only if it hasn't been
+// modified after since, compared at the one second resolution of HTTP
+// dates, and returns ErrPreconditionFailed otherwise.
+func (svc *articleSvc) DeleteArticleIfUnmodifiedSince(ctx context.Context, id string, since time.Time) error {
+	return svc.deleteArticle(ctx, id, since)
+}
+
+// deleteArticle checks the precondition and deletes under the change log
+// lock, so no write can slip in between. A zero unmodifiedSince skips the
+// check.
+func (svc *articleSvc) deleteArticle(ctx context.Context, id string, unmodifiedSince time.Time) error {
+	var deleted *Article
+	err := svc.changes.write(id, true, func(int64) error {
+		article, err := svc.repo.ArticleByID(ctx, id)
+		if err != nil {
+			return err
+		}
+		if !unmodifiedSince.IsZero() && article.ModifiedAt.Truncate(time.Second).After(unmodifiedSince) {
+			return ErrPreconditionFailed
+		}
+		if article.Locked {
+			return ErrArticleLocked
+		}
+		deleted = article
+		return svc.repo.DeleteArticle(ctx, id)
+	})
+	if err != nil {
+		return err
+	}
+
+	svc.publish(ctx, eventArticleDeleted, *deleted)
+	return nil
+}
+
+// EachArticle iterates over the stored articles, in no particular order.
+func (svc *articleSvc) EachArticle(ctx context.Context, fn func(Article) error) error {
+	return svc.repo.EachArticle(ctx, func(article Article) error {
+		return fn(svc.withScheduled(article))
+	})
+}
+
+// Clear empties the store. Instead of per-article events a single
+// articles.cleared event is published, and every removed article gets a
+// delete marker in the change feed.
+func (svc *articleSvc) Clear(ctx context.Context) (int, error) {
+	var removed int
+	err := svc.changes.clear(func() ([]string, error) {
+		articles, err := svc.repo.AllArticles(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if removed, err = svc.repo.Clear(ctx); err != nil {
+			return nil, err
+		}
+
+		ids := make([]string, 0, len(articles))
+		for _, article := range articles {
+			ids = append(ids, article.ID)
+		}
+		return ids, nil
+	})
+	if err != nil {
+		return removed, err
+	}
+
+	svc.publish(ctx, eventArticlesCleared, Article{})
+	return removed, nil
+}
+
+func (svc *articleSvc) Articles(ctx context.Context, filter ArticleFilter) ([]Article, error) {
+	var (
+		all []Article
+		err error
+	)
+	switch {
+	case len(filter.Tags) > 0:
+		all, err = svc.repo.ArticlesByTags(ctx, filter.Tags)
+	case len(filter.AnyTags) > 0:
+		all, err = svc.repo.ArticlesByAnyTag(ctx, filter.AnyTags)
+	case !filter.From.IsZero() || !filter.To.IsZero():
+		all, err = svc.repo.ArticlesInRange(ctx, filter.From, filter.To)
+	default:
+		all, err = svc.repo.AllArticles(ctx)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	articles := all[:0]
+	for _, article := range all {
+		if filter.matches(article) {
+			articles = append(articles, svc.withScheduled(article))
+		}
+	}
+
+	sortArticles(articles)
+	return articles, nil
+}
+
+type transportOption func(*articlesHttpTransport)
+
+// withEvents lets the transport stream changes published on bus.
+func withEvents(bus *eventBus) transportOption {
+	return func(t *articlesHttpTransport) {
+		t.events = bus
+	}
+}
+
+// withCursorSigner signs and verifies the pagination cursors of listings.
+// Without one, listings page by offset only and ?cursor= is refused.
+func withCursorSigner(signer *cursorSigner) transportOption {
+	return func(t *articlesHttpTransport) {
+		t.cursors = signer
+	}
+}
+
+// withPrettyJSON makes every JSON response indented, regardless of the
+// ?pretty query parameter.
+func withPrettyJSON(pretty bool) transportOption {
+	return func(t *articlesHttpTransport) {
+		t.pretty = pretty
+	}
+}
+
+// withHiddenDrafts answers requests for a draft with 404 unless the caller
+// has write access, so anonymous readers can't tell drafts exist.
+func withHiddenDrafts(hide bool) transportOption {
+	return func(t *articlesHttpTransport) {
+		t.hideDrafts = hide
+	}
+}
+
+// withIdempotentDeletes answers DELETE /articles/{id} for an unknown ID with
+// 204 rather than 404, so retried deletes look the same as the first one.
+func withIdempotentDeletes(idempotent bool) transportOption {
+	return func(t *articlesHttpTransport) {
+		t.idempotentDeletes = idempotent
+	}
+}
+
+// withMaxUnpaginated caps GET /articles without ?limit at max articles,
+// flagged with X-Truncated: true and a cursor to continue from. Clients that
+// need every article should paginate. Zero or less removes the cap.
+func withMaxUnpaginated(max int) transportOption {
+	return func(t *articlesHttpTransport) {
+		t.maxUnpaginated = max
+	}
+}
+
+// withEmptyListNoContent answers GET /articles with 204 and no body when the
+// page holds no articles, for clients that expect that. The default 200 with
+// [] is easier on generic clients, which can decode every listing the same
+// way; 204 carries no envelope either, so the total is lost.
+func withEmptyListNoContent(noContent bool) transportOption {
+	return func(t *articlesHttpTransport) {
+		t.emptyListNoContent = noContent
+	}
+}
+
+func newArticlesHttpTransport(svc ArticlesService, opts ...transportOption) *articlesHttpTransport {
+	t := &articlesHttpTransport{svc: svc}
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t
+}
+
+type articlesHttpTransport struct {
+	svc     ArticlesService
+	events  *eventBus
+	cursors *cursorSigner
+	views   *viewTracker
+	pretty  bool
+	// hideDrafts makes drafts look missing to callers without write access.
+	hideDrafts bool
+	// idempotentDeletes answers deletes of unknown IDs with 204.
+	idempotentDeletes bool
+	// lists caches rendered listings; nil disables caching.
+	lists *listCache
+	// envelope wraps listings in listEnvelope unless ?envelope=false.
+	envelope bool
+	// maxUnpaginated caps listings requested without a limit; zero means
+	// no cap.
+	maxUnpaginated int
+	// tagScopes maps restricted tags to the scope needed to read articles
+	// carrying them; hideRestricted answers 404 rather than 403.
+	tagScopes      map[string]string
+	hideRestricted bool
+	// logRedaction shapes article bodies in debug logs.
+	logRedaction logRedaction
+	// emptyListNoContent answers empty listings with 204.
+	emptyListNoContent bool
+	// previews mints and checks draft preview tokens; nil disables them.
+	previews *previewSigner
+}
+
+// jsonEncoder returns an encoder writing to w that indents its output when
+// pretty printing is enabled globally or requested with ?pretty=true.
+func (t *articlesHttpTransport) jsonEncoder(w io.Writer, r *http.Request) *json.Encoder {
+	enc := json.NewEncoder(w)
+	if pretty, _ := strconv.ParseBool(r.URL.Query().Get("pretty")); pretty || t.pretty {
+		enc.SetIndent("", "  ")
+	}
+	return enc
+}
+
+func (t *articlesHttpTransport) setupRoutes(r *mux.Router) *mux.Router {
+	r.Use(t.articleLoaderMiddleware)
+	r.HandleFunc("", t.addArticle).Methods("PUT")
+	r.Handle("", validateQuery(listQuery...)(http.HandlerFunc(t.articles))).Methods("GET", "HEAD")
+	r.HandleFunc("/schema", t.articleSchema).Methods("GET")
+	r.HandleFunc("/events", t.articleEvents).Methods("GET")
+	r.HandleFunc("/ws", t.articlesWebSocket).Methods("GET")
+	r.HandleFunc("/batch", t.batchPatchArticles).Methods("PATCH")
+	r.HandleFunc("/import", t.importArticles).Methods("POST")
+	r.HandleFunc("/changes", t.articleChanges).Methods("GET")
+	r.HandleFunc("/trending", t.trendingArticles).Methods("GET")
+	r.HandleFunc("/validate", t.validateArticle).Methods("POST")
+	r.HandleFunc("/export.zip", t.exportZip).Methods("GET")
+	r.Handle("/publish", requireScope(scopeWrite)(http.HandlerFunc(t.publishArticles))).Methods("POST")
+	r.Handle("/tags/bulk", requireScope(scopeWrite)(http.HandlerFunc(t.bulkTags))).Methods("POST")
+	r.HandleFunc("/{id}", t.updateArticle).Methods("PUT")
+	r.HandleFunc("/{id}", t.patchArticle).Methods("PATCH")
+	r.HandleFunc("/{id}", t.articleByID).Methods("GET")
+	r.HandleFunc("/{id}", t.deleteArticle).Methods("DELETE")
+	r.HandleFunc("/{id}/attachments", t.articleAttachments).Methods("GET")
+	r.HandleFunc("/{id}/siblings", t.articleSiblings).Methods("GET")
+	r.HandleFunc("/{id}/export.md", t.exportMarkdown).Methods("GET")
+	r.HandleFunc("/{id}/clone", t.cloneArticle).Methods("POST")
+	r.Handle("/{id}/preview-token", requireScope(scopeWrite)(http.HandlerFunc(t.previewToken))).Methods("POST")
+	r.HandleFunc("/{id}/pin", t.pinArticle).Methods("POST")
+	r.HandleFunc("/{id}/unpin", t.unpinArticle).Methods("POST")
+	r.Handle("/{id}/lock", requireScope(scopeAdmin)(http.HandlerFunc(t.lockArticle))).Methods("POST")
+	r.Handle("/{id}/unlock", requireScope(scopeAdmin)(http.HandlerFunc(t.unlockArticle))).Methods("POST")
+	return r
+}
+
+func (t *articlesHttpTransport) addArticle(w http.ResponseWriter, r *http.Request) {
+	var article Article
+	if err := json.NewDecoder(r.Body).Decode(&article); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+	slog.Debug("article received", "method", r.Method, "article", t.logRedaction.article(article))
+
+	t.createArticle(w, r, article, false)
+}
+
+// createArticle adds article and answers 201. An existing article with the
+// same ID is answered with 409, or with 412 when ifAbsent, as for
+// If-None-Match: *.
+func (t *articlesHttpTransport) createArticle(w http.ResponseWriter, r *http.Request, article Article, ifAbsent bool) {
+	warnings, err := t.svc.AddArticle(r.Context(), article)
+	if err != nil {
+		log.Println(err)
+		var (
+			verr *ValidationError
+			terr *TransformError
+		)
+		switch {
+		case errors.As(err, &verr):
+			writeValidationError(w, verr)
+			return
+		case errors.As(err, &terr):
+			w.WriteHeader(http.StatusUnprocessableEntity)
+		case ifAbsent && errors.Is(err, ErrArticleExists):
+			w.WriteHeader(http.StatusPreconditionFailed)
+		case errors.Is(err, ErrTooManyPinned), errors.Is(err, ErrSlugConflict), errors.Is(err, ErrArticleExists):
+			w.WriteHeader(http.StatusConflict)
+		default:
+			w.WriteHeader(serverErrorStatus(err))
+		}
+		io.WriteString(w, errorBody(err))
+		return
+	}
+
+	t.writeJSON(w, r, http.StatusCreated, createArticleResponse{ID: article.ID, Warnings: warnings})
+}
+
+// errEmptyBody is reported when a request that needs a body has none.
+var errEmptyBody = errors.New("request body is required")
+
+// writeDecodeError reports a request body that could not be read or
+// decoded: 413 when it exceeded the body size limit, 400 otherwise.
+func writeDecodeError(w http.ResponseWriter, err error) {
+	log.Println(err)
+	if errors.Is(err, io.EOF) || errors.Is(err, errEmptyBody) {
+		w.WriteHeader(http.StatusBadRequest)
+		io.WriteString(w, errEmptyBody.Error())
+		return
+	}
+
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
+		io.WriteString(w, "request body too large")
+		return
+	}
+
+	var badTime *PublishAtError
+	if errors.As(err, &badTime) {
+		w.WriteHeader(http.StatusBadRequest)
+		io.WriteString(w, badTime.Error())
+		return
+	}
+
+	w.WriteHeader(http.StatusBadRequest)
+	if _, err := io.WriteString(w, "bad request"); err != nil {
+		log.Println(err)
+	}
+}
+
+// writeValidationError answers a well-formed request that failed validation
+// with 422 and the field errors as JSON. Bodies that can't be decoded at all
+// get 400 from writeDecodeError instead.
+func writeValidationError(w http.ResponseWriter, verr *ValidationError) {
+	writeEncoded(w, jsonEncoding{}, http.StatusUnprocessableEntity, verr)
+}
+
+// maxEchoedIDLength caps how much of a requested ID is echoed back in error
+// bodies.
+const maxEchoedIDLength = 256
+
+// notFoundResponse is the body of a 404 for a single article.
+type notFoundResponse struct {
+	Error struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+		ID      string `json:"id"`
+	} `json:"error"`
+}
+
+// writeArticleNotFound answers 404 with a JSON body naming the requested ID.
+// The ID only ever appears JSON encoded, which escapes control characters
+// and HTML, and is made valid UTF-8 and truncated first.
+func writeArticleNotFound(w http.ResponseWriter, id string) {
+	id = strings.ToValidUTF8(id, "\uFFFD")
+	if len(id) > maxEchoedIDLength {
+		id = excerpt(id, maxEchoedIDLength)
+	}
+
+	var resp notFoundResponse
+	resp.Error.Code = "not_found"
+	resp.Error.Message = ErrArticleNotFound.Error()
+	resp.Error.ID = id
+
+	writeEncoded(w, jsonEncoding{}, http.StatusNotFound, resp)
+}
+
+// createArticleResponse is the body returned for a successful create.
+type createArticleResponse struct {
+	ID       string   `json:"id"`
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+func (t *articlesHttpTransport) updateArticle(w http.ResponseWriter, r *http.Request) {
+	var article Article
+	if err := json.NewDecoder(r.Body).Decode(&article); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+
+	vars := mux.Vars(r)
+	articleID := vars["id"]
+	article.ID = articleID
+	slog.Debug("article received", "method", r.Method, "article", t.logRedaction.article(article))
+
+	if strings.TrimSpace(r.Header.Get("If-None-Match")) == "*" {
+		t.createArticle(w, r, article, true)
+		return
+	}
+
+	if err := t.svc.UpdateArticle(r.Context(), article); err != nil {
+		log.Println(err)
+		var (
+			verr *ValidationError
+			terr *TransformError
+		)
+		switch {
+		case errors.As(err, &verr):
+			writeValidationError(w, verr)
+			return
+		case errors.As(err, &terr):
+			w.WriteHeader(http.StatusUnprocessableEntity)
+		case errors.Is(err, ErrArticleNotFound):
+			w.WriteHeader(http.StatusNotFound)
+		case errors.Is(err, ErrTooManyPinned):
+			w.WriteHeader(http.StatusConflict)
+		case errors.Is(err, ErrArticleLocked):
+			w.WriteHeader(http.StatusLocked)
+		default:
+			w.WriteHeader(serverErrorStatus(err))
+		}
+		io.WriteString(w, errorBody(err))
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	io.WriteString(w, "ok")
+}
+
+// patchArticle applies either a JSON Merge Patch (RFC 7386) or a JSON Patch
+// (RFC 6902) to the stored article, depending on the request Content-Type.
+// The patched article is validated before it is persisted.
+func (t *articlesHttpTransport) patchArticle(w http.ResponseWriter, r *http.Request) {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || (mediaType != mergePatchContentType && mediaType != jsonPatchContentType) {
+		w.WriteHeader(http.StatusUnsupportedMediaType)
+		io.WriteString(w, "unsupported patch content type")
+		return
+	}
+
+	patch, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxPatchBytes))
+	if err == nil && len(bytes.TrimSpace(patch)) == 0 {
+		err = errEmptyBody
+	}
+	if err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+
+	articleID := mux.Vars(r)["id"]
+	current, err := t.requestedArticle(r, articleID)
+	if err == nil && t.hidesDraft(r, *current) {
+		err = ErrArticleNotFound
+	}
+	if err != nil {
+		log.Println(err)
+		if errors.Is(err, ErrArticleNotFound) {
+			w.WriteHeader(http.StatusNotFound)
+		} else {
+			w.WriteHeader(serverErrorStatus(err))
+		}
+		io.WriteString(w, errorBody(err))
+		return
+	}
+	if restricted(*current, t.deniedTags(r)) {
+		t.writeRestricted(w, articleID)
+		return
+	}
+
+	original, err := json.Marshal(current)
+	if err != nil {
+		log.Println(err)
+		w.WriteHeader(serverErrorStatus(err))
+		io.WriteString(w, errorBody(err))
+		return
+	}
+
+	var patched []byte
+	if mediaType == mergePatchContentType {
+		patched, err = jsonpatch.MergePatch(original, patch)
+		if err != nil {
+			log.Println(err)
+			w.WriteHeader(http.StatusBadRequest)
+			io.WriteString(w, "bad request")
+			return
+		}
+	} else {
+		ops, err := jsonpatch.DecodePatch(patch)
+		if err != nil {
+			log.Println(err)
+			w.WriteHeader(http.StatusBadRequest)
+			io.WriteString(w, "bad request")
+			return
+		}
+
+		patched, err = ops.Apply(original)
+		if err != nil {
+			log.Println(err)
+			if errors.Is(err, jsonpatch.ErrTestFailed) {
+				w.WriteHeader(http.StatusConflict)
+			} else {
+				w.WriteHeader(http.StatusUnprocessableEntity)
+			}
+			io.WriteString(w, errorBody(err))
+			return
+		}
+	}
+
+	// A patch that changes nothing, as idempotent syncs often send, is not
+	// written, so ModifiedAt stays put and no change event fires.
+	if jsonpatch.Equal(original, patched) {
+		w.Header().Set("X-Unchanged", "true")
+		writeEncoded(w, jsonEncoding{}, http.StatusOK, current)
+		return
+	}
+
+	var article Article
+	if err := json.Unmarshal(patched, &article); err != nil {
+		log.Println(err)
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		io.WriteString(w, errorBody(err))
+		return
+	}
+	article.ID = articleID
+	slog.Debug("article patched", "article", t.logRedaction.article(article))
+
+	if err := article.Validate(); err != nil {
+		writeEncoded(w, jsonEncoding{}, http.StatusUnprocessableEntity, err)
+		return
+	}
+
+	if err := t.svc.UpdateArticle(r.Context(), article); err != nil {
+		log.Println(err)
+		var (
+			verr *ValidationError
+			terr *TransformError
+		)
+		switch {
+		case errors.As(err, &verr):
+			writeValidationError(w, verr)
+			return
+		case errors.As(err, &terr):
+			w.WriteHeader(http.StatusUnprocessableEntity)
+		case errors.Is(err, ErrArticleNotFound):
+			w.WriteHeader(http.StatusNotFound)
+		case errors.Is(err, ErrTooManyPinned):
+			w.WriteHeader(http.StatusConflict)
+		case errors.Is(err, ErrArticleLocked):
+			w.WriteHeader(http.StatusLocked)
+		default:
+			w.WriteHeader(serverErrorStatus(err))
+		}
+		io.WriteString(w, errorBody(err))
+		return
+	}
+
+	writeEncoded(w, jsonEncoding{}, http.StatusOK, article)
+}
+
+// maxListLimit is the largest page GET /articles serves.
+const maxListLimit = 100
+
+// listQuery declares the query parameters of GET /articles that
+// validateQuery checks before the handler runs.
+var listQuery = []queryParam{
+	{name: "pinned", kind: boolParam},
+	{name: "from", kind: timeParam},
+	{name: "to", kind: timeParam},
+	{name: "limit", kind: intParam, min: 1, max: maxListLimit},
+	{name: "offset", kind: intParam, min: 0},
+	{name: "excerpt", kind: intParam, min: 0},
+	{name: "envelope", kind: boolParam},
+}
+
+// articles lists articles. Its query parameters are checked by
+// validateQuery(listQuery...) first. Repeated ?tag= keeps the articles
+// carrying all of the tags, repeated ?anyTag= those carrying any of them.
+//
+// X-Total-Count and Last-Modified describe every matching article, not just
+// the page, so HEAD with the same filters cheaply tells a client how many
+// articles there are and whether any changed.
+func (t *articlesHttpTransport) articles(w http.ResponseWriter, r *http.Request) {
+	filter := ArticleFilter{
+		PinnedOnly: queryBool(r, "pinned", false),
+		Tags:       r.URL.Query()["tag"],
+		AnyTags:    r.URL.Query()["anyTag"],
+		From:       queryTime(r, "from"),
+		To:         queryTime(r, "to"),
+	}
+	if !filter.From.IsZero() && !filter.To.IsZero() && filter.From.After(filter.To) {
+		w.WriteHeader(http.StatusBadRequest)
+		io.WriteString(w, "from must not be after to")
+		return
+	}
+
+	limit := queryInt(r, "limit", 0)
+	offset := queryInt(r, "offset", 0)
+	excerptLength := queryInt(r, "excerpt", -1)
+	envelope := queryBool(r, "envelope", t.envelope)
+
+	var cursor *cursorPayload
+	if v := r.URL.Query().Get("cursor"); v != "" {
+		if t.cursors == nil {
+			w.WriteHeader(http.StatusBadRequest)
+			io.WriteString(w, errCursorsDisabled.Error())
+			return
+		}
+		c, err := t.cursors.decode(v)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			io.WriteString(w, errorBody(err))
+			return
+		}
+		cursor = &c
+	}
+	if cursor != nil && offset > 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		io.WriteString(w, "cursor and offset cannot be combined")
+		return
+	}
+
+	enc, ok := t.negotiateEncoder(r)
+	if !ok {
+		w.WriteHeader(http.StatusNotAcceptable)
+		io.WriteString(w, "unsupported format")
+		return
+	}
+	w.Header().Add("Vary", "Accept")
+
+	// A cached build is shared with concurrent requests, so it must not
+	// fail just because the request that started it went away.
+	ctx := r.Context()
+	if t.lists != nil {
+		ctx = context.WithoutCancel(ctx)
+	}
+	denied := t.deniedTags(r)
+	drafts := t.showsDrafts(r)
+	build := func() (cachedList, error) {
+		articles, err := t.svc.Articles(ctx, filter)
+		if err != nil {
+			return cachedList{}, err
+		}
+		articles = withoutRestricted(articles, denied)
+		if !drafts {
+			articles = withoutDrafts(articles)
+		}
+
+		var list cachedList
+		total := len(articles)
+		list.total = total
+		for _, article := range articles {
+			if article.ModifiedAt.After(list.lastModified) {
+				list.lastModified = article.ModifiedAt
+			}
+		}
+		if cursor != nil {
+			articles = afterCursor(articles, *cursor)
+		}
+		articles = articles[min(offset, len(articles)):]
+		pageSize := limit
+		if pageSize == 0 && t.maxUnpaginated > 0 {
+			pageSize = t.maxUnpaginated
+		}
+		if pageSize > 0 && len(articles) > pageSize {
+			list.truncated = limit == 0
+			articles = articles[:pageSize]
+			if t.cursors != nil {
+				next, err := t.cursors.encode(articles[pageSize-1])
+				if err != nil {
+					log.Println(err)
+				} else {
+					list.nextCursor = next
+				}
+			}
+		}
+		if articles == nil {
+			articles = []Article{}
+		}
+		list.empty = len(articles) == 0
+		for i := range articles {
+			articles[i] = articles[i].withExcerpt()
+			if excerptLength >= 0 {
+				articles[i].Content = excerpt(articles[i].Content, excerptLength)
+			}
+		}
+
+		var v interface{} = articles
+		if envelope {
+			v = listEnvelope{Data: articles, Meta: listMeta{
+				Total:      total,
+				Limit:      limit,
+				Offset:     offset,
+				NextCursor: list.nextCursor,
+				Truncated:  list.truncated,
+			}}
+		}
+
+		var body bytes.Buffer
+		if err := enc.encode(&body, v); err != nil {
+			return cachedList{}, err
+		}
+		list.body = body.Bytes()
+		return list, nil
+	}
+
+	var (
+		list cachedList
+		err  error
+	)
+	if t.lists != nil {
+		// Callers denied different tags, or not shown drafts, see
+		// different listings. Content types hold no spaces, so the drafts
+		// prefix can't be mistaken for part of one.
+		key := enc.contentType() + "?" + r.URL.Query().Encode()
+		if len(denied) > 0 {
+			key += "#" + strings.Join(denied, ",")
+		}
+		if !drafts {
+			key = "published " + key
+		}
+		list, err = t.lists.get(key, build)
+	} else {
+		list, err = build()
+	}
+	if err != nil {
+		w.WriteHeader(serverErrorStatus(err))
+		log.Println(err)
+		if _, err := io.WriteString(w, errorBody(err)); err != nil {
+			log.Println(err)
+		}
+		return
+	}
+
+	if list.nextCursor != "" {
+		w.Header().Set("X-Next-Cursor", list.nextCursor)
+	}
+	if list.truncated {
+		w.Header().Set("X-Truncated", "true")
+	}
+	w.Header().Set("X-Total-Count", strconv.Itoa(list.total))
+	if !list.lastModified.IsZero() {
+		w.Header().Set("Last-Modified", list.lastModified.UTC().Format(http.TimeFormat))
+	}
+	if list.empty && t.emptyListNoContent {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	w.Header().Set("Content-Type", enc.contentType())
+	if _, err := w.Write(list.body); err != nil {
+		log.Println(err)
+	}
+}
+
+// articleAttachments lists the attachment references of a single article.
+func (t *articlesHttpTransport) articleAttachments(w http.ResponseWriter, r *http.Request) {
+	articleID := mux.Vars(r)["id"]
+	article, err := t.requestedArticle(r, articleID)
+	if err == nil && t.hidesDraft(r, *article) {
+		err = ErrArticleNotFound
+	}
+	if err != nil {
+		log.Println(err)
+		if errors.Is(err, ErrArticleNotFound) {
+			w.WriteHeader(http.StatusNotFound)
+		} else {
+			w.WriteHeader(serverErrorStatus(err))
+		}
+		io.WriteString(w, errorBody(err))
+		return
+	}
+	if restricted(*article, t.deniedTags(r)) {
+		t.writeRestricted(w, articleID)
+		return
+	}
+
+	attachments := article.Attachments
+	if attachments == nil {
+		attachments = []Attachment{}
+	}
+
+	writeEncoded(w, jsonEncoding{}, http.StatusOK, attachments)
+}
+
+func (t *articlesHttpTransport) articleByID(w http.ResponseWriter, r *http.Request) {
+	articleID := mux.Vars(r)["id"]
+
+	// A preview token stands in for write access to this one article.
+	preview := false
+	if token := r.URL.Query().Get("preview"); token != "" {
+		if t.previews == nil || !t.previews.verify(token, articleID) {
+			w.WriteHeader(http.StatusForbidden)
+			io.WriteString(w, errInvalidPreviewToken.Error())
+			return
+		}
+		preview = true
+		w.Header().Set("Cache-Control", "private, no-store")
+	}
+
+	article, err := t.requestedArticle(r, articleID)
+	if errors.Is(err, ErrArticleNotFound) {
+		writeArticleNotFound(w, articleID)
+		return
+	}
+	if err != nil {
+		log.Println(err)
+		w.WriteHeader(serverErrorStatus(err))
+		io.WriteString(w, errorBody(err))
+		return
+	}
+
+	if t.hidesDraft(r, *article) && !preview {
+		writeArticleNotFound(w, articleID)
+		return
+	}
+	if restricted(*article, t.deniedTags(r)) {
+		t.writeRestricted(w, articleID)
+		return
+	}
+
+	if t.views != nil {
+		t.views.record(article.ID)
+	}
+
+	enc, ok := t.negotiateEncoder(r)
+	if !ok {
+		w.WriteHeader(http.StatusNotAcceptable)
+		io.WriteString(w, "unsupported format")
+		return
+	}
+	w.Header().Add("Vary", "Accept")
+
+	etag := formatETag(article.ETag, enc)
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		writeNotModified(w, etag)
+		return
+	}
+
+	if etag != "" {
+		w.Header().Set("ETag", etag)
+	}
+	if !article.ModifiedAt.IsZero() {
+		w.Header().Set("Last-Modified", article.ModifiedAt.UTC().Format(http.TimeFormat))
+	}
+	// Large articles can be fetched in parts or resumed with Range.
+	serveEncoded(w, r, enc, article.ModifiedAt, article.withExcerpt())
+}
+
+// deleteArticle removes an article and answers 204, or 404 when there is
+// no such article unless deletes are idempotent. With If-Unmodified-Since it
+// answers 412 instead, keeping the article, when the article was modified
+// after that time.
+func (t *articlesHttpTransport) deleteArticle(w http.ResponseWriter, r *http.Request) {
+	articleID := mux.Vars(r)["id"]
+
+	var err error
+	if header := r.Header.Get("If-Unmodified-Since"); header != "" {
+		since, perr := http.ParseTime(header)
+		if perr != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			io.WriteString(w, "If-Unmodified-Since must be an HTTP date")
+			return
+		}
+		err = t.svc.DeleteArticleIfUnmodifiedSince(r.Context(), articleID, since)
+	} else {
+		err = t.svc.DeleteArticle(r.Context(), articleID)
+	}
+
+	if errors.Is(err, ErrArticleNotFound) {
+		if t.idempotentDeletes {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+		io.WriteString(w, errorBody(err))
+		return
+	}
+	if err != nil {
+		log.Println(err)
+		switch {
+		case errors.Is(err, ErrPreconditionFailed):
+			w.WriteHeader(http.StatusPreconditionFailed)
+		case errors.Is(err, ErrArticleLocked):
+			w.WriteHeader(http.StatusLocked)
+		default:
+			w.WriteHeader(serverErrorStatus(err))
+		}
+		io.WriteString(w, errorBody(err))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
 }
 
 func main() {
+	cfg := parseConfig()
+	if err := cfg.validate(); err != nil {
+		log.Fatalln(err)
+	}
+
 	var (
-		rootRouter        = mux.NewRouter()
-		repo              = newInMemoryRepo()
-		svc               = newArticleSvc(repo)
-		articlesTransport = newArticlesHttpTransport(svc)
+		rootRouter = mux.NewRouter()
+		events     = newEventBus()
+		metrics    = newMetrics(cfg)
+		repo       = newRepo(cfg, metrics)
+		clock      = realClock{}
 	)
 
+	auth, err := loadAPIKeys(cfg.apiKeysFile)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	proxies, err := parseTrustedProxies(cfg.trustedProxies)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	vocabulary, err := loadTagVocabulary(cfg.tagVocabulary)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	tagScopes, err := parseTagScopes(cfg.tagScopes)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	var lists *listCache
+	if cfg.listCacheTTL > 0 {
+		lists = newListCache(cfg.listCacheTTL, clock)
+	}
+
+	svc := newArticleSvc(repo,
+		withEventBus(events),
+		withListInvalidation(lists),
+		withClock(clock),
+		withDuplicateTitleWarnings(cfg.warnDuplicateTitles),
+		withDefaultPublishAt(cfg.defaultPublishAt),
+		withMaxPinned(cfg.maxPinned),
+		withMaxContentLength(cfg.maxContentLength),
+		withNamedContentTransformers(cfg.contentTransformers),
+		withTagVocabulary(vocabulary),
+		withIDRetries(cfg.idRetries),
+		withStrictSlugs(cfg.strictSlugs),
+	)
+
+	cursors, err := newCursorSigner(cfg.cursorSecret)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	previews, err := newPreviewSigner(cfg.previewSecret, cfg.previewTokenTTL, clock)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	articlesTransport := newArticlesHttpTransport(svc,
+		withEvents(events),
+		withCursorSigner(cursors),
+		withViewTracker(newViewTracker(clock)),
+		withPrettyJSON(cfg.pretty),
+		withHiddenDrafts(cfg.hideDrafts),
+		withIdempotentDeletes(cfg.idempotentDeletes),
+		withListCache(lists),
+		withEnvelope(cfg.envelope),
+		withMaxUnpaginated(cfg.maxUnpaginated),
+		withTagScopes(tagScopes, cfg.hideRestrictedTags),
+		withLogRedaction(newLogRedaction(cfg.logContentChars, cfg.logOmitFields)),
+		withEmptyListNoContent(cfg.emptyListNoContent),
+		withPreviewTokens(previews),
+	)
+
+	var (
+		readOnly atomic.Bool
+		inFlight atomic.Int64
+		logLevel slog.LevelVar
+	)
+	readOnly.Store(cfg.readOnly)
+	logLevel.Set(cfg.logLevel)
+	setupLogging(os.Stderr, &logLevel)
+	toggleLogLevelOnSIGHUP(&logLevel)
+	if cfg.seedFile != "" {
+		seeded, skipped, failed, err := seedArticles(context.Background(), svc, cfg.seedFile)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		log.Printf("seeded %d articles from %s, skipped %d already stored, %d rejected", seeded, cfg.seedFile, skipped, failed)
+	}
+
+	if cfg.matchedRouteHeader {
+		rootRouter.Use(matchedRouteMiddleware)
+	}
+	if metrics != nil {
+		rootRouter.Use(metrics.middleware)
+		rootRouter.Handle("/metrics", metrics.handler()).Methods("GET")
+	}
+
 	articlesTransport.setupRoutes(rootRouter.PathPrefix("/articles").Subrouter())
+	articlesTransport.setupTagRoutes(rootRouter.PathPrefix("/tags").Subrouter())
+	newAdminHttpTransport(svc, &readOnly, &logLevel, cfg.enableAdmin, metrics).setupRoutes(rootRouter.PathPrefix("/admin").Subrouter())
 
+	rootRouter.HandleFunc("/healthz", healthz(&inFlight)).Methods("GET")
+	var statsHandler http.Handler = http.HandlerFunc(articlesTransport.stats)
+	if auth.enabled() {
+		statsHandler = requireScope(scopeAdmin)(statsHandler)
+	}
+	rootRouter.Handle("/stats", statsHandler).Methods("GET")
+	rootRouter.HandleFunc("/preview", articlesTransport.previewMarkdown).Methods("POST")
+	if cfg.staticDir != "" {
+		rootRouter.PathPrefix("/static/").Handler(http.StripPrefix("/static", staticHandler(cfg.staticDir))).Methods("GET", "HEAD")
+	}
 	rootRouter.HandleFunc("/", func(w http.ResponseWriter, request *http.Request) {
 		w.Write([]byte("Hello Ghochu!"))
 	})
 
-	if err := http.ListenAndServe(":8888", rootRouter); err != nil {
+	var handler http.Handler = rootRouter
+	handler = readOnlyMiddleware(&readOnly, handler)
+	handler = auth.middleware(handler)
+	handler = featureFlagsMiddleware(cfg.featureFlags, handler)
+	if cfg.serverTiming {
+		handler = serverTimingMiddleware(handler)
+	}
+	handler = maxBodyMiddleware(cfg.maxBodyBytes, cfg.maxBatchBodyBytes, handler)
+	if cfg.requireHTTPS {
+		handler = requireHTTPSMiddleware(handler)
+	}
+	if len(cfg.cors.origins) > 0 {
+		handler = corsMiddleware(cfg.cors, handler)
+	}
+	if cfg.maxConcurrent > 0 {
+		handler = concurrencyLimitMiddleware(cfg.maxConcurrent, handler)
+	}
+	handler = accessLogMiddleware(slog.New(slog.NewJSONHandler(newAccessLogWriter(cfg), nil)), handler)
+	if cfg.requestIDHeader != "" {
+		handler = requestIDMiddleware(cfg.requestIDHeader, handler)
+	}
+	handler = clientIPMiddleware(proxies, handler)
+	handler = inFlightMiddleware(&inFlight, handler)
+
+	srv := newServer(cfg, handler)
+	srv.RegisterOnShutdown(events.Close)
+	if cfg.purgeInterval > 0 {
+		janitor, stopJanitor := context.WithCancel(context.Background())
+		srv.RegisterOnShutdown(stopJanitor)
+		go purgeDeletedEvery(janitor, svc, cfg.purgeInterval, cfg.tombstoneRetention, clock)
+	}
+	if err := serve(srv, cfg, &inFlight); err != nil {
 		log.Println(err)
 	}
 }
 
+// newRepo builds the article store described by cfg, instrumented when
+// metrics or Server-Timing are enabled.
+func newRepo(cfg config, metrics *httpMetrics) ArticlesRepo {
+	var repo ArticlesRepo
+	if cfg.repoShards > 1 {
+		perShard := (cfg.maxArticles + cfg.repoShards - 1) / cfg.repoShards
+		repo = newShardedRepo(cfg.repoShards, withCapacity(perShard, cfg.evictionPolicy))
+	} else {
+		repo = newInMemoryRepo(withCapacity(cfg.maxArticles, cfg.evictionPolicy))
+	}
+
+	if metrics != nil {
+		repo = newMetricsRepo(repo, metrics.registry)
+	}
+	if cfg.serverTiming {
+		repo = newTimingRepo(repo)
+	}
+	return repo
+}
+
 func printArticles(svc ArticlesService) {
-	articles, err := svc.Articles(context.Background())
+	articles, err := svc.Articles(context.Background(), ArticleFilter{})
 	if err != nil {
 		log.Fatalln(err)
 	}
@@ -0,0 +1,1030 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// newTestHandler serves the /articles and /tags routes of a transport over
+// svc, the way main mounts them.
+func newTestHandler(svc ArticlesService, opts ...transportOption) http.Handler {
+	root := mux.NewRouter()
+	t := newArticlesHttpTransport(svc, opts...)
+	t.setupRoutes(root.PathPrefix("/articles").Subrouter())
+	t.setupTagRoutes(root.PathPrefix("/tags").Subrouter())
+	return root
+}
+
+// newTestSvc returns a service over an empty in-memory repo.
+func newTestSvc(opts ...svcOption) *articleSvc {
+	return newArticleSvc(newInMemoryRepo(), opts...)
+}
+
+// doRequest serves one request through h. headers are name, value pairs.
+func doRequest(h http.Handler, method, target, body string, headers ...string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+// testAuth wraps h in an authenticator knowing keys, which map an API key to
+// its scopes. Requests authenticate with the X-API-Key header.
+func testAuth(h http.Handler, keys map[string][]string) http.Handler {
+	auth := &authenticator{keys: make(map[[sha256.Size]byte]*principal)}
+	for key, scopes := range keys {
+		p := &principal{Name: key, Scopes: make(map[string]bool)}
+		for _, scope := range scopes {
+			p.Scopes[scope] = true
+		}
+		auth.keys[sha256.Sum256([]byte(key))] = p
+	}
+	return auth.middleware(h)
+}
+
+// mustAdd stores articles through svc, failing the test on any error.
+func mustAdd(t testing.TB, svc ArticlesService, articles ...Article) {
+	t.Helper()
+	for _, article := range articles {
+		if _, err := svc.AddArticle(context.Background(), article); err != nil {
+			t.Fatalf("adding %q: %v", article.ID, err)
+		}
+	}
+}
+
+// mustGet returns the stored article id, failing the test if it is missing.
+func mustGet(t testing.TB, svc ArticlesService, id string) Article {
+	t.Helper()
+	article, err := svc.Article(context.Background(), id)
+	if err != nil {
+		t.Fatalf("getting %q: %v", id, err)
+	}
+	return *article
+}
+
+// decodeJSON decodes the recorded body into v, failing the test if it
+// isn't valid JSON.
+func decodeJSON(t testing.TB, rec *httptest.ResponseRecorder, v any) {
+	t.Helper()
+	if err := json.Unmarshal(rec.Body.Bytes(), v); err != nil {
+		t.Fatalf("decoding %q: %v", rec.Body.String(), err)
+	}
+}
+
+func TestMergePatchArticle(t *testing.T) {
+	tests := []struct {
+		name   string
+		patch  string
+		status int
+		check  func(t *testing.T, got Article)
+	}{
+		{
+			name:   "set",
+			patch:  `{"title":"Renamed","tags":["go"]}`,
+			status: http.StatusOK,
+			check: func(t *testing.T, got Article) {
+				if got.Title != "Renamed" || !slices.Equal(got.Tags, []string{"go"}) {
+					t.Errorf("got title %q tags %v, want Renamed [go]", got.Title, got.Tags)
+				}
+			},
+		},
+		{
+			name:   "clear via null",
+			patch:  `{"tags":null,"content":null}`,
+			status: http.StatusOK,
+			check: func(t *testing.T, got Article) {
+				if len(got.Tags) != 0 || got.Content != "" {
+					t.Errorf("got tags %v content %q, want both cleared", got.Tags, got.Content)
+				}
+			},
+		},
+		{
+			name:   "omitted fields are kept",
+			patch:  `{"content":"new body"}`,
+			status: http.StatusOK,
+			check: func(t *testing.T, got Article) {
+				if got.Title != "Original" || !slices.Equal(got.Tags, []string{"a", "b"}) || got.Content != "new body" {
+					t.Errorf("got %+v, want title and tags untouched", got)
+				}
+			},
+		},
+		{
+			name:   "id cannot change",
+			patch:  `{"id":"other"}`,
+			status: http.StatusOK,
+			check: func(t *testing.T, got Article) {
+				if got.ID != "a1" {
+					t.Errorf("got id %q, want a1", got.ID)
+				}
+			},
+		},
+		{name: "invalid result", patch: `{"title":""}`, status: http.StatusUnprocessableEntity},
+		{name: "not json", patch: `{"title":`, status: http.StatusBadRequest},
+		{name: "empty body", patch: ``, status: http.StatusBadRequest},
+		{name: "too large", patch: `{"content":"` + strings.Repeat("x", maxPatchBytes) + `"}`, status: http.StatusRequestEntityTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := newTestSvc()
+			mustAdd(t, svc, Article{ID: "a1", Title: "Original", Tags: []string{"a", "b"}, Content: "body"})
+			h := newTestHandler(svc)
+
+			rec := doRequest(h, "PATCH", "/articles/a1", tt.patch, "Content-Type", mergePatchContentType)
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.status, rec.Body)
+			}
+			if tt.check != nil {
+				tt.check(t, mustGet(t, svc, "a1"))
+			}
+		})
+	}
+}
+
+func TestPatchArticleRejectsOtherContentTypes(t *testing.T) {
+	svc := newTestSvc()
+	mustAdd(t, svc, Article{ID: "a1", Title: "Original"})
+
+	rec := doRequest(newTestHandler(svc), "PATCH", "/articles/a1", `{"title":"x"}`, "Content-Type", "application/json")
+	if rec.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("status = %d, want 415", rec.Code)
+	}
+}
+
+func TestPatchUnknownArticle(t *testing.T) {
+	rec := doRequest(newTestHandler(newTestSvc()), "PATCH", "/articles/nope", `{"title":"x"}`, "Content-Type", mergePatchContentType)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want 404", rec.Code)
+	}
+}
+
+func TestJSONPatchArticle(t *testing.T) {
+	tests := []struct {
+		name   string
+		patch  string
+		status int
+		check  func(t *testing.T, got Article)
+	}{
+		{
+			name:   "replace",
+			patch:  `[{"op":"replace","path":"/title","value":"Renamed"}]`,
+			status: http.StatusOK,
+			check: func(t *testing.T, got Article) {
+				if got.Title != "Renamed" {
+					t.Errorf("title = %q, want Renamed", got.Title)
+				}
+			},
+		},
+		{
+			name:   "add to tags",
+			patch:  `[{"op":"add","path":"/tags/-","value":"c"}]`,
+			status: http.StatusOK,
+			check: func(t *testing.T, got Article) {
+				if !slices.Equal(got.Tags, []string{"a", "b", "c"}) {
+					t.Errorf("tags = %v, want [a b c]", got.Tags)
+				}
+			},
+		},
+		{
+			name:   "failing test op",
+			patch:  `[{"op":"test","path":"/title","value":"Other"},{"op":"replace","path":"/title","value":"Renamed"}]`,
+			status: http.StatusConflict,
+			check: func(t *testing.T, got Article) {
+				if got.Title != "Original" {
+					t.Errorf("title = %q, want the article untouched", got.Title)
+				}
+			},
+		},
+		{name: "passing test op", patch: `[{"op":"test","path":"/title","value":"Original"},{"op":"replace","path":"/content","value":"x"}]`, status: http.StatusOK},
+		{name: "missing path", patch: `[{"op":"replace","path":"/nope/deeper","value":1}]`, status: http.StatusUnprocessableEntity},
+		{name: "invalid result", patch: `[{"op":"replace","path":"/status","value":"archived"}]`, status: http.StatusUnprocessableEntity},
+		{name: "not a patch", patch: `{"op":"replace"}`, status: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := newTestSvc()
+			mustAdd(t, svc, Article{ID: "a1", Title: "Original", Tags: []string{"a", "b"}})
+
+			rec := doRequest(newTestHandler(svc), "PATCH", "/articles/a1", tt.patch, "Content-Type", jsonPatchContentType)
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.status, rec.Body)
+			}
+			if tt.check != nil {
+				tt.check(t, mustGet(t, svc, "a1"))
+			}
+		})
+	}
+}
+
+func TestInMemoryRepoEvictionOrder(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2024, 1, d, 0, 0, 0, 0, time.UTC) }
+	// Inserted in this order; b was published first, a inserted first.
+	stored := []Article{
+		{ID: "a", Title: "A", PublishAt: day(2)},
+		{ID: "b", Title: "B", PublishAt: day(1)},
+		{ID: "c", Title: "C", PublishAt: day(3)},
+	}
+
+	tests := []struct {
+		name      string
+		policy    evictionPolicy
+		inserts   []Article
+		wantIDs   []string
+		wantError error
+	}{
+		{
+			name:    "oldest published goes first",
+			policy:  evictOldestPublished,
+			inserts: []Article{{ID: "d", Title: "D", PublishAt: day(4)}},
+			wantIDs: []string{"a", "c", "d"},
+		},
+		{
+			name:    "evicts one per insert",
+			policy:  evictOldestPublished,
+			inserts: []Article{{ID: "d", Title: "D", PublishAt: day(4)}, {ID: "e", Title: "E", PublishAt: day(5)}},
+			wantIDs: []string{"c", "d", "e"},
+		},
+		{
+			name:    "oldest inserted goes first",
+			policy:  evictOldestInserted,
+			inserts: []Article{{ID: "d", Title: "D", PublishAt: day(4)}},
+			wantIDs: []string{"b", "c", "d"},
+		},
+		{
+			name:      "reject when full",
+			policy:    rejectWhenFull,
+			inserts:   []Article{{ID: "d", Title: "D"}},
+			wantIDs:   []string{"a", "b", "c"},
+			wantError: ErrRepoFull,
+		},
+		{
+			// Replacing a stored article doesn't count against capacity.
+			name:    "replacing does not evict",
+			policy:  rejectWhenFull,
+			inserts: []Article{{ID: "b", Title: "B2"}},
+			wantIDs: []string{"a", "b", "c"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			repo := newInMemoryRepo(withCapacity(len(stored), tt.policy))
+			for _, article := range stored {
+				if err := repo.InsertArticle(ctx, article); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			for _, article := range tt.inserts {
+				if err := repo.InsertArticle(ctx, article); !errors.Is(err, tt.wantError) {
+					t.Fatalf("inserting %q: got %v, want %v", article.ID, err, tt.wantError)
+				}
+			}
+
+			if got := storedIDs(t, repo); !slices.Equal(got, tt.wantIDs) {
+				t.Errorf("stored %v, want %v", got, tt.wantIDs)
+			}
+		})
+	}
+}
+
+// storedIDs lists the IDs stored in repo, sorted.
+func storedIDs(t testing.TB, repo ArticlesRepo) []string {
+	t.Helper()
+	articles, err := repo.AllArticles(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := make([]string, 0, len(articles))
+	for _, article := range articles {
+		ids = append(ids, article.ID)
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+func TestArticleValidateAttachments(t *testing.T) {
+	valid := Attachment{URL: "https://cdn.example.com/a.png", MIMEType: "image/png", Size: 10, Alt: "a"}
+	tests := []struct {
+		name       string
+		attachment Attachment
+		wantField  string
+	}{
+		{name: "valid", attachment: valid},
+		{name: "relative url", attachment: Attachment{URL: "/a.png", MIMEType: "image/png"}, wantField: "attachments[0].url"},
+		{name: "ftp url", attachment: Attachment{URL: "ftp://example.com/a.png", MIMEType: "image/png"}, wantField: "attachments[0].url"},
+		{name: "unsupported type", attachment: Attachment{URL: valid.URL, MIMEType: "application/x-msdownload"}, wantField: "attachments[0].mimeType"},
+		{name: "negative size", attachment: Attachment{URL: valid.URL, MIMEType: "image/png", Size: -1}, wantField: "attachments[0].size"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Article{ID: "a1", Title: "T", Attachments: []Attachment{tt.attachment}}.Validate()
+			if tt.wantField == "" {
+				if err != nil {
+					t.Fatalf("Validate() = %v, want nil", err)
+				}
+				return
+			}
+			var verr *ValidationError
+			if !errors.As(err, &verr) || len(verr.Fields) != 1 || verr.Fields[0].Field != tt.wantField {
+				t.Fatalf("Validate() = %v, want one error on %s", err, tt.wantField)
+			}
+		})
+	}
+}
+
+func TestArticleAttachments(t *testing.T) {
+	svc := newTestSvc()
+	attachments := []Attachment{
+		{URL: "https://cdn.example.com/a.png", MIMEType: "image/png", Size: 10, Alt: "a"},
+		{URL: "https://cdn.example.com/b.pdf", MIMEType: "application/pdf", Size: 20},
+	}
+	mustAdd(t, svc, Article{ID: "with", Title: "With", Attachments: attachments}, Article{ID: "without", Title: "Without"})
+	h := newTestHandler(svc)
+
+	tests := []struct {
+		id     string
+		status int
+		want   []Attachment
+	}{
+		{id: "with", status: http.StatusOK, want: attachments},
+		{id: "without", status: http.StatusOK, want: []Attachment{}},
+		{id: "missing", status: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.id, func(t *testing.T) {
+			rec := doRequest(h, "GET", "/articles/"+tt.id+"/attachments", "")
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.status)
+			}
+			if tt.want == nil {
+				return
+			}
+			var got []Attachment
+			decodeJSON(t, rec, &got)
+			if !slices.Equal(got, tt.want) || got == nil {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCreateArticleRejectsInvalidAttachment(t *testing.T) {
+	body := `{"id":"a1","title":"T","attachments":[{"url":"nope","mimeType":"image/png"}]}`
+	rec := doRequest(newTestHandler(newTestSvc()), "PUT", "/articles", body)
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("status = %d, want 422: %s", rec.Code, rec.Body)
+	}
+	if !strings.Contains(rec.Body.String(), "attachments[0].url") {
+		t.Errorf("body %q doesn't name the invalid field", rec.Body)
+	}
+}
+
+// filledRepo returns an in-memory repo holding n articles with IDs a0 to
+// a<n-1>, each carrying three of 100 tags.
+func filledRepo(b *testing.B, n int) *inMemoryRepo {
+	b.Helper()
+	repo := newInMemoryRepo()
+	for i := 0; i < n; i++ {
+		article := benchArticle(i)
+		if err := repo.InsertArticle(context.Background(), article); err != nil {
+			b.Fatal(err)
+		}
+	}
+	return repo
+}
+
+func benchArticle(i int) Article {
+	return Article{
+		ID:        "a" + strconv.Itoa(i),
+		Title:     "Article " + strconv.Itoa(i),
+		Tags:      []string{"t" + strconv.Itoa(i%100), "t" + strconv.Itoa((i+1)%100), "t" + strconv.Itoa((i+2)%100)},
+		Content:   strings.Repeat("lorem ipsum ", 50),
+		PublishAt: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC).Add(time.Duration(i) * time.Minute),
+		Status:    StatusPublished,
+	}
+}
+
+func BenchmarkInMemoryRepoAllArticles(b *testing.B) {
+	for _, n := range []int{100, 10000} {
+		b.Run(strconv.Itoa(n), func(b *testing.B) {
+			repo := filledRepo(b, n)
+			ctx := context.Background()
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := repo.AllArticles(ctx); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkInMemoryRepoArticleByID(b *testing.B) {
+	repo := filledRepo(b, 10000)
+	ctx := context.Background()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := repo.ArticleByID(ctx, "a"+strconv.Itoa(i%10000)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkInMemoryRepoInsertArticle(b *testing.B) {
+	repo := newInMemoryRepo()
+	ctx := context.Background()
+	articles := make([]Article, b.N)
+	for i := range articles {
+		articles[i] = benchArticle(i)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := repo.InsertArticle(ctx, articles[i]); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// linearArticlesByTags is ArticlesByTags without the tag index: a scan of
+// every article, as it was before the index existed.
+func linearArticlesByTags(repo *inMemoryRepo, tags []string) []Article {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	var articles []Article
+	for _, article := range repo.articles {
+		if slices.ContainsFunc(tags, func(tag string) bool { return !slices.Contains(article.Tags, tag) }) {
+			continue
+		}
+		articles = append(articles, article)
+	}
+	return articles
+}
+
+// BenchmarkInMemoryRepoArticlesByTags compares the indexed lookup with a
+// linear scan. With 100 tags each tag matches 3% of the articles.
+func BenchmarkInMemoryRepoArticlesByTags(b *testing.B) {
+	for _, n := range []int{100, 10000} {
+		repo := filledRepo(b, n)
+		ctx := context.Background()
+		for _, tags := range [][]string{{"t7"}, {"t7", "t8"}} {
+			name := strconv.Itoa(n) + "/" + strings.Join(tags, "+")
+			b.Run("indexed/"+name, func(b *testing.B) {
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					if _, err := repo.ArticlesByTags(ctx, tags); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+			b.Run("linear/"+name, func(b *testing.B) {
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					linearArticlesByTags(repo, tags)
+				}
+			})
+		}
+	}
+}
+
+// checkTagIndex fails the test unless repo's tag index holds exactly the
+// tags of its stored articles.
+func checkTagIndex(t *testing.T, repo *inMemoryRepo) {
+	t.Helper()
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	want := make(map[string]map[string]struct{})
+	for id, article := range repo.articles {
+		for _, tag := range article.Tags {
+			if want[tag] == nil {
+				want[tag] = make(map[string]struct{})
+			}
+			want[tag][id] = struct{}{}
+		}
+	}
+	if !reflect.DeepEqual(repo.tags, want) {
+		t.Errorf("tag index = %v, want %v", repo.tags, want)
+	}
+}
+
+func TestInMemoryRepoTagIndex(t *testing.T) {
+	ctx := context.Background()
+	day := func(d int) time.Time { return time.Date(2024, 1, d, 0, 0, 0, 0, time.UTC) }
+	repo := newInMemoryRepo(withCapacity(3, evictOldestPublished))
+
+	steps := []struct {
+		name string
+		do   func() error
+		// query and want check ArticlesByTags after the step.
+		query []string
+		want  []string
+	}{
+		{"insert", func() error {
+			return errors.Join(
+				repo.InsertArticle(ctx, Article{ID: "a1", Title: "T", Tags: []string{"go", "web"}, PublishAt: day(1)}),
+				repo.InsertArticle(ctx, Article{ID: "a2", Title: "T", Tags: []string{"go"}, PublishAt: day(2)}),
+			)
+		}, []string{"go"}, []string{"a1", "a2"}},
+		{"update adds a tag", func() error {
+			return repo.UpdateArticle(ctx, Article{ID: "a2", Title: "T", Tags: []string{"go", "web"}, PublishAt: day(2)})
+		}, []string{"go", "web"}, []string{"a1", "a2"}},
+		{"update removes a tag", func() error {
+			return repo.UpdateArticle(ctx, Article{ID: "a1", Title: "T", Tags: []string{"web"}, PublishAt: day(1)})
+		}, []string{"go"}, []string{"a2"}},
+		{"update drops the last article of a tag", func() error {
+			return repo.UpdateArticle(ctx, Article{ID: "a2", Title: "T", Tags: []string{"rust"}, PublishAt: day(2)})
+		}, []string{"go"}, nil},
+		{"update clears every tag", func() error {
+			return repo.UpdateArticle(ctx, Article{ID: "a1", Title: "T", PublishAt: day(1)})
+		}, []string{"web"}, nil},
+		{"delete", func() error {
+			return errors.Join(
+				repo.InsertArticle(ctx, Article{ID: "a3", Title: "T", Tags: []string{"rust"}, PublishAt: day(3)}),
+				repo.DeleteArticle(ctx, "a2"),
+			)
+		}, []string{"rust"}, []string{"a3"}},
+		{"eviction", func() error {
+			// a1 is the oldest published article and makes way for a5.
+			return errors.Join(
+				repo.UpdateArticle(ctx, Article{ID: "a1", Title: "T", Tags: []string{"old"}, PublishAt: day(1)}),
+				repo.InsertArticle(ctx, Article{ID: "a4", Title: "T", Tags: []string{"rust"}, PublishAt: day(4)}),
+				repo.InsertArticle(ctx, Article{ID: "a5", Title: "T", Tags: []string{"new"}, PublishAt: day(5)}),
+			)
+		}, []string{"old"}, nil},
+		{"clear", func() error {
+			_, err := repo.Clear(ctx)
+			return err
+		}, []string{"rust"}, nil},
+	}
+	for _, step := range steps {
+		if err := step.do(); err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		checkTagIndex(t, repo)
+		got, err := repo.ArticlesByTags(ctx, step.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ids := articleIDs(got); !slices.Equal(ids, step.want) {
+			t.Errorf("%s: ArticlesByTags(%v) = %v, want %v", step.name, step.query, ids, step.want)
+		}
+		if ids := articleIDs(linearArticlesByTags(repo, step.query)); !slices.Equal(ids, step.want) {
+			t.Errorf("%s: a linear scan finds %v, want %v", step.name, ids, step.want)
+		}
+	}
+}
+
+func TestDuplicateTitleWarning(t *testing.T) {
+	tests := []struct {
+		name     string
+		enabled  bool
+		title    string
+		warnings []string
+	}{
+		{name: "same title", enabled: true, title: "Hello World", warnings: []string{`article "a1" already has this title`}},
+		{name: "normalized title", enabled: true, title: "  hello   WORLD ", warnings: []string{`article "a1" already has this title`}},
+		{name: "different title", enabled: true, title: "Goodbye"},
+		{name: "disabled", title: "Hello World"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := newTestSvc(withDuplicateTitleWarnings(tt.enabled))
+			mustAdd(t, svc, Article{ID: "a1", Title: "Hello World"})
+			h := newTestHandler(svc)
+
+			rec := doRequest(h, "PUT", "/articles", `{"id":"a2","title":"`+tt.title+`"}`)
+			if rec.Code != http.StatusCreated {
+				t.Fatalf("status = %d, want 201: %s", rec.Code, rec.Body)
+			}
+			var resp createArticleResponse
+			decodeJSON(t, rec, &resp)
+			if resp.ID != "a2" || !slices.Equal(resp.Warnings, tt.warnings) {
+				t.Errorf("got %+v, want id a2 and warnings %q", resp, tt.warnings)
+			}
+			// The article is stored despite the warning.
+			if got := mustGet(t, svc, "a2"); got.Title != tt.title {
+				t.Errorf("stored title %q, want %q", got.Title, tt.title)
+			}
+		})
+	}
+}
+
+func TestDefaultPublishAt(t *testing.T) {
+	now := time.Date(2024, 6, 1, 9, 30, 0, 0, time.UTC)
+	given := time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		enabled bool
+		body    string
+		want    time.Time
+	}{
+		{name: "omitted", enabled: true, body: `{"id":"a1","title":"T"}`, want: now},
+		{name: "null", enabled: true, body: `{"id":"a1","title":"T","publishAt":null}`, want: now},
+		{name: "given", enabled: true, body: `{"id":"a1","title":"T","publishAt":"2023-01-02T03:04:05Z"}`, want: given},
+		{name: "draft", enabled: true, body: `{"id":"a1","title":"T","status":"draft"}`},
+		{name: "disabled", body: `{"id":"a1","title":"T"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := newTestSvc(withClock(newFakeClock(now)), withDefaultPublishAt(tt.enabled))
+			rec := doRequest(newTestHandler(svc), "PUT", "/articles", tt.body)
+			if rec.Code != http.StatusCreated {
+				t.Fatalf("status = %d, want 201: %s", rec.Code, rec.Body)
+			}
+			if got := mustGet(t, svc, "a1").PublishAt; !got.Equal(tt.want) {
+				t.Errorf("publishAt = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEachArticle(t *testing.T) {
+	const stored = 25
+	errStop := errors.New("stop")
+
+	repos := []struct {
+		name string
+		repo ArticlesRepo
+	}{
+		{name: "in-memory", repo: newInMemoryRepo()},
+		{name: "sharded", repo: newShardedRepo(4)},
+	}
+	for _, r := range repos {
+		for i := 0; i < stored; i++ {
+			if err := r.repo.InsertArticle(context.Background(), Article{ID: "a" + strconv.Itoa(i), Title: "T"}); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		t.Run(r.name+"/counts every article once", func(t *testing.T) {
+			seen := make(map[string]int)
+			if err := r.repo.EachArticle(context.Background(), func(a Article) error {
+				seen[a.ID]++
+				return nil
+			}); err != nil {
+				t.Fatal(err)
+			}
+			if len(seen) != stored {
+				t.Errorf("saw %d articles, want %d", len(seen), stored)
+			}
+			for id, n := range seen {
+				if n != 1 {
+					t.Errorf("saw %s %d times", id, n)
+				}
+			}
+		})
+
+		t.Run(r.name+"/stops at the first error", func(t *testing.T) {
+			calls := 0
+			err := r.repo.EachArticle(context.Background(), func(Article) error {
+				calls++
+				if calls == 3 {
+					return errStop
+				}
+				return nil
+			})
+			if !errors.Is(err, errStop) || calls != 3 {
+				t.Errorf("got %v after %d calls, want errStop after 3", err, calls)
+			}
+		})
+
+		t.Run(r.name+"/stops when the context is cancelled", func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			calls := 0
+			err := r.repo.EachArticle(ctx, func(Article) error {
+				calls++
+				cancel()
+				return nil
+			})
+			if !errors.Is(err, context.Canceled) || calls != 1 {
+				t.Errorf("got %v after %d calls, want context.Canceled after 1", err, calls)
+			}
+		})
+
+		t.Run(r.name+"/articleSeq", func(t *testing.T) {
+			n := 0
+			for _, err := range articleSeq(context.Background(), r.repo) {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if n++; n == 5 {
+					break
+				}
+			}
+			if n != 5 {
+				t.Errorf("ranged over %d articles before break, want 5", n)
+			}
+		})
+	}
+}
+
+func TestEachArticleMayCallBackIntoTheRepo(t *testing.T) {
+	repo := newInMemoryRepo()
+	ctx := context.Background()
+	for _, id := range []string{"a", "b", "c"} {
+		if err := repo.InsertArticle(ctx, Article{ID: id, Title: id}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Deleting from inside the callback must neither deadlock nor yield
+	// the deleted articles afterwards.
+	var seen []string
+	err := repo.EachArticle(ctx, func(a Article) error {
+		seen = append(seen, a.ID)
+		for _, id := range []string{"a", "b", "c"} {
+			if id != a.ID {
+				repo.DeleteArticle(ctx, id)
+			}
+		}
+		return nil
+	})
+	if err != nil || len(seen) != 1 {
+		t.Errorf("got %v, saw %v; want exactly one article", err, seen)
+	}
+}
+
+// countingRepo is an inMemoryRepo that counts ArticleByID calls and, while
+// gate is non-nil, holds each of them until gate is closed.
+type countingRepo struct {
+	*inMemoryRepo
+	byID    atomic.Int64
+	gate    chan struct{}
+	entered chan struct{}
+}
+
+func newCountingRepo() *countingRepo {
+	return &countingRepo{inMemoryRepo: newInMemoryRepo(), entered: make(chan struct{}, 1024)}
+}
+
+func (repo *countingRepo) ArticleByID(ctx context.Context, id string) (*Article, error) {
+	repo.byID.Add(1)
+	repo.entered <- struct{}{}
+	if repo.gate != nil {
+		<-repo.gate
+	}
+	return repo.inMemoryRepo.ArticleByID(ctx, id)
+}
+
+func TestConcurrentReadsShareOneRepoCall(t *testing.T) {
+	repo := newCountingRepo()
+	svc := newArticleSvc(repo)
+	mustAdd(t, svc, Article{ID: "a1", Title: "Hot"})
+	h := newTestHandler(svc)
+
+	repo.byID.Store(0)
+	for len(repo.entered) > 0 {
+		<-repo.entered
+	}
+	repo.gate = make(chan struct{})
+
+	const readers = 50
+	var (
+		started sync.WaitGroup
+		done    sync.WaitGroup
+		codes   = make(chan int, readers)
+	)
+	started.Add(readers)
+	done.Add(readers)
+	for i := 0; i < readers; i++ {
+		go func() {
+			defer done.Done()
+			started.Done()
+			codes <- doRequest(h, "GET", "/articles/a1", "").Code
+		}()
+	}
+
+	<-repo.entered
+	started.Wait()
+	// Give the remaining readers time to join the call in flight.
+	time.Sleep(50 * time.Millisecond)
+	close(repo.gate)
+	done.Wait()
+	close(codes)
+
+	for code := range codes {
+		if code != http.StatusOK {
+			t.Errorf("a reader got %d, want 200", code)
+		}
+	}
+	if n := repo.byID.Load(); n != 1 {
+		t.Errorf("repo was hit %d times by %d concurrent readers, want 1", n, readers)
+	}
+}
+
+func TestSharedReadReturnsIndependentCopies(t *testing.T) {
+	svc := newTestSvc()
+	mustAdd(t, svc, Article{ID: "a1", Title: "T", Tags: []string{"a"}})
+
+	first := mustGet(t, svc, "a1")
+	first.Title = "changed"
+	if got := mustGet(t, svc, "a1"); got.Title != "T" {
+		t.Errorf("a caller's change leaked into the store: title %q", got.Title)
+	}
+}
+
+// fuzzBodyLimit is the body size limit FuzzAddArticle serves under, small
+// enough that the fuzzer reaches it.
+const fuzzBodyLimit = 8 << 10
+
+// FuzzAddArticle feeds arbitrary bodies to PUT /articles, through decoding,
+// validation and insertion. It must never panic, must answer with one of the
+// statuses the API documents for creates, and must store an article exactly
+// when it answers 201, one that listings can still encode. Seeds live in
+// testdata/fuzz/FuzzAddArticle.
+func FuzzAddArticle(f *testing.F) {
+	f.Add([]byte(`{"id":"a1","title":"Hello","tags":["go"],"content":"Body"}`))
+
+	f.Fuzz(func(t *testing.T, body []byte) {
+		svc := newTestSvc()
+		mustAdd(t, svc, Article{ID: "taken", Title: "Taken"})
+		h := maxBodyMiddleware(fuzzBodyLimit, fuzzBodyLimit, newTestHandler(svc))
+
+		rec := doRequest(h, "PUT", "/articles", string(body))
+		stored, err := svc.repo.AllArticles(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		switch rec.Code {
+		case http.StatusCreated:
+			var resp createArticleResponse
+			decodeJSON(t, rec, &resp)
+			if len(stored) != 2 {
+				t.Fatalf("answered 201 but %d articles are stored", len(stored))
+			}
+			article := mustGet(t, svc, resp.ID)
+			if err := article.Validate(); err != nil {
+				t.Fatalf("stored an invalid article %+v: %v", article, err)
+			}
+			if rec := doRequest(h, "GET", "/articles", ""); rec.Code != http.StatusOK {
+				t.Fatalf("listing after storing %+v: status %d: %s", article, rec.Code, rec.Body)
+			}
+		case http.StatusBadRequest, http.StatusConflict, http.StatusRequestEntityTooLarge, http.StatusUnprocessableEntity:
+			if len(stored) != 1 {
+				t.Fatalf("answered %d but stored an article: %s", rec.Code, rec.Body)
+			}
+		default:
+			t.Fatalf("status %d for body %q: %s", rec.Code, body, rec.Body)
+		}
+	})
+}
+
+func TestEmptyRequestBody(t *testing.T) {
+	tests := []struct {
+		name     string
+		method   string
+		target   string
+		body     string
+		headers  []string
+		wantCode int
+		wantBody string
+	}{
+		{"create", "PUT", "/articles", "", nil, http.StatusBadRequest, "request body is required"},
+		{"update", "PUT", "/articles/a1", "", nil, http.StatusBadRequest, "request body is required"},
+		{"merge patch", "PATCH", "/articles/a1", "", []string{"Content-Type", mergePatchContentType}, http.StatusBadRequest, "request body is required"},
+		{"whitespace patch", "PATCH", "/articles/a1", " \n\t", []string{"Content-Type", jsonPatchContentType}, http.StatusBadRequest, "request body is required"},
+		{"truncated create", "PUT", "/articles", `{"id":"a2","title":`, nil, http.StatusBadRequest, "bad request"},
+		{"truncated update", "PUT", "/articles/a1", `{"title":"T"`, nil, http.StatusBadRequest, "bad request"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := newTestSvc()
+			mustAdd(t, svc, Article{ID: "a1", Title: "Original"})
+			h := newTestHandler(svc)
+
+			rec := doRequest(h, tt.method, tt.target, tt.body, tt.headers...)
+			if rec.Code != tt.wantCode {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantCode, rec.Body)
+			}
+			if got := rec.Body.String(); got != tt.wantBody {
+				t.Errorf("body = %q, want %q", got, tt.wantBody)
+			}
+			if got := mustGet(t, svc, "a1"); got.Title != "Original" {
+				t.Errorf("article changed to %+v", got)
+			}
+			if ids := storedIDs(t, svc.repo); !slices.Equal(ids, []string{"a1"}) {
+				t.Errorf("stored %v, want [a1]", ids)
+			}
+		})
+	}
+}
+
+func TestEmptyListStatus(t *testing.T) {
+	tests := []struct {
+		name      string
+		noContent bool
+		articles  []Article
+		target    string
+		want      int
+		wantBody  string
+	}{
+		{"default, empty repo", false, nil, "/articles", http.StatusOK, "[]\n"},
+		{"default, nothing matches", false, []Article{{ID: "a1", Title: "A"}}, "/articles?tag=none", http.StatusOK, "[]\n"},
+		{"204, empty repo", true, nil, "/articles", http.StatusNoContent, ""},
+		{"204, nothing matches", true, []Article{{ID: "a1", Title: "A"}}, "/articles?tag=none", http.StatusNoContent, ""},
+		{"204, past the last page", true, []Article{{ID: "a1", Title: "A"}}, "/articles?offset=5", http.StatusNoContent, ""},
+		{"204, not empty", true, []Article{{ID: "a1", Title: "A"}}, "/articles", http.StatusOK, ""},
+	}
+	for _, tt := range tests {
+		svc := newTestSvc()
+		mustAdd(t, svc, tt.articles...)
+		h := newTestHandler(svc, withEmptyListNoContent(tt.noContent))
+
+		rec := doRequest(h, "GET", tt.target, "")
+		if rec.Code != tt.want {
+			t.Errorf("%s: status = %d, want %d", tt.name, rec.Code, tt.want)
+		}
+		if tt.want != http.StatusOK || tt.wantBody != "" {
+			if got := rec.Body.String(); got != tt.wantBody {
+				t.Errorf("%s: body = %q, want %q", tt.name, got, tt.wantBody)
+			}
+		}
+		if rec.Header().Get("X-Total-Count") == "" {
+			t.Errorf("%s: no X-Total-Count", tt.name)
+		}
+		if head := doRequest(h, "HEAD", tt.target, ""); head.Code != tt.want {
+			t.Errorf("%s: HEAD status = %d, want %d", tt.name, head.Code, tt.want)
+		}
+	}
+}
+
+func TestHeadArticles(t *testing.T) {
+	svc := newTestSvc()
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	mustAdd(t, svc,
+		Article{ID: "a1", Title: "A", Tags: []string{"go"}, PublishAt: base},
+		Article{ID: "a2", Title: "B", Tags: []string{"go"}, PublishAt: base.Add(time.Hour), Pinned: true},
+		Article{ID: "a3", Title: "C", Tags: []string{"rust"}, PublishAt: base.Add(2 * time.Hour)},
+	)
+	// Through a real server, which drops HEAD bodies the way clients see it.
+	srv := httptest.NewServer(newTestHandler(svc))
+	t.Cleanup(srv.Close)
+
+	tests := []struct {
+		query string
+		total string
+	}{
+		{"", "3"},
+		{"?tag=go", "2"},
+		{"?tag=none", "0"},
+		{"?pinned=true", "1"},
+		{"?from=" + base.Add(30*time.Minute).Format(time.RFC3339), "2"},
+		{"?offset=1", "3"},
+	}
+	for _, tt := range tests {
+		get, err := http.Get(srv.URL + "/articles" + tt.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		get.Body.Close()
+		head, err := http.Head(srv.URL + "/articles" + tt.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(head.Body)
+		head.Body.Close()
+
+		if head.StatusCode != http.StatusOK || len(body) != 0 {
+			t.Errorf("HEAD %s: got %d with %d body bytes, want 200 and none", tt.query, head.StatusCode, len(body))
+		}
+		if got := head.Header.Get("X-Total-Count"); got != tt.total {
+			t.Errorf("HEAD %s: X-Total-Count = %q, want %s", tt.query, got, tt.total)
+		}
+		for _, name := range []string{"X-Total-Count", "Last-Modified", "Content-Type"} {
+			if head.Header.Get(name) != get.Header.Get(name) {
+				t.Errorf("HEAD %s: %s = %q, GET has %q", tt.query, name, head.Header.Get(name), get.Header.Get(name))
+			}
+		}
+	}
+}
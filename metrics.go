@@ -0,0 +1,168 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// sizeBuckets covers payloads from 64 bytes up to 16 MiB.
+var sizeBuckets = prometheus.ExponentialBuckets(64, 4, 10)
+
+// httpMetrics collects per-route request metrics into its own registry,
+// served in the Prometheus text format by handler.
+type httpMetrics struct {
+	registry     *prometheus.Registry
+	requests     *prometheus.CounterVec
+	duration     *prometheus.HistogramVec
+	requestSize  *prometheus.HistogramVec
+	responseSize *prometheus.HistogramVec
+}
+
+// newMetrics returns the metrics collector, or nil when -metrics is off.
+func newMetrics(cfg config) *httpMetrics {
+	if !cfg.metrics {
+		return nil
+	}
+	return newHTTPMetrics()
+}
+
+func newHTTPMetrics() *httpMetrics {
+	m := &httpMetrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "http_requests_total",
+			Help: "Requests served, by route, method and status code.",
+		}, []string{"route", "method", "code"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "http_request_duration_seconds",
+			Help:    "Time spent serving requests, by route and method.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"route", "method"}),
+		requestSize: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "http_request_size_bytes",
+			Help:    "Request body bytes read by the handler, by route.",
+			Buckets: sizeBuckets,
+		}, []string{"route"}),
+		responseSize: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "http_response_size_bytes",
+			Help:    "Response body bytes written by the handler, before any compression, by route.",
+			Buckets: sizeBuckets,
+		}, []string{"route"}),
+	}
+	m.registry.MustRegister(m.requests, m.duration, m.requestSize, m.responseSize)
+	return m
+}
+
+func (m *httpMetrics) handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// middleware records metrics for every request matched by the router it is
+// installed on. Routes are labelled by their path template, such as
+// /articles/{id}, to keep the label set small. Streaming responses are
+// observed once they end.
+func (m *httpMetrics) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := "unknown"
+		if current := mux.CurrentRoute(r); current != nil {
+			if tpl, err := current.GetPathTemplate(); err == nil {
+				route = tpl
+			}
+		}
+
+		started := time.Now()
+		body := &countingReader{ReadCloser: r.Body}
+		r.Body = body
+		rec := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(rec, r)
+
+		if rec.status == 0 {
+			rec.status = http.StatusOK
+		}
+		m.requests.WithLabelValues(route, r.Method, strconv.Itoa(rec.status)).Inc()
+		m.duration.WithLabelValues(route, r.Method).Observe(time.Since(started).Seconds())
+		m.requestSize.WithLabelValues(route).Observe(float64(body.n))
+		m.responseSize.WithLabelValues(route).Observe(float64(rec.bytes))
+	})
+}
+
+// countingReader counts the bytes read through it.
+type countingReader struct {
+	io.ReadCloser
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.ReadCloser.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// metricSnapshot is the JSON form of one metric family in /admin/metrics.json.
+type metricSnapshot struct {
+	Help    string         `json:"help"`
+	Type    string         `json:"type"`
+	Samples []metricSample `json:"samples"`
+}
+
+// metricSample is one labelled series. Counters and gauges carry Value;
+// histograms carry Count, Sum and cumulative Buckets keyed by upper bound.
+type metricSample struct {
+	Labels  map[string]string `json:"labels,omitempty"`
+	Value   *float64          `json:"value,omitempty"`
+	Count   *uint64           `json:"count,omitempty"`
+	Sum     *float64          `json:"sum,omitempty"`
+	Buckets map[string]uint64 `json:"buckets,omitempty"`
+}
+
+// snapshot gathers the registry, the same one /metrics serves, into a map
+// from metric name to its samples.
+func (m *httpMetrics) snapshot() (map[string]metricSnapshot, error) {
+	families, err := m.registry.Gather()
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := make(map[string]metricSnapshot, len(families))
+	for _, family := range families {
+		s := metricSnapshot{
+			Help:    family.GetHelp(),
+			Type:    strings.ToLower(family.GetType().String()),
+			Samples: make([]metricSample, 0, len(family.GetMetric())),
+		}
+		for _, metric := range family.GetMetric() {
+			var sample metricSample
+			if pairs := metric.GetLabel(); len(pairs) > 0 {
+				sample.Labels = make(map[string]string, len(pairs))
+				for _, pair := range pairs {
+					sample.Labels[pair.GetName()] = pair.GetValue()
+				}
+			}
+			switch {
+			case metric.Counter != nil:
+				sample.Value = metric.Counter.Value
+			case metric.Gauge != nil:
+				sample.Value = metric.Gauge.Value
+			case metric.Untyped != nil:
+				sample.Value = metric.Untyped.Value
+			case metric.Histogram != nil:
+				h := metric.GetHistogram()
+				sample.Count, sample.Sum = h.SampleCount, h.SampleSum
+				sample.Buckets = make(map[string]uint64, len(h.GetBucket()))
+				for _, bucket := range h.GetBucket() {
+					sample.Buckets[strconv.FormatFloat(bucket.GetUpperBound(), 'g', -1, 64)] = bucket.GetCumulativeCount()
+				}
+			}
+			s.Samples = append(s.Samples, sample)
+		}
+		snapshot[family.GetName()] = s
+	}
+	return snapshot, nil
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// metricsRepo decorates an ArticlesRepo with per-operation call counts and
+// latencies, so storage time can be told apart from transport time.
+// EachArticle's latency includes the time spent in its callback.
+type metricsRepo struct {
+	next     ArticlesRepo
+	calls    *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+}
+
+func newMetricsRepo(next ArticlesRepo, registry prometheus.Registerer) *metricsRepo {
+	repo := &metricsRepo{
+		next: next,
+		calls: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "repo_operations_total",
+			Help: "Repository calls, by operation and outcome (ok, not_found or error).",
+		}, []string{"operation", "outcome"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "repo_operation_duration_seconds",
+			Help:    "Repository call latency, by operation and outcome.",
+			Buckets: prometheus.ExponentialBuckets(0.00001, 4, 10),
+		}, []string{"operation", "outcome"}),
+	}
+	registry.MustRegister(repo.calls, repo.duration)
+	return repo
+}
+
+func (repo *metricsRepo) observe(operation string, started time.Time, err error) {
+	outcome := "ok"
+	switch {
+	case errors.Is(err, ErrArticleNotFound):
+		outcome = "not_found"
+	case err != nil:
+		outcome = "error"
+	}
+	repo.calls.WithLabelValues(operation, outcome).Inc()
+	repo.duration.WithLabelValues(operation, outcome).Observe(time.Since(started).Seconds())
+}
+
+func (repo *metricsRepo) InsertArticle(ctx context.Context, article Article) (err error) {
+	started := time.Now()
+	defer func() { repo.observe("insert", started, err) }()
+	return repo.next.InsertArticle(ctx, article)
+}
+
+func (repo *metricsRepo) UpdateArticle(ctx context.Context, article Article) (err error) {
+	started := time.Now()
+	defer func() { repo.observe("update", started, err) }()
+	return repo.next.UpdateArticle(ctx, article)
+}
+
+func (repo *metricsRepo) DeleteArticle(ctx context.Context, id string) (err error) {
+	started := time.Now()
+	defer func() { repo.observe("delete", started, err) }()
+	return repo.next.DeleteArticle(ctx, id)
+}
+
+func (repo *metricsRepo) ArticleByID(ctx context.Context, id string) (article *Article, err error) {
+	started := time.Now()
+	defer func() { repo.observe("by_id", started, err) }()
+	return repo.next.ArticleByID(ctx, id)
+}
+
+func (repo *metricsRepo) ArticleBySlug(ctx context.Context, slug string) (article *Article, err error) {
+	started := time.Now()
+	defer func() { repo.observe("by_slug", started, err) }()
+	return repo.next.ArticleBySlug(ctx, slug)
+}
+
+func (repo *metricsRepo) AllArticles(ctx context.Context) (articles []Article, err error) {
+	started := time.Now()
+	defer func() { repo.observe("all", started, err) }()
+	return repo.next.AllArticles(ctx)
+}
+
+func (repo *metricsRepo) ArticlesByTitle(ctx context.Context, title string) (articles []Article, err error) {
+	started := time.Now()
+	defer func() { repo.observe("by_title", started, err) }()
+	return repo.next.ArticlesByTitle(ctx, title)
+}
+
+func (repo *metricsRepo) ArticlesByTags(ctx context.Context, tags []string) (articles []Article, err error) {
+	started := time.Now()
+	defer func() { repo.observe("by_tags", started, err) }()
+	return repo.next.ArticlesByTags(ctx, tags)
+}
+
+func (repo *metricsRepo) ArticlesByAnyTag(ctx context.Context, tags []string) (articles []Article, err error) {
+	started := time.Now()
+	defer func() { repo.observe("by_any_tag", started, err) }()
+	return repo.next.ArticlesByAnyTag(ctx, tags)
+}
+
+func (repo *metricsRepo) TagFrequency(ctx context.Context) (counts map[string]int, err error) {
+	started := time.Now()
+	defer func() { repo.observe("tag_frequency", started, err) }()
+	return repo.next.TagFrequency(ctx)
+}
+
+func (repo *metricsRepo) SuggestTags(ctx context.Context, prefix string, limit int) (suggestions []TagSuggestion, err error) {
+	started := time.Now()
+	defer func() { repo.observe("suggest_tags", started, err) }()
+	return repo.next.SuggestTags(ctx, prefix, limit)
+}
+
+func (repo *metricsRepo) ArticlesInRange(ctx context.Context, from, to time.Time) (articles []Article, err error) {
+	started := time.Now()
+	defer func() { repo.observe("in_range", started, err) }()
+	return repo.next.ArticlesInRange(ctx, from, to)
+}
+
+func (repo *metricsRepo) Reindex(ctx context.Context) (summary ReindexSummary, err error) {
+	started := time.Now()
+	defer func() { repo.observe("reindex", started, err) }()
+	return reindexRepo(ctx, repo.next)
+}
+
+func (repo *metricsRepo) EachArticle(ctx context.Context, fn func(Article) error) (err error) {
+	started := time.Now()
+	defer func() { repo.observe("each", started, err) }()
+	return repo.next.EachArticle(ctx, fn)
+}
+
+func (repo *metricsRepo) Clear(ctx context.Context) (removed int, err error) {
+	started := time.Now()
+	defer func() { repo.observe("clear", started, err) }()
+	return repo.next.Clear(ctx)
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// brokenListRepo is an inMemoryRepo whose AllArticles always fails.
+type brokenListRepo struct {
+	*inMemoryRepo
+}
+
+func (brokenListRepo) AllArticles(context.Context) ([]Article, error) {
+	return nil, errors.New("disk on fire")
+}
+
+func TestMetricsRepoCounters(t *testing.T) {
+	ctx := context.Background()
+	repo := newMetricsRepo(brokenListRepo{newInMemoryRepo()}, prometheus.NewRegistry())
+
+	repo.InsertArticle(ctx, Article{ID: "a1", Title: "One", Tags: []string{"go"}})
+	repo.InsertArticle(ctx, Article{ID: "a2", Title: "Two"})
+	repo.UpdateArticle(ctx, Article{ID: "a1", Title: "One again"})
+	repo.UpdateArticle(ctx, Article{ID: "missing", Title: "Nobody"})
+	repo.ArticleByID(ctx, "a1")
+	repo.ArticleByID(ctx, "missing")
+	repo.AllArticles(ctx)
+	repo.ArticlesByTags(ctx, []string{"go"})
+	repo.DeleteArticle(ctx, "a2")
+
+	tests := []struct {
+		operation, outcome string
+		want               float64
+	}{
+		{"insert", "ok", 2},
+		{"update", "ok", 1},
+		{"update", "not_found", 1},
+		{"by_id", "ok", 1},
+		{"by_id", "not_found", 1},
+		{"all", "error", 1},
+		{"all", "ok", 0},
+		{"by_tags", "ok", 1},
+		{"delete", "ok", 1},
+		{"delete", "not_found", 0},
+		{"by_title", "ok", 0},
+	}
+	for _, tt := range tests {
+		got := testutil.ToFloat64(repo.calls.WithLabelValues(tt.operation, tt.outcome))
+		if got != tt.want {
+			t.Errorf("calls{%s,%s} = %v, want %v", tt.operation, tt.outcome, got, tt.want)
+		}
+	}
+	if n := testutil.CollectAndCount(repo.duration); n != 8 {
+		t.Errorf("%d latency series, want one per operation and outcome seen (8)", n)
+	}
+}
+
+func TestNewRepoAddsMetrics(t *testing.T) {
+	if _, ok := newRepo(config{}, nil).(*metricsRepo); ok {
+		t.Error("repo instrumented with metrics disabled")
+	}
+
+	metrics := newHTTPMetrics()
+	repo, ok := newRepo(config{}, metrics).(*metricsRepo)
+	if !ok {
+		t.Fatal("repo not instrumented with metrics enabled")
+	}
+	repo.InsertArticle(context.Background(), Article{ID: "a1", Title: "One"})
+	if got := testutil.ToFloat64(repo.calls.WithLabelValues("insert", "ok")); got != 1 {
+		t.Errorf("calls{insert,ok} = %v, want 1", got)
+	}
+	if n, err := testutil.GatherAndCount(metrics.registry, "repo_operations_total"); err != nil || n != 1 {
+		t.Errorf("registry has %d repo_operations_total series (%v), want 1", n, err)
+	}
+}
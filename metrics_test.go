@@ -0,0 +1,192 @@
+package main
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/gorilla/mux"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// newMetricsTestHandler mounts the article routes and a streaming route
+// behind m.middleware, the way main installs it on the root router.
+func newMetricsTestHandler(m *httpMetrics, svc ArticlesService) http.Handler {
+	root := mux.NewRouter()
+	root.Use(m.middleware)
+	root.Handle("/metrics", m.handler()).Methods("GET")
+	root.HandleFunc("/stream", func(w http.ResponseWriter, r *http.Request) {
+		for i := 0; i < 4; i++ {
+			io.WriteString(w, strings.Repeat("s", 100))
+			w.(http.Flusher).Flush()
+		}
+	})
+	newArticlesHttpTransport(svc).setupRoutes(root.PathPrefix("/articles").Subrouter())
+	return root
+}
+
+// histogram returns the histogram of family name for route.
+func histogram(t *testing.T, m *httpMetrics, name, route string) *dto.Histogram {
+	t.Helper()
+	families, err := m.registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			for _, label := range metric.GetLabel() {
+				if label.GetName() == "route" && label.GetValue() == route {
+					return metric.GetHistogram()
+				}
+			}
+		}
+	}
+	t.Fatalf("no %s series for route %s", name, route)
+	return nil
+}
+
+func TestSizeMetrics(t *testing.T) {
+	m := newHTTPMetrics()
+	svc := newTestSvc()
+	h := newMetricsTestHandler(m, svc)
+
+	body := `{"id":"a1","title":"` + strings.Repeat("t", 1000) + `"}`
+	put := doRequest(h, "PUT", "/articles", body)
+	if put.Code != http.StatusCreated {
+		t.Fatalf("PUT status = %d: %s", put.Code, put.Body)
+	}
+	get := doRequest(h, "GET", "/articles/a1", "")
+	stream := doRequest(h, "GET", "/stream", "")
+	if !stream.Flushed {
+		t.Error("the streaming response was not flushed through the middleware")
+	}
+
+	tests := []struct {
+		name, route string
+		count       uint64
+		sum         float64
+	}{
+		{"http_request_size_bytes", "/articles", 1, float64(len(body))},
+		{"http_response_size_bytes", "/articles", 1, float64(put.Body.Len())},
+		{"http_request_size_bytes", "/articles/{id}", 1, 0},
+		{"http_response_size_bytes", "/articles/{id}", 1, float64(get.Body.Len())},
+		{"http_response_size_bytes", "/stream", 1, 400},
+	}
+	for _, tt := range tests {
+		h := histogram(t, m, tt.name, tt.route)
+		if h.GetSampleCount() != tt.count || h.GetSampleSum() != tt.sum {
+			t.Errorf("%s{route=%q}: count %d sum %v, want count %d sum %v",
+				tt.name, tt.route, h.GetSampleCount(), h.GetSampleSum(), tt.count, tt.sum)
+		}
+	}
+
+	// The 1 KiB request body lands in the 1024 byte bucket and above only.
+	for _, bucket := range histogram(t, m, "http_request_size_bytes", "/articles").GetBucket() {
+		want := uint64(0)
+		if bucket.GetUpperBound() >= float64(len(body)) {
+			want = 1
+		}
+		if bucket.GetCumulativeCount() != want {
+			t.Errorf("bucket le=%v holds %d, want %d", bucket.GetUpperBound(), bucket.GetCumulativeCount(), want)
+		}
+	}
+
+	scrape := doRequest(h, "GET", "/metrics", "")
+	for _, want := range []string{
+		`http_request_size_bytes_sum{route="/articles"} ` + strconv.Itoa(len(body)),
+		`http_response_size_bytes_count{route="/stream"} 1`,
+	} {
+		if !strings.Contains(scrape.Body.String(), want) {
+			t.Errorf("scrape is missing %q", want)
+		}
+	}
+}
+
+func TestMetricsSnapshot(t *testing.T) {
+	m := newHTTPMetrics()
+	svc := newArticleSvc(newMetricsRepo(newInMemoryRepo(), m.registry))
+	mustAdd(t, svc, Article{ID: "a1", Title: "A"})
+
+	root := mux.NewRouter()
+	root.Use(m.middleware)
+	newArticlesHttpTransport(svc).setupRoutes(root.PathPrefix("/articles").Subrouter())
+	newAdminHttpTransport(svc, new(atomic.Bool), new(slog.LevelVar), false, m).setupRoutes(root.PathPrefix("/admin").Subrouter())
+	h := testAuth(root, testKeys)
+
+	doRequest(h, "GET", "/articles/a1", "")
+	doRequest(h, "GET", "/articles/a1", "")
+	doRequest(h, "GET", "/articles/missing", "")
+
+	for _, key := range []string{"", testWriteKey} {
+		var headers []string
+		if key != "" {
+			headers = []string{"X-API-Key", key}
+		}
+		if rec := doRequest(h, "GET", "/admin/metrics.json", "", headers...); rec.Code == http.StatusOK {
+			t.Errorf("key %q: the snapshot was served without the admin scope", key)
+		}
+	}
+
+	rec := doRequest(h, "GET", "/admin/metrics.json", "", "X-API-Key", testAdminKey)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d", rec.Code)
+	}
+	var snapshot map[string]metricSnapshot
+	decodeJSON(t, rec, &snapshot)
+
+	// sample finds the series of family name carrying every label in labels.
+	sample := func(name string, labels map[string]string) *metricSample {
+		t.Helper()
+		family, ok := snapshot[name]
+		if !ok {
+			t.Fatalf("snapshot has no %s", name)
+		}
+	samples:
+		for i, s := range family.Samples {
+			for k, v := range labels {
+				if s.Labels[k] != v {
+					continue samples
+				}
+			}
+			return &family.Samples[i]
+		}
+		t.Fatalf("%s has no series %v", name, labels)
+		return nil
+	}
+
+	tests := []struct {
+		name   string
+		labels map[string]string
+		want   float64
+	}{
+		{"http_requests_total", map[string]string{"route": "/articles/{id}", "method": "GET", "code": "200"}, 2},
+		{"http_requests_total", map[string]string{"route": "/articles/{id}", "method": "GET", "code": "404"}, 1},
+	}
+	for _, tt := range tests {
+		s := sample(tt.name, tt.labels)
+		if s.Value == nil {
+			t.Errorf("%s%v has no value", tt.name, tt.labels)
+		} else if *s.Value != tt.want {
+			t.Errorf("%s%v = %v, want %v", tt.name, tt.labels, *s.Value, tt.want)
+		}
+	}
+
+	// Adding a1 looked for it first, so the repo saw more than the requests.
+	if s := sample("repo_operations_total", map[string]string{"operation": "by_id", "outcome": "not_found"}); s.Value == nil || *s.Value < 1 {
+		t.Errorf("repo_operations_total has no missing lookup: %+v", s)
+	}
+	if got := snapshot["http_request_duration_seconds"].Type; got != "histogram" {
+		t.Errorf("http_request_duration_seconds type = %q, want histogram", got)
+	}
+	latency := sample("repo_operation_duration_seconds", map[string]string{"outcome": "ok"})
+	if latency.Count == nil || *latency.Count == 0 || len(latency.Buckets) == 0 {
+		t.Errorf("repo latency sample = %+v, want a count and buckets", latency)
+	}
+}
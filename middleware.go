@@ -0,0 +1,132 @@
+package main
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync/atomic"
+
+	"github.com/gorilla/mux"
+)
+
+// isHTTPS reports whether the request reached us over TLS, either directly or
+// through a TLS-terminating proxy that sets X-Forwarded-Proto.
+func isHTTPS(r *http.Request) bool {
+	if r.TLS != nil {
+		return true
+	}
+	return strings.EqualFold(r.Header.Get("X-Forwarded-Proto"), "https")
+}
+
+// requireHTTPSMiddleware redirects plaintext GET and HEAD requests to their
+// HTTPS equivalent and rejects any other plaintext request with 403, since
+// replaying a request body over a redirect is not safe.
+func requireHTTPSMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if isHTTPS(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.WriteHeader(http.StatusForbidden)
+			io.WriteString(w, "https required")
+			return
+		}
+
+		target := "https://" + r.Host + r.URL.RequestURI()
+		http.Redirect(w, r, target, http.StatusPermanentRedirect)
+	})
+}
+
+// batchBodyPaths are the routes that take many articles in one body and get
+// the batch body limit instead of the single-write one.
+var batchBodyPaths = map[string]bool{
+	"/articles/batch":  true,
+	"/articles/import": true,
+}
+
+// maxBodyMiddleware caps request bodies at limit bytes, or at batchLimit
+// bytes on batchBodyPaths. A limit of zero or less leaves those bodies
+// unbounded. Reads beyond the cap fail with *http.MaxBytesError, which
+// handlers report as 413.
+func maxBodyMiddleware(limit, batchLimit int64, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		max := limit
+		if batchBodyPaths[r.URL.Path] {
+			max = batchLimit
+		}
+		if max > 0 {
+			r.Body = http.MaxBytesReader(w, r.Body, max)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// inFlightMiddleware counts the requests currently being served in
+// inFlight. Long-lived streams such as /articles/events count until they end.
+func inFlightMiddleware(inFlight *atomic.Int64, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		inFlight.Add(1)
+		defer inFlight.Add(-1)
+		slog.Debug("request started", "method", r.Method, "uri", r.URL.RequestURI(), "inFlight", inFlight.Load())
+		next.ServeHTTP(w, r)
+	})
+}
+
+// unlimitedPaths bypass the concurrency limit: health and metrics must
+// answer while the server is saturated, and the event streams would hold a
+// slot for as long as a client stays connected.
+var unlimitedPaths = map[string]bool{
+	"/healthz":            true,
+	"/metrics":            true,
+	"/admin/metrics.json": true,
+	"/articles/events":    true,
+	"/articles/ws":        true,
+}
+
+// overloadRetryAfter is the Retry-After value, in seconds, sent with
+// requests turned away by concurrencyLimitMiddleware.
+const overloadRetryAfter = "1"
+
+// concurrencyLimitMiddleware serves at most limit requests at a time, outside
+// unlimitedPaths. Requests beyond that are answered with 503 and Retry-After
+// straight away rather than queued, so a slow backend sheds load instead of
+// piling it up. Rejections are logged at debug level with the client address,
+// to find who saturates the server without flooding the log while it is.
+func concurrencyLimitMiddleware(limit int, next http.Handler) http.Handler {
+	slots := make(chan struct{}, limit)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if unlimitedPaths[r.URL.Path] {
+			next.ServeHTTP(w, r)
+			return
+		}
+		select {
+		case slots <- struct{}{}:
+		default:
+			slog.Debug("too many concurrent requests", "method", r.Method, "path", r.URL.Path, "client", clientIP(r))
+			w.Header().Set("Retry-After", overloadRetryAfter)
+			w.WriteHeader(http.StatusServiceUnavailable)
+			io.WriteString(w, "too many concurrent requests, try again later")
+			return
+		}
+		defer func() { <-slots }()
+		next.ServeHTTP(w, r)
+	})
+}
+
+// matchedRouteMiddleware sets X-Matched-Route to the path template of the
+// route that handles the request, such as /articles/{id}, so clients can
+// group requests the way the server does. It must be installed with
+// Router.Use so the route is known; unmatched requests get no header.
+func matchedRouteMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if route := mux.CurrentRoute(r); route != nil {
+			if tpl, err := route.GetPathTemplate(); err == nil {
+				w.Header().Set("X-Matched-Route", tpl)
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"crypto/tls"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+// okHandler answers every request with 200 and "ok".
+var okHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	w.Write([]byte("ok"))
+})
+
+func TestRequireHTTPSMiddleware(t *testing.T) {
+	tests := []struct {
+		name     string
+		method   string
+		tls      bool
+		proto    string
+		status   int
+		location string
+	}{
+		{name: "plaintext GET redirects", method: "GET", status: http.StatusPermanentRedirect, location: "https://example.com/articles?limit=2"},
+		{name: "plaintext HEAD redirects", method: "HEAD", status: http.StatusPermanentRedirect, location: "https://example.com/articles?limit=2"},
+		{name: "plaintext PUT is rejected", method: "PUT", status: http.StatusForbidden},
+		{name: "plaintext DELETE is rejected", method: "DELETE", status: http.StatusForbidden},
+		{name: "TLS passes", method: "PUT", tls: true, status: http.StatusOK},
+		{name: "TLS-terminating proxy passes", method: "PUT", proto: "https", status: http.StatusOK},
+		{name: "forwarded plaintext redirects", method: "GET", proto: "http", status: http.StatusPermanentRedirect, location: "https://example.com/articles?limit=2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "http://example.com/articles?limit=2", nil)
+			if tt.tls {
+				req.TLS = &tls.ConnectionState{}
+			}
+			if tt.proto != "" {
+				req.Header.Set("X-Forwarded-Proto", tt.proto)
+			}
+			rec := httptest.NewRecorder()
+			requireHTTPSMiddleware(okHandler).ServeHTTP(rec, req)
+
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.status)
+			}
+			if got := rec.Header().Get("Location"); got != tt.location {
+				t.Errorf("Location = %q, want %q", got, tt.location)
+			}
+		})
+	}
+}
+
+func TestConcurrencyLimitMiddleware(t *testing.T) {
+	logLevel := new(slog.LevelVar)
+	logLevel.Set(slog.LevelDebug)
+	logs := captureSlog(t, logLevel)
+
+	proxies, err := parseTrustedProxies([]string{"10.0.0.0/8"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	release := make(chan struct{})
+	entered := make(chan struct{})
+	h := clientIPMiddleware(proxies, concurrencyLimitMiddleware(1, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		entered <- struct{}{}
+		<-release
+	})))
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/articles", nil))
+	}()
+	<-entered
+
+	req := httptest.NewRequest("GET", "/articles", nil)
+	req.RemoteAddr = "203.0.113.7:1234"
+	req.Header.Set("X-Forwarded-For", "198.51.100.1")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusServiceUnavailable || rec.Header().Get("Retry-After") != overloadRetryAfter {
+		t.Errorf("over the limit: got %d Retry-After %q, want 503 with Retry-After", rec.Code, rec.Header().Get("Retry-After"))
+	}
+	if !strings.Contains(logs.String(), "client=203.0.113.7") {
+		t.Errorf("rejection log %q does not name the direct peer", logs)
+	}
+
+	close(release)
+	<-done
+	go func() { <-entered }()
+	if rec := doRequest(h, "GET", "/articles", ""); rec.Code != http.StatusOK {
+		t.Errorf("after the slot freed: status = %d, want 200", rec.Code)
+	}
+}
+
+func TestMatchedRouteMiddleware(t *testing.T) {
+	root := mux.NewRouter()
+	root.Use(matchedRouteMiddleware)
+	transport := newArticlesHttpTransport(newTestSvc())
+	transport.setupRoutes(root.PathPrefix("/articles").Subrouter())
+	transport.setupTagRoutes(root.PathPrefix("/tags").Subrouter())
+	root.Handle("/", okHandler)
+
+	tests := []struct {
+		method, target, want string
+	}{
+		{"GET", "/articles", "/articles"},
+		{"HEAD", "/articles", "/articles"},
+		{"GET", "/articles/a1", "/articles/{id}"},
+		{"DELETE", "/articles/a1", "/articles/{id}"},
+		{"GET", "/articles/a1/siblings", "/articles/{id}/siblings"},
+		// Literal routes win over {id}.
+		{"GET", "/articles/schema", "/articles/schema"},
+		{"GET", "/tags/suggest", "/tags/suggest"},
+		{"GET", "/", "/"},
+		{"GET", "/nowhere", ""},
+	}
+	for _, tt := range tests {
+		rec := doRequest(root, tt.method, tt.target, "")
+		if got := rec.Header().Get("X-Matched-Route"); got != tt.want {
+			t.Errorf("%s %s: X-Matched-Route = %q, want %q", tt.method, tt.target, got, tt.want)
+		}
+	}
+}
+
+func TestConcurrencyLimitExemptPaths(t *testing.T) {
+	const limit = 2
+	release := make(chan struct{})
+	entered := make(chan struct{}, limit)
+	h := concurrencyLimitMiddleware(limit, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			entered <- struct{}{}
+			<-release
+		}
+		w.Write([]byte("ok"))
+	}))
+
+	var done sync.WaitGroup
+	for i := 0; i < limit; i++ {
+		done.Add(1)
+		go func() {
+			defer done.Done()
+			doRequest(h, "GET", "/slow", "")
+		}()
+	}
+	for i := 0; i < limit; i++ {
+		<-entered
+	}
+
+	tests := []struct {
+		path string
+		want int
+	}{
+		{"/articles", http.StatusServiceUnavailable},
+		{"/articles/a1", http.StatusServiceUnavailable},
+		{"/healthz", http.StatusOK},
+		{"/metrics", http.StatusOK},
+		{"/admin/metrics.json", http.StatusOK},
+		{"/articles/events", http.StatusOK},
+		{"/articles/ws", http.StatusOK},
+	}
+	for _, tt := range tests {
+		if rec := doRequest(h, "GET", tt.path, ""); rec.Code != tt.want {
+			t.Errorf("saturated, %s: status = %d, want %d", tt.path, rec.Code, tt.want)
+		}
+	}
+
+	close(release)
+	done.Wait()
+	if rec := doRequest(h, "GET", "/articles", ""); rec.Code != http.StatusOK {
+		t.Errorf("after the slots freed: status = %d, want 200", rec.Code)
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+// cursorVersion is bumped whenever the cursor payload changes shape, which
+// invalidates every cursor handed out before.
+const cursorVersion = 1
+
+var errInvalidCursor = errors.New("invalid cursor")
+
+// errCursorsDisabled answers ?cursor= on a transport without a cursor
+// signer.
+var errCursorsDisabled = errors.New("cursor pagination is not enabled; page with offset instead")
+
+// cursorPayload identifies the last article of a page by its sort key.
+type cursorPayload struct {
+	Version   int       `json:"v"`
+	Pinned    bool      `json:"p,omitempty"`
+	PublishAt time.Time `json:"t"`
+	ID        string    `json:"id"`
+}
+
+// cursorSigner encodes listing cursors as "<payload>.<hmac>" so clients can
+// pass them back verbatim but cannot forge or alter them.
+type cursorSigner struct {
+	secret []byte
+}
+
+// newCursorSigner signs with secret, or with a random per-process secret when
+// secret is empty; cursors then stop working across restarts.
+func newCursorSigner(secret string) (*cursorSigner, error) {
+	if secret != "" {
+		return &cursorSigner{secret: []byte(secret)}, nil
+	}
+
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	return &cursorSigner{secret: key}, nil
+}
+
+func (s *cursorSigner) mac(payload string) []byte {
+	m := hmac.New(sha256.New, s.secret)
+	m.Write([]byte(payload))
+	return m.Sum(nil)
+}
+
+// encode returns the cursor pointing just past article.
+func (s *cursorSigner) encode(article Article) (string, error) {
+	data, err := json.Marshal(cursorPayload{
+		Version:   cursorVersion,
+		Pinned:    article.Pinned,
+		PublishAt: article.PublishAt,
+		ID:        article.ID,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	payload := base64.RawURLEncoding.EncodeToString(data)
+	return payload + "." + base64.RawURLEncoding.EncodeToString(s.mac(payload)), nil
+}
+
+// decode verifies cursor and returns its payload. Tampered, malformed and
+// outdated cursors all yield errInvalidCursor.
+func (s *cursorSigner) decode(cursor string) (cursorPayload, error) {
+	payload, sig, ok := strings.Cut(cursor, ".")
+	if !ok {
+		return cursorPayload{}, errInvalidCursor
+	}
+
+	mac, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil || !hmac.Equal(mac, s.mac(payload)) {
+		return cursorPayload{}, errInvalidCursor
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return cursorPayload{}, errInvalidCursor
+	}
+
+	var p cursorPayload
+	if err := json.Unmarshal(data, &p); err != nil || p.Version != cursorVersion {
+		return cursorPayload{}, errInvalidCursor
+	}
+	return p, nil
+}
+
+// afterCursor drops the leading articles of a sorted listing up to and
+// including the position recorded in cursor.
+func afterCursor(articles []Article, cursor cursorPayload) []Article {
+	key := Article{Pinned: cursor.Pinned, PublishAt: cursor.PublishAt, ID: cursor.ID}
+	for i, article := range articles {
+		if articleLess(key, article) {
+			return articles[i:]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCursorPagination(t *testing.T) {
+	signer, err := newCursorSigner("test-secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	svc := newTestSvc()
+	for i, id := range []string{"a", "b", "c", "d", "e"} {
+		mustAdd(t, svc, Article{ID: id, Title: "T", PublishAt: time.Date(2024, 3, 10-i, 0, 0, 0, 0, time.UTC)})
+	}
+	h := newTestHandler(svc, withCursorSigner(signer))
+
+	var pages [][]string
+	target := "/articles?limit=2"
+	for target != "" {
+		rec := doRequest(h, "GET", target, "")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("GET %s: status = %d: %s", target, rec.Code, rec.Body)
+		}
+		var articles []Article
+		decodeJSON(t, rec, &articles)
+		var ids []string
+		for _, article := range articles {
+			ids = append(ids, article.ID)
+		}
+		pages = append(pages, ids)
+
+		target = ""
+		if next := rec.Header().Get("X-Next-Cursor"); next != "" {
+			target = "/articles?limit=2&cursor=" + next
+		}
+		if len(pages) > 5 {
+			t.Fatal("pagination does not end")
+		}
+	}
+
+	want := [][]string{{"a", "b"}, {"c", "d"}, {"e"}}
+	if !slices.EqualFunc(pages, want, slices.Equal[[]string]) {
+		t.Errorf("pages = %v, want %v", pages, want)
+	}
+}
+
+func TestPaginationWithoutCursorSigner(t *testing.T) {
+	svc := newTestSvc()
+	mustAdd(t, svc, Article{ID: "a", Title: "T"}, Article{ID: "b", Title: "T"}, Article{ID: "c", Title: "T"})
+	h := newTestHandler(svc)
+
+	tests := []struct {
+		target   string
+		wantCode int
+		wantLen  int
+	}{
+		{"/articles?limit=2", http.StatusOK, 2},
+		{"/articles?limit=2&offset=2", http.StatusOK, 1},
+		{"/articles?limit=2&cursor=abc.def", http.StatusBadRequest, 0},
+	}
+	for _, tt := range tests {
+		rec := doRequest(h, "GET", tt.target, "")
+		if rec.Code != tt.wantCode {
+			t.Fatalf("%s: status = %d, want %d: %s", tt.target, rec.Code, tt.wantCode, rec.Body)
+		}
+		if rec.Code != http.StatusOK {
+			continue
+		}
+		var articles []Article
+		decodeJSON(t, rec, &articles)
+		if len(articles) != tt.wantLen || rec.Header().Get("X-Next-Cursor") != "" {
+			t.Errorf("%s: %d articles, X-Next-Cursor %q; want %d and none", tt.target, len(articles), rec.Header().Get("X-Next-Cursor"), tt.wantLen)
+		}
+	}
+}
+
+func TestCursorRejected(t *testing.T) {
+	signer, err := newCursorSigner("test-secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := newCursorSigner("other-secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	article := Article{ID: "b", PublishAt: time.Date(2024, 3, 9, 0, 0, 0, 0, time.UTC)}
+	valid, err := signer.encode(article)
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload, sig, _ := strings.Cut(valid, ".")
+
+	// signed re-signs a payload with the server's secret, as a cursor from
+	// an older release would have been.
+	signed := func(p any) string {
+		data, err := json.Marshal(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		payload := base64.RawURLEncoding.EncodeToString(data)
+		return payload + "." + base64.RawURLEncoding.EncodeToString(signer.mac(payload))
+	}
+	tampered := func() string {
+		data, _ := base64.RawURLEncoding.DecodeString(payload)
+		data = []byte(strings.Replace(string(data), `"id":"b"`, `"id":"z"`, 1))
+		return base64.RawURLEncoding.EncodeToString(data) + "." + sig
+	}
+
+	tests := []struct {
+		name   string
+		cursor string
+	}{
+		{"tampered payload", tampered()},
+		{"tampered signature", payload + "." + base64.RawURLEncoding.EncodeToString([]byte("forged"))},
+		{"signed with another secret", func() string { c, _ := other.encode(article); return c }()},
+		{"missing signature", payload},
+		{"not base64", "!!!." + sig},
+		{"expired schema", signed(cursorPayload{Version: cursorVersion - 1, PublishAt: article.PublishAt, ID: "b"})},
+		{"future schema", signed(cursorPayload{Version: cursorVersion + 1, PublishAt: article.PublishAt, ID: "b"})},
+		{"signed garbage", signed("not a payload")},
+	}
+
+	svc := newTestSvc()
+	mustAdd(t, svc, Article{ID: "a", Title: "T"})
+	h := newTestHandler(svc, withCursorSigner(signer))
+	if rec := doRequest(h, "GET", "/articles?limit=1&cursor="+valid, ""); rec.Code != http.StatusOK {
+		t.Fatalf("valid cursor: status = %d: %s", rec.Code, rec.Body)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := signer.decode(tt.cursor); err != errInvalidCursor {
+				t.Errorf("decode err = %v, want errInvalidCursor", err)
+			}
+			rec := doRequest(h, "GET", "/articles?limit=1&cursor="+tt.cursor, "")
+			if rec.Code != http.StatusBadRequest || rec.Body.String() != "invalid cursor" {
+				t.Errorf("got %d %q, want 400 \"invalid cursor\"", rec.Code, rec.Body)
+			}
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+var ErrTooManyPinned = errors.New("too many pinned articles")
+
+// errPinUnchanged ends a SetPinned write that would not change the article,
+// so it doesn't use up a sequence number.
+var errPinUnchanged = errors.New("pin unchanged")
+
+// withMaxPinned caps how many articles may be pinned at once. Zero or less
+// means unlimited.
+func withMaxPinned(max int) svcOption {
+	return func(svc *articleSvc) {
+		svc.maxPinned = max
+	}
+}
+
+// checkPinLimit returns ErrTooManyPinned if pinning one more article would
+// exceed the configured maximum. It must run inside svc.changes.write, so the
+// count can't change before the pin is stored.
+func (svc *articleSvc) checkPinLimit(ctx context.Context) error {
+	if svc.maxPinned <= 0 {
+		return nil
+	}
+
+	articles, err := svc.repo.AllArticles(ctx)
+	if err != nil {
+		return err
+	}
+
+	pinned := 0
+	for _, article := range articles {
+		if article.Pinned {
+			pinned++
+		}
+	}
+	if pinned >= svc.maxPinned {
+		return fmt.Errorf("%w: at most %d articles can be pinned", ErrTooManyPinned, svc.maxPinned)
+	}
+	return nil
+}
+
+// SetPinned pins or unpins an article and returns it as stored. Repeating
+// the current state is a no-op. The article is read and written back under
+// the change log lock, so a concurrent edit can't be overwritten with the
+// state from before it.
+func (svc *articleSvc) SetPinned(ctx context.Context, id string, pinned bool) (*Article, error) {
+	var article *Article
+	err := svc.changes.write(id, false, func(seq int64) error {
+		var err error
+		article, err = svc.repo.ArticleByID(ctx, id)
+		if err != nil {
+			return err
+		}
+		if article.Pinned == pinned {
+			return errPinUnchanged
+		}
+		if article.Locked {
+			return ErrArticleLocked
+		}
+		if pinned {
+			if err := svc.checkPinLimit(ctx); err != nil {
+				return err
+			}
+		}
+		article.Pinned = pinned
+		article.Seq = seq
+		article.ModifiedAt = svc.clock.Now().UTC()
+		article.ETag = article.contentETag()
+		return svc.repo.UpdateArticle(ctx, *article)
+	})
+	switch {
+	case errors.Is(err, errPinUnchanged):
+	case err != nil:
+		return nil, err
+	default:
+		svc.publish(ctx, eventArticleUpdated, *article)
+	}
+	stored := svc.withScheduled(*article)
+	return &stored, nil
+}
+
+func (t *articlesHttpTransport) pinArticle(w http.ResponseWriter, r *http.Request) {
+	t.setPinned(w, r, true)
+}
+
+func (t *articlesHttpTransport) unpinArticle(w http.ResponseWriter, r *http.Request) {
+	t.setPinned(w, r, false)
+}
+
+func (t *articlesHttpTransport) setPinned(w http.ResponseWriter, r *http.Request, pinned bool) {
+	articleID := mux.Vars(r)["id"]
+	current, err := t.requestedArticle(r, articleID)
+	if err == nil && t.hidesDraft(r, *current) {
+		writeArticleNotFound(w, articleID)
+		return
+	}
+	if err == nil && restricted(*current, t.deniedTags(r)) {
+		t.writeRestricted(w, articleID)
+		return
+	}
+
+	article, err := t.svc.SetPinned(r.Context(), articleID, pinned)
+	if err != nil {
+		log.Println(err)
+		switch {
+		case errors.Is(err, ErrArticleNotFound):
+			w.WriteHeader(http.StatusNotFound)
+		case errors.Is(err, ErrTooManyPinned):
+			w.WriteHeader(http.StatusConflict)
+		case errors.Is(err, ErrArticleLocked):
+			w.WriteHeader(http.StatusLocked)
+		default:
+			w.WriteHeader(serverErrorStatus(err))
+		}
+		io.WriteString(w, errorBody(err))
+		return
+	}
+
+	if article.ETag != "" {
+		w.Header().Set("ETag", article.ETag)
+	}
+	t.writeJSON(w, r, http.StatusOK, article)
+}
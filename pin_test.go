@@ -0,0 +1,246 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestListingPinnedFirst(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2024, 3, d, 0, 0, 0, 0, time.UTC) }
+	svc := newTestSvc(withMaxPinned(0))
+	mustAdd(t, svc,
+		Article{ID: "new", Title: "T", PublishAt: day(9)},
+		Article{ID: "old-pinned", Title: "T", PublishAt: day(1), Pinned: true},
+		Article{ID: "mid", Title: "T", PublishAt: day(5)},
+		Article{ID: "new-pinned", Title: "T", PublishAt: day(7), Pinned: true},
+	)
+	h := newTestHandler(svc)
+
+	tests := []struct {
+		target string
+		want   []string
+	}{
+		{"/articles", []string{"new-pinned", "old-pinned", "new", "mid"}},
+		{"/articles?pinned=true", []string{"new-pinned", "old-pinned"}},
+		{"/articles?pinned=false", []string{"new-pinned", "old-pinned", "new", "mid"}},
+		{"/articles?from=2024-03-04T00:00:00Z", []string{"new-pinned", "new", "mid"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			rec := doRequest(h, "GET", tt.target, "")
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+			}
+			var articles []Article
+			decodeJSON(t, rec, &articles)
+			var ids []string
+			for _, article := range articles {
+				ids = append(ids, article.ID)
+			}
+			if !slices.Equal(ids, tt.want) {
+				t.Errorf("order = %v, want %v", ids, tt.want)
+			}
+		})
+	}
+}
+
+func TestPinTransitions(t *testing.T) {
+	svc := newTestSvc(withMaxPinned(2))
+	mustAdd(t, svc,
+		Article{ID: "a", Title: "T"},
+		Article{ID: "b", Title: "T"},
+		Article{ID: "c", Title: "T"},
+	)
+	h := newTestHandler(svc)
+
+	steps := []struct {
+		name       string
+		target     string
+		wantCode   int
+		wantPinned []string
+	}{
+		{"pin a", "/articles/a/pin", http.StatusOK, []string{"a"}},
+		{"pin a again is a no-op", "/articles/a/pin", http.StatusOK, []string{"a"}},
+		{"pin b", "/articles/b/pin", http.StatusOK, []string{"a", "b"}},
+		{"pin c over the limit", "/articles/c/pin", http.StatusConflict, []string{"a", "b"}},
+		{"repin at the limit", "/articles/b/pin", http.StatusOK, []string{"a", "b"}},
+		{"unpin a", "/articles/a/unpin", http.StatusOK, []string{"b"}},
+		{"unpin a again is a no-op", "/articles/a/unpin", http.StatusOK, []string{"b"}},
+		{"pin c with room", "/articles/c/pin", http.StatusOK, []string{"b", "c"}},
+		{"pin unknown", "/articles/missing/pin", http.StatusNotFound, []string{"b", "c"}},
+		{"unpin unknown", "/articles/missing/unpin", http.StatusNotFound, []string{"b", "c"}},
+	}
+	for _, step := range steps {
+		rec := doRequest(h, "POST", step.target, "")
+		if rec.Code != step.wantCode {
+			t.Fatalf("%s: status = %d, want %d: %s", step.name, rec.Code, step.wantCode, rec.Body)
+		}
+		if rec.Code == http.StatusOK {
+			var article Article
+			decodeJSON(t, rec, &article)
+			if want := slices.Contains(step.wantPinned, article.ID); article.Pinned != want {
+				t.Errorf("%s: response pinned = %v, want %v", step.name, article.Pinned, want)
+			}
+		}
+		if got := pinnedIDs(t, svc); !slices.Equal(got, step.wantPinned) {
+			t.Errorf("%s: pinned = %v, want %v", step.name, got, step.wantPinned)
+		}
+	}
+}
+
+func TestCreatePinnedOverLimit(t *testing.T) {
+	svc := newTestSvc(withMaxPinned(1))
+	h := newTestHandler(svc)
+
+	if rec := doRequest(h, "PUT", "/articles", `{"id":"a","title":"T","pinned":true}`); rec.Code != http.StatusCreated {
+		t.Fatalf("first pinned create: status = %d: %s", rec.Code, rec.Body)
+	}
+	if rec := doRequest(h, "PUT", "/articles", `{"id":"b","title":"T","pinned":true}`); rec.Code != http.StatusConflict {
+		t.Fatalf("second pinned create: status = %d, want 409: %s", rec.Code, rec.Body)
+	}
+	if rec := doRequest(h, "PUT", "/articles/a", `{"title":"Edited","pinned":true}`); rec.Code != http.StatusOK {
+		t.Errorf("updating the pinned article: status = %d: %s", rec.Code, rec.Body)
+	}
+	if ids := storedIDs(t, svc.repo); !slices.Equal(ids, []string{"a"}) {
+		t.Errorf("stored %v, want [a]", ids)
+	}
+}
+
+// slowListRepo delays AllArticles results, the read checkPinLimit counts
+// from, so concurrent pins act on stale counts unless something serializes
+// them.
+type slowListRepo struct {
+	*inMemoryRepo
+}
+
+func (repo slowListRepo) AllArticles(ctx context.Context) ([]Article, error) {
+	articles, err := repo.inMemoryRepo.AllArticles(ctx)
+	time.Sleep(time.Millisecond)
+	return articles, err
+}
+
+// TestConcurrentPinsRespectLimit pins many articles at once. The limit is
+// checked under the same lock as the write, so exactly maxPinned succeed.
+func TestConcurrentPinsRespectLimit(t *testing.T) {
+	const maxPinned, n = 2, 20
+	svc := newArticleSvc(slowListRepo{newInMemoryRepo()}, withMaxPinned(maxPinned))
+	ids := make([]string, n)
+	for i := range ids {
+		ids[i] = "a" + string(rune('a'+i))
+		mustAdd(t, svc, Article{ID: ids[i], Title: "T"})
+	}
+
+	var wg sync.WaitGroup
+	errs := make([]error, n)
+	for i, id := range ids {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, errs[i] = svc.SetPinned(context.Background(), id, true)
+		}()
+	}
+	wg.Wait()
+
+	succeeded := 0
+	for _, err := range errs {
+		switch {
+		case err == nil:
+			succeeded++
+		case !errors.Is(err, ErrTooManyPinned):
+			t.Errorf("unexpected error: %v", err)
+		}
+	}
+	if succeeded != maxPinned {
+		t.Errorf("%d pins succeeded, want %d", succeeded, maxPinned)
+	}
+	if got := pinnedIDs(t, svc); len(got) != maxPinned {
+		t.Errorf("pinned = %v, want %d articles", got, maxPinned)
+	}
+}
+
+// slowByIDRepo delays ArticleByID results, so a read-modify-write that
+// reads outside the change log lock overwrites writes landing meanwhile.
+type slowByIDRepo struct {
+	*inMemoryRepo
+}
+
+func (repo slowByIDRepo) ArticleByID(ctx context.Context, id string) (*Article, error) {
+	article, err := repo.inMemoryRepo.ArticleByID(ctx, id)
+	time.Sleep(time.Millisecond)
+	return article, err
+}
+
+func TestPinKeepsConcurrentEdits(t *testing.T) {
+	svc := newArticleSvc(slowByIDRepo{newInMemoryRepo()})
+	ctx := context.Background()
+
+	for i := 0; i < 20; i++ {
+		id := "a" + strconv.Itoa(i)
+		mustAdd(t, svc, Article{ID: id, Title: "Before"})
+
+		var wg sync.WaitGroup
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			if _, err := svc.SetPinned(ctx, id, true); err != nil {
+				t.Error(err)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			if err := svc.UpdateArticle(ctx, Article{ID: id, Title: "After", Pinned: true}); err != nil {
+				t.Error(err)
+			}
+		}()
+		wg.Wait()
+
+		stored, err := svc.repo.ArticleByID(ctx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stored.Title != "After" || !stored.Pinned {
+			t.Errorf("%s: stored title %q pinned %v, want the edit and the pin", id, stored.Title, stored.Pinned)
+		}
+	}
+}
+
+func TestPinReturnsStoredArticle(t *testing.T) {
+	svc := newTestSvc()
+	mustAdd(t, svc, Article{ID: "a", Title: "T"})
+	h := newTestHandler(svc)
+
+	for _, target := range []string{"/articles/a/pin", "/articles/a/pin", "/articles/a/unpin"} {
+		rec := doRequest(h, "POST", target, "")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: status = %d: %s", target, rec.Code, rec.Body)
+		}
+		got := decodeArticle(t, rec)
+		stored := mustGet(t, svc, "a")
+		if etag := rec.Header().Get("ETag"); got.Seq != stored.Seq || etag != stored.ETag || got.Pinned != stored.Pinned {
+			t.Errorf("%s: returned seq %d etag %q pinned %v, stored seq %d etag %q pinned %v",
+				target, got.Seq, etag, got.Pinned, stored.Seq, stored.ETag, stored.Pinned)
+		}
+	}
+}
+
+func pinnedIDs(t *testing.T, svc *articleSvc) []string {
+	t.Helper()
+	articles, err := svc.repo.AllArticles(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, article := range articles {
+		if article.Pinned {
+			ids = append(ids, article.ID)
+		}
+	}
+	slices.Sort(ids)
+	return ids
+}
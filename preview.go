@@ -0,0 +1,145 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+var errInvalidPreviewToken = errors.New("invalid or expired preview token")
+
+// previewPayload grants read access to one article until Expires.
+type previewPayload struct {
+	ID      string `json:"id"`
+	Expires int64  `json:"exp"`
+}
+
+// previewSigner mints and checks preview tokens, "<payload>.<hmac>" like
+// pagination cursors, which let anyone holding them read one draft for a
+// limited time.
+type previewSigner struct {
+	secret []byte
+	ttl    time.Duration
+	clock  Clock
+}
+
+// newPreviewSigner signs tokens valid for ttl with secret, or with a random
+// per-process secret when secret is empty; tokens then stop working across
+// restarts.
+func newPreviewSigner(secret string, ttl time.Duration, clock Clock) (*previewSigner, error) {
+	s := &previewSigner{secret: []byte(secret), ttl: ttl, clock: clock}
+	if secret == "" {
+		s.secret = make([]byte, 32)
+		if _, err := rand.Read(s.secret); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+func (s *previewSigner) mac(payload string) []byte {
+	m := hmac.New(sha256.New, s.secret)
+	// Keeps a token signed for another purpose with the same secret from
+	// passing as a preview token.
+	m.Write([]byte("preview:"))
+	m.Write([]byte(payload))
+	return m.Sum(nil)
+}
+
+// mint returns a token for the article id and when it expires.
+func (s *previewSigner) mint(id string) (string, time.Time, error) {
+	expires := s.clock.Now().Add(s.ttl).Truncate(time.Second)
+	data, err := json.Marshal(previewPayload{ID: id, Expires: expires.Unix()})
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	payload := base64.RawURLEncoding.EncodeToString(data)
+	return payload + "." + base64.RawURLEncoding.EncodeToString(s.mac(payload)), expires, nil
+}
+
+// verify reports whether token is an unexpired token for the article id.
+func (s *previewSigner) verify(token, id string) bool {
+	payload, sig, ok := strings.Cut(token, ".")
+	if !ok {
+		return false
+	}
+
+	mac, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil || !hmac.Equal(mac, s.mac(payload)) {
+		return false
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return false
+	}
+
+	var p previewPayload
+	if err := json.Unmarshal(data, &p); err != nil {
+		return false
+	}
+	return p.ID == id && s.clock.Now().Before(time.Unix(p.Expires, 0))
+}
+
+// withPreviewTokens lets writers mint preview tokens with signer, and lets
+// GET /articles/{id}?preview=<token> show a draft to anyone holding one.
+func withPreviewTokens(signer *previewSigner) transportOption {
+	return func(t *articlesHttpTransport) {
+		t.previews = signer
+	}
+}
+
+type previewTokenResponse struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expiresAt"`
+	// URL is the path reviewers open to read the article.
+	URL string `json:"url"`
+}
+
+// previewToken mints a token for sharing an article, typically a draft,
+// with reviewers who couldn't read it otherwise.
+func (t *articlesHttpTransport) previewToken(w http.ResponseWriter, r *http.Request) {
+	if t.previews == nil {
+		w.WriteHeader(http.StatusNotFound)
+		io.WriteString(w, "preview tokens are disabled")
+		return
+	}
+
+	articleID := mux.Vars(r)["id"]
+	if _, err := t.requestedArticle(r, articleID); err != nil {
+		log.Println(err)
+		if errors.Is(err, ErrArticleNotFound) {
+			writeArticleNotFound(w, articleID)
+			return
+		}
+		w.WriteHeader(serverErrorStatus(err))
+		io.WriteString(w, errorBody(err))
+		return
+	}
+
+	token, expires, err := t.previews.mint(articleID)
+	if err != nil {
+		log.Println(err)
+		w.WriteHeader(serverErrorStatus(err))
+		io.WriteString(w, errorBody(err))
+		return
+	}
+
+	t.writeJSON(w, r, http.StatusCreated, previewTokenResponse{
+		Token:     token,
+		ExpiresAt: expires.UTC(),
+		URL:       "/articles/" + url.PathEscape(articleID) + "?preview=" + url.QueryEscape(token),
+	})
+}
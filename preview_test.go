@@ -0,0 +1,110 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPreviewTokens(t *testing.T) {
+	clock := newFakeClock(time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC))
+	signer, err := newPreviewSigner("secret", time.Hour, clock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := newDraftsHandler(t, withPreviewTokens(signer))
+
+	rec := doRequest(h, "POST", "/articles/d1/preview-token", "", "X-API-Key", testWriteKey)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("minting: status = %d", rec.Code)
+	}
+	var minted previewTokenResponse
+	decodeJSON(t, rec, &minted)
+	if !minted.ExpiresAt.Equal(clock.Now().Add(time.Hour)) {
+		t.Errorf("expiresAt = %v, want an hour from now", minted.ExpiresAt)
+	}
+	if minted.URL != "/articles/d1?preview="+url.QueryEscape(minted.Token) {
+		t.Errorf("url = %q", minted.URL)
+	}
+
+	other, err := newPreviewSigner("other secret", time.Hour, clock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	foreign, _, err := other.mint("d1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	forP1, _, err := signer.mint("p1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload, sig, _ := strings.Cut(minted.Token, ".")
+
+	tests := []struct {
+		name, target string
+		want         int
+	}{
+		{"valid token shows the draft", minted.URL, http.StatusOK},
+		{"without a token the draft is hidden", "/articles/d1", http.StatusNotFound},
+		{"token for another article", "/articles/d1?preview=" + url.QueryEscape(forP1), http.StatusForbidden},
+		{"token used on another article", "/articles/p1?preview=" + url.QueryEscape(minted.Token), http.StatusForbidden},
+		{"signed with another secret", "/articles/d1?preview=" + url.QueryEscape(foreign), http.StatusForbidden},
+		{"tampered payload", "/articles/d1?preview=" + url.QueryEscape(payload+"x."+sig), http.StatusForbidden},
+		{"garbage", "/articles/d1?preview=garbage", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		rec := doRequest(h, "GET", tt.target, "")
+		if rec.Code != tt.want {
+			t.Errorf("%s: status = %d, want %d", tt.name, rec.Code, tt.want)
+		}
+		if rec.Code == http.StatusOK && rec.Header().Get("Cache-Control") != "private, no-store" {
+			t.Errorf("%s: Cache-Control = %q, want private, no-store", tt.name, rec.Header().Get("Cache-Control"))
+		}
+	}
+
+	clock.Advance(time.Hour)
+	if rec := doRequest(h, "GET", minted.URL, ""); rec.Code != http.StatusForbidden {
+		t.Errorf("expired token: status = %d, want 403", rec.Code)
+	}
+}
+
+func TestPreviewTokenMinting(t *testing.T) {
+	signer, err := newPreviewSigner("", time.Hour, realClock{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name, target, key string
+		enabled           bool
+		want              int
+	}{
+		{"writer", "/articles/d1/preview-token", testWriteKey, true, http.StatusCreated},
+		{"anonymous", "/articles/d1/preview-token", "", true, http.StatusUnauthorized},
+		{"read scope", "/articles/d1/preview-token", testReadKey, true, http.StatusForbidden},
+		{"missing article", "/articles/missing/preview-token", testWriteKey, true, http.StatusNotFound},
+		{"disabled", "/articles/d1/preview-token", testWriteKey, false, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		var opts []transportOption
+		if tt.enabled {
+			opts = append(opts, withPreviewTokens(signer))
+		}
+		h := newDraftsHandler(t, opts...)
+		var headers []string
+		if tt.key != "" {
+			headers = []string{"X-API-Key", tt.key}
+		}
+		if rec := doRequest(h, "POST", tt.target, "", headers...); rec.Code != tt.want {
+			t.Errorf("%s: status = %d, want %d", tt.name, rec.Code, tt.want)
+		}
+	}
+
+	// Without tokens enabled, none is accepted.
+	if rec := doRequest(newDraftsHandler(t), "GET", "/articles/d1?preview=anything", ""); rec.Code != http.StatusForbidden {
+		t.Errorf("preview while disabled: status = %d, want 403", rec.Code)
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+)
+
+// PublishArticle publishes a draft, stamping PublishAt with the current time
+// when it has none. It reports whether anything changed: publishing an
+// already published article is a no-op.
+func (svc *articleSvc) PublishArticle(ctx context.Context, id string) (*Article, bool, error) {
+	article, err := svc.repo.ArticleByID(ctx, id)
+	if err != nil {
+		return nil, false, err
+	}
+	if article.Status == StatusPublished {
+		return article, false, nil
+	}
+
+	article.Status = StatusPublished
+	if article.PublishAt.IsZero() {
+		article.PublishAt = svc.clock.Now().UTC()
+	}
+	if err := svc.UpdateArticle(ctx, *article); err != nil {
+		return nil, false, err
+	}
+	return article, true, nil
+}
+
+type publishRequest struct {
+	IDs []string `json:"ids"`
+}
+
+// publishResult reports the outcome for one id of a POST /articles/publish
+// request. Changed is false for articles that were already published.
+type publishResult struct {
+	ID      string `json:"id"`
+	OK      bool   `json:"ok"`
+	Status  int    `json:"status"`
+	Changed bool   `json:"changed"`
+	Error   string `json:"error,omitempty"`
+}
+
+// publishArticles publishes each listed draft in turn. Like a batch patch,
+// every id is handled independently and a failure only affects its own
+// result.
+func (t *articlesHttpTransport) publishArticles(w http.ResponseWriter, r *http.Request) {
+	var req publishRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+
+	if len(req.IDs) == 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		io.WriteString(w, "ids is empty")
+		return
+	}
+	if len(req.IDs) > maxBatchSize {
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
+		fmt.Fprintf(w, "ids exceeds %d items", maxBatchSize)
+		return
+	}
+
+	results := make([]publishResult, 0, len(req.IDs))
+	for _, id := range req.IDs {
+		ctx, cancel := context.WithTimeout(r.Context(), batchItemTimeout)
+		_, changed, err := t.svc.PublishArticle(ctx, id)
+		cancel()
+
+		result := publishResult{ID: id, OK: err == nil, Status: http.StatusOK, Changed: changed}
+		if err != nil {
+			log.Println(err)
+			result.Status = publishErrorStatus(err)
+			result.Error = errorBody(err)
+		}
+		results = append(results, result)
+	}
+
+	t.writeJSON(w, r, http.StatusOK, results)
+}
+
+// publishErrorStatus maps a publish error to the status a single update of
+// the article would have answered with.
+func publishErrorStatus(err error) int {
+	var verr *ValidationError
+	switch {
+	case errors.Is(err, ErrArticleNotFound):
+		return http.StatusNotFound
+	case errors.As(err, &verr):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, ErrArticleLocked):
+		return http.StatusLocked
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout
+	default:
+		return serverErrorStatus(err)
+	}
+}
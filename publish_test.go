@@ -0,0 +1,91 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestPublishArticles(t *testing.T) {
+	clock := newFakeClock(time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC))
+	scheduled := clock.Now().Add(24 * time.Hour)
+	earlier := clock.Now().Add(-24 * time.Hour)
+	svc := newTestSvc(withClock(clock))
+	mustAdd(t, svc,
+		Article{ID: "draft", Title: "Draft", Status: StatusDraft},
+		Article{ID: "scheduled", Title: "Scheduled", Status: StatusDraft, PublishAt: scheduled},
+		Article{ID: "live", Title: "Live", Status: StatusPublished, PublishAt: earlier},
+	)
+	s := newAdminTestServer(svc, false, nil)
+	doRequest(s, "POST", "/articles/live/lock", "", "X-API-Key", testAdminKey)
+	mustAdd(t, svc, Article{ID: "held", Title: "Held", Status: StatusDraft})
+	doRequest(s, "POST", "/articles/held/lock", "", "X-API-Key", testAdminKey)
+
+	rec := doRequest(s, "POST", "/articles/publish", `{"ids":["draft","scheduled","live","missing","held"]}`, "X-API-Key", testWriteKey)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+	}
+	var results []publishResult
+	decodeJSON(t, rec, &results)
+
+	tests := []struct {
+		id        string
+		status    int
+		changed   bool
+		publishAt time.Time
+	}{
+		{"draft", http.StatusOK, true, clock.Now()},
+		{"scheduled", http.StatusOK, true, scheduled},
+		// Already published, and so a no-op even though it is locked.
+		{"live", http.StatusOK, false, earlier},
+		{"missing", http.StatusNotFound, false, time.Time{}},
+		{"held", http.StatusLocked, false, time.Time{}},
+	}
+	if len(results) != len(tests) {
+		t.Fatalf("results = %+v, want one per id", results)
+	}
+	for i, tt := range tests {
+		got := results[i]
+		if got.ID != tt.id || got.Status != tt.status || got.OK != (tt.status == http.StatusOK) || got.Changed != tt.changed {
+			t.Errorf("result %d = %+v, want %s with %d, changed %v", i, got, tt.id, tt.status, tt.changed)
+		}
+		if tt.status != http.StatusOK {
+			continue
+		}
+		article := mustGet(t, svc, tt.id)
+		if article.Status != StatusPublished || !article.PublishAt.Equal(tt.publishAt) {
+			t.Errorf("%s: %s at %v, want published at %v", tt.id, article.Status, article.PublishAt, tt.publishAt)
+		}
+	}
+	if got := mustGet(t, svc, "held"); got.Status != StatusDraft {
+		t.Errorf("locked draft was published")
+	}
+}
+
+func TestPublishArticlesRequest(t *testing.T) {
+	svc := newTestSvc()
+	mustAdd(t, svc, Article{ID: "draft", Title: "Draft", Status: StatusDraft})
+	s := newAdminTestServer(svc, false, nil)
+
+	tests := []struct {
+		name, key, body string
+		want            int
+	}{
+		{"anonymous", "", `{"ids":["draft"]}`, http.StatusUnauthorized},
+		{"read scope", testReadKey, `{"ids":["draft"]}`, http.StatusForbidden},
+		{"no ids", testWriteKey, `{"ids":[]}`, http.StatusBadRequest},
+		{"malformed", testWriteKey, `{"ids":`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		var headers []string
+		if tt.key != "" {
+			headers = []string{"X-API-Key", tt.key}
+		}
+		if rec := doRequest(s, "POST", "/articles/publish", tt.body, headers...); rec.Code != tt.want {
+			t.Errorf("%s: status = %d, want %d", tt.name, rec.Code, tt.want)
+		}
+	}
+	if got := mustGet(t, svc, "draft"); got.Status != StatusDraft {
+		t.Error("a rejected request published the draft")
+	}
+}
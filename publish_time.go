@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// PublishAtError reports a publishAt value in none of the accepted formats,
+// or one outside the years 0000 to 9999 that RFC 3339 can encode.
+type PublishAtError struct {
+	Value string
+}
+
+func (e *PublishAtError) Error() string {
+	return fmt.Sprintf("invalid publishAt %s: expected an RFC 3339 timestamp, epoch seconds or YYYY-MM-DD between the years 0000 and 9999", e.Value)
+}
+
+// UnmarshalJSON decodes an article, accepting publishAt as an RFC 3339
+// timestamp, Unix epoch seconds (number or numeric string) or a YYYY-MM-DD
+// date, which is taken as midnight UTC. Articles are always encoded with
+// RFC 3339 timestamps.
+func (a *Article) UnmarshalJSON(data []byte) error {
+	type article Article
+	aux := struct {
+		*article
+		PublishAt json.RawMessage `json:"publishAt"`
+	}{article: (*article)(a)}
+
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	if len(aux.PublishAt) == 0 || bytes.Equal(aux.PublishAt, []byte("null")) {
+		a.PublishAt = time.Time{}
+		return nil
+	}
+
+	publishAt, err := parsePublishAt(aux.PublishAt)
+	if err != nil {
+		return err
+	}
+	a.PublishAt = publishAt
+	return nil
+}
+
+func parsePublishAt(raw json.RawMessage) (time.Time, error) {
+	var value string
+	if err := json.Unmarshal(raw, &value); err != nil {
+		// Not a string, so it has to be a number of epoch seconds.
+		var seconds int64
+		if err := json.Unmarshal(raw, &seconds); err != nil {
+			return time.Time{}, &PublishAtError{Value: string(raw)}
+		}
+		return encodablePublishAt(time.Unix(seconds, 0).UTC(), string(raw))
+	}
+
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		return t, nil
+	}
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return encodablePublishAt(time.Unix(seconds, 0).UTC(), strconv.Quote(value))
+	}
+	return time.Time{}, &PublishAtError{Value: strconv.Quote(value)}
+}
+
+// encodablePublishAt rejects times whose year RFC 3339 can't encode. Such an
+// article could be stored but never listed again.
+func encodablePublishAt(t time.Time, value string) (time.Time, error) {
+	if t.Year() < 0 || t.Year() > 9999 {
+		return time.Time{}, &PublishAtError{Value: value}
+	}
+	return t, nil
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestArticleUnmarshalPublishAt(t *testing.T) {
+	tests := []struct {
+		name      string
+		publishAt string
+		want      time.Time
+	}{
+		{"RFC 3339", `"2024-03-05T10:30:00Z"`, time.Date(2024, 3, 5, 10, 30, 0, 0, time.UTC)},
+		{"RFC 3339 with offset", `"2024-03-05T12:30:00+02:00"`, time.Date(2024, 3, 5, 10, 30, 0, 0, time.UTC)},
+		{"epoch seconds", `1709634600`, time.Date(2024, 3, 5, 10, 30, 0, 0, time.UTC)},
+		{"epoch seconds as a string", `"1709634600"`, time.Date(2024, 3, 5, 10, 30, 0, 0, time.UTC)},
+		{"date only", `"2024-03-05"`, time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC)},
+		{"null", `null`, time.Time{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var article Article
+			data := `{"id":"a1","title":"T","publishAt":` + tt.publishAt + `}`
+			if err := json.Unmarshal([]byte(data), &article); err != nil {
+				t.Fatal(err)
+			}
+			if !article.PublishAt.Equal(tt.want) {
+				t.Errorf("PublishAt = %v, want %v", article.PublishAt, tt.want)
+			}
+			if article.ID != "a1" || article.Title != "T" {
+				t.Errorf("other fields lost: %+v", article)
+			}
+		})
+	}
+}
+
+func TestArticleUnmarshalPublishAtRejects(t *testing.T) {
+	tests := []struct {
+		name      string
+		publishAt string
+		wantValue string
+	}{
+		{"day first date", `"05/03/2024"`, `"05/03/2024"`},
+		{"impossible date", `"2024-02-30"`, `"2024-02-30"`},
+		{"fractional epoch", `1709634600.5`, `1709634600.5`},
+		{"boolean", `true`, `true`},
+		{"epoch past year 9999", `99999999999999`, `99999999999999`},
+		{"epoch string past year 9999", `"253402300800"`, `"253402300800"`},
+		{"epoch before year 0", `-62167219201`, `-62167219201`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var article Article
+			err := json.Unmarshal([]byte(`{"publishAt":`+tt.publishAt+`}`), &article)
+			var badTime *PublishAtError
+			if !errors.As(err, &badTime) {
+				t.Fatalf("err = %v, want a *PublishAtError", err)
+			}
+			if badTime.Value != tt.wantValue {
+				t.Errorf("Value = %s, want %s", badTime.Value, tt.wantValue)
+			}
+		})
+	}
+}
+
+func TestCreateArticlePublishAtFormats(t *testing.T) {
+	tests := []struct {
+		name      string
+		publishAt string
+		wantCode  int
+		wantBody  string
+	}{
+		{"RFC 3339", `"2024-03-05T10:30:00Z"`, http.StatusCreated, `"publishAt":"2024-03-05T10:30:00Z"`},
+		{"epoch seconds", `1709634600`, http.StatusCreated, `"publishAt":"2024-03-05T10:30:00Z"`},
+		{"date only", `"2024-03-05"`, http.StatusCreated, `"publishAt":"2024-03-05T00:00:00Z"`},
+		{"rejected", `"next tuesday"`, http.StatusBadRequest, `invalid publishAt "next tuesday"`},
+		{"past year 9999", `99999999999999`, http.StatusBadRequest, `invalid publishAt 99999999999999`},
+		{"last encodable second", `253402300799`, http.StatusCreated, `"publishAt":"9999-12-31T23:59:59Z"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newTestHandler(newTestSvc())
+
+			rec := doRequest(h, "PUT", "/articles", `{"id":"a1","title":"T","publishAt":`+tt.publishAt+`}`)
+			if rec.Code != tt.wantCode {
+				t.Fatalf("PUT status = %d, want %d: %s", rec.Code, tt.wantCode, rec.Body)
+			}
+			if tt.wantCode != http.StatusCreated {
+				if !strings.Contains(rec.Body.String(), tt.wantBody) {
+					t.Errorf("body = %q, want it to contain %q", rec.Body, tt.wantBody)
+				}
+				return
+			}
+
+			rec = doRequest(h, "GET", "/articles/a1", "")
+			if rec.Code != http.StatusOK {
+				t.Fatalf("GET status = %d: %s", rec.Code, rec.Body)
+			}
+			if !strings.Contains(rec.Body.String(), tt.wantBody) {
+				t.Errorf("GET body = %s, want it to contain %s", rec.Body, tt.wantBody)
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+func TestValidateQuery(t *testing.T) {
+	var reached bool
+	h := validateQuery(
+		queryParam{name: "q", kind: stringParam, required: true},
+		queryParam{name: "n", kind: intParam, min: 1, max: 10},
+		queryParam{name: "skip", kind: intParam, min: 0},
+		queryParam{name: "on", kind: boolParam},
+		queryParam{name: "at", kind: timeParam},
+	)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { reached = true }))
+
+	tests := []struct {
+		name  string
+		query string
+		// want lists the rejected parameters; none means the handler runs.
+		want []FieldError
+	}{
+		{"valid", "q=x&n=5&skip=0&on=true&at=2024-01-01T00:00:00Z", nil},
+		{"bounds are inclusive", "q=x&n=1", nil},
+		{"upper bound", "q=x&n=10", nil},
+		{"missing required", "n=5", []FieldError{{"q", "is required"}}},
+		{"empty required", "q=", []FieldError{{"q", "is required"}}},
+		{"not an integer", "q=x&n=five", []FieldError{{"n", "must be an integer"}}},
+		{"below range", "q=x&n=0", []FieldError{{"n", "must be between 1 and 10"}}},
+		{"above range", "q=x&n=11", []FieldError{{"n", "must be between 1 and 10"}}},
+		{"below open range", "q=x&skip=-1", []FieldError{{"skip", "must be at least 0"}}},
+		{"not a boolean", "q=x&on=yes", []FieldError{{"on", "must be a boolean"}}},
+		{"not a timestamp", "q=x&at=yesterday", []FieldError{{"at", "must be an RFC 3339 timestamp"}}},
+		{"every offence is listed", "n=0&on=yes", []FieldError{{"q", "is required"}, {"n", "must be between 1 and 10"}, {"on", "must be a boolean"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reached = false
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, httptest.NewRequest("GET", "/?"+tt.query, nil))
+
+			if tt.want == nil {
+				if rec.Code != http.StatusOK || !reached {
+					t.Errorf("status = %d, handler ran = %v; want 200 from the handler", rec.Code, reached)
+				}
+				return
+			}
+			if rec.Code != http.StatusBadRequest || reached {
+				t.Fatalf("status = %d, handler ran = %v; want 400 before the handler", rec.Code, reached)
+			}
+			var got ValidationError
+			decodeJSON(t, rec, &got)
+			if !slices.Equal(got.Fields, tt.want) {
+				t.Errorf("errors = %v, want %v", got.Fields, tt.want)
+			}
+		})
+	}
+}
+
+func TestListQueryChecked(t *testing.T) {
+	h := newTestHandler(newTestSvc())
+
+	tests := []struct {
+		query string
+		want  int
+	}{
+		{"limit=100", http.StatusOK},
+		{"limit=101", http.StatusBadRequest},
+		{"limit=0", http.StatusBadRequest},
+		{"offset=-1", http.StatusBadRequest},
+		{"pinned=maybe", http.StatusBadRequest},
+		{"from=2024-01-01", http.StatusBadRequest},
+		{"excerpt=-5", http.StatusBadRequest},
+		{"envelope=1", http.StatusOK},
+	}
+	for _, tt := range tests {
+		for _, method := range []string{"GET", "HEAD"} {
+			if rec := doRequest(h, method, "/articles?"+tt.query, ""); rec.Code != tt.want {
+				t.Errorf("%s ?%s: status = %d, want %d", method, tt.query, rec.Code, tt.want)
+			}
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestListDateRange(t *testing.T) {
+	midnight := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	svc := newTestSvc()
+	mustAdd(t, svc,
+		Article{ID: "before", Title: "Before", PublishAt: midnight.Add(-time.Nanosecond)},
+		Article{ID: "start", Title: "Start", PublishAt: midnight, Tags: []string{"go"}},
+		Article{ID: "middle", Title: "Middle", PublishAt: midnight.Add(12 * time.Hour)},
+		Article{ID: "end", Title: "End", PublishAt: midnight.Add(24 * time.Hour), Tags: []string{"go"}},
+		Article{ID: "after", Title: "After", PublishAt: midnight.Add(24*time.Hour + time.Nanosecond)},
+	)
+	h := newTestHandler(svc)
+
+	const (
+		dayStart = "2024-03-01T00:00:00Z"
+		dayEnd   = "2024-03-02T00:00:00Z"
+	)
+	tests := []struct {
+		name  string
+		query url.Values
+		want  []string
+	}{
+		{"inclusive on both ends", url.Values{"from": {dayStart}, "to": {dayEnd}}, []string{"end", "middle", "start"}},
+		{"a single instant", url.Values{"from": {dayStart}, "to": {dayStart}}, []string{"start"}},
+		{"other time zone", url.Values{"from": {"2024-03-01T01:00:00+01:00"}, "to": {"2024-03-02T01:00:00+01:00"}}, []string{"end", "middle", "start"}},
+		{"fractional seconds", url.Values{"from": {"2024-02-29T23:59:59.999999999Z"}, "to": {"2024-03-01T00:00:00.000000001Z"}}, []string{"before", "start"}},
+		{"open start", url.Values{"to": {dayStart}}, []string{"before", "start"}},
+		{"open end", url.Values{"from": {dayEnd}}, []string{"after", "end"}},
+		{"no match", url.Values{"from": {"2030-01-01T00:00:00Z"}}, nil},
+		{"with a tag", url.Values{"from": {dayStart}, "to": {dayEnd}, "tag": {"go"}}, []string{"end", "start"}},
+		{"with anyTag", url.Values{"from": {"2024-03-01T00:00:00.000000001Z"}, "anyTag": {"go", "rust"}}, []string{"end"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := doRequest(h, "GET", "/articles?"+tt.query.Encode(), "")
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d %s", rec.Code, rec.Body)
+			}
+			var articles []Article
+			decodeJSON(t, rec, &articles)
+			if ids := articleIDs(articles); !slices.Equal(ids, tt.want) {
+				t.Errorf("got %v, want %v", ids, tt.want)
+			}
+		})
+	}
+}
+
+func TestListDateRangeRejects(t *testing.T) {
+	h := newTestHandler(newTestSvc())
+
+	tests := []struct {
+		name  string
+		query url.Values
+	}{
+		{"inverted", url.Values{"from": {"2024-03-02T00:00:00Z"}, "to": {"2024-03-01T00:00:00Z"}}},
+		{"inverted by a nanosecond", url.Values{"from": {"2024-03-01T00:00:00.000000001Z"}, "to": {"2024-03-01T00:00:00Z"}}},
+		{"not RFC 3339", url.Values{"from": {"2024-03-01"}}},
+		{"not a time", url.Values{"to": {"yesterday"}}},
+	}
+	for _, tt := range tests {
+		if rec := doRequest(h, "GET", "/articles?"+tt.query.Encode(), ""); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", tt.name, rec.Code)
+		}
+	}
+}
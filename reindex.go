@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"io"
+	"log"
+	"net/http"
+)
+
+// ReindexSummary reports what rebuilding the secondary indexes found.
+type ReindexSummary struct {
+	// Articles is how many articles were indexed.
+	Articles int `json:"articles"`
+	// Entries is how many tag to article entries the rebuilt tag index
+	// holds.
+	Entries int `json:"entries"`
+	// Fixed counts the index entries that were missing or stale before the
+	// rebuild; zero means the indexes were consistent.
+	Fixed int `json:"fixed"`
+}
+
+// reindexer is implemented by repos with secondary indexes that can be
+// rebuilt from the articles themselves.
+type reindexer interface {
+	Reindex(ctx context.Context) (ReindexSummary, error)
+}
+
+// reindexRepo rebuilds repo's indexes if it has any.
+func reindexRepo(ctx context.Context, repo ArticlesRepo) (ReindexSummary, error) {
+	if r, ok := repo.(reindexer); ok {
+		return r.Reindex(ctx)
+	}
+	return ReindexSummary{}, nil
+}
+
+// Reindex rebuilds the tag and slug indexes from the stored articles.
+func (repo *inMemoryRepo) Reindex(_ context.Context) (ReindexSummary, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	stale, staleSlugs := repo.tags, repo.slugs
+	repo.tags = make(map[string]map[string]struct{})
+	repo.slugs = make(map[string]string)
+	for _, article := range repo.articles {
+		repo.index(article)
+	}
+
+	entries, fixed := 0, 0
+	for tag, ids := range repo.tags {
+		entries += len(ids)
+		for id := range ids {
+			if _, ok := stale[tag][id]; !ok {
+				fixed++
+			}
+		}
+	}
+	for tag, ids := range stale {
+		for id := range ids {
+			if _, ok := repo.tags[tag][id]; !ok {
+				fixed++
+			}
+		}
+	}
+	for slug, id := range repo.slugs {
+		if staleSlugs[slug] != id {
+			fixed++
+		}
+	}
+	for slug, id := range staleSlugs {
+		if repo.slugs[slug] != id {
+			fixed++
+		}
+	}
+	return ReindexSummary{Articles: len(repo.articles), Entries: entries, Fixed: fixed}, nil
+}
+
+func (repo *shardedRepo) Reindex(ctx context.Context) (ReindexSummary, error) {
+	var total ReindexSummary
+	for _, shard := range repo.shards {
+		summary, err := shard.Reindex(ctx)
+		if err != nil {
+			return total, err
+		}
+		total.Articles += summary.Articles
+		total.Entries += summary.Entries
+		total.Fixed += summary.Fixed
+	}
+	return total, nil
+}
+
+// Reindex rebuilds the repo's secondary indexes. It is a recovery tool for
+// indexes that a bug left inconsistent with the articles.
+func (svc *articleSvc) Reindex(ctx context.Context) (ReindexSummary, error) {
+	return reindexRepo(ctx, svc.repo)
+}
+
+// reindex rebuilds the secondary indexes and reports what it fixed.
+func (t *adminHttpTransport) reindex(w http.ResponseWriter, r *http.Request) {
+	summary, err := t.svc.Reindex(r.Context())
+	if err != nil {
+		log.Println(err)
+		w.WriteHeader(serverErrorStatus(err))
+		io.WriteString(w, errorBody(err))
+		return
+	}
+
+	log.Printf("indexes rebuilt by %s from %s: %d entries fixed", principalFromContext(r.Context()).Name, clientIP(r), summary.Fixed)
+	writeEncoded(w, jsonEncoding{}, http.StatusOK, summary)
+}
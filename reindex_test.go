@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"testing"
+)
+
+// newIndexedRepo stores a1 tagged go and a2 tagged rust behind a service.
+func newIndexedRepo(t *testing.T) (*inMemoryRepo, *articleSvc) {
+	t.Helper()
+	repo := newInMemoryRepo()
+	svc := newArticleSvc(repo)
+	mustAdd(t, svc,
+		Article{ID: "a1", Title: "Go", Tags: []string{"go"}},
+		Article{ID: "a2", Title: "Rust", Tags: []string{"rust"}},
+	)
+	return repo, svc
+}
+
+func TestReindex(t *testing.T) {
+	tests := []struct {
+		name    string
+		corrupt func(repo *inMemoryRepo)
+		// wantGo lists what the go tag query finds before the rebuild.
+		wantGo    []string
+		wantFixed int
+	}{
+		{"consistent", func(*inMemoryRepo) {}, []string{"a1"}, 0},
+		{"missing entry", func(repo *inMemoryRepo) { delete(repo.tags["go"], "a1") }, nil, 1},
+		{"entry for an article without the tag", func(repo *inMemoryRepo) { repo.tags["go"]["a2"] = struct{}{} }, []string{"a1", "a2"}, 1},
+		{"entry replaced", func(repo *inMemoryRepo) {
+			delete(repo.tags["go"], "a1")
+			repo.tags["go"]["a2"] = struct{}{}
+		}, []string{"a2"}, 2},
+		{"tag nobody carries", func(repo *inMemoryRepo) {
+			repo.tags["java"] = map[string]struct{}{"a1": {}}
+		}, []string{"a1"}, 1},
+		{"missing slug entry", func(repo *inMemoryRepo) { delete(repo.slugs, "go") }, []string{"a1"}, 1},
+		{"slug entry for another article", func(repo *inMemoryRepo) { repo.slugs["go"] = "a2" }, []string{"a1"}, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo, svc := newIndexedRepo(t)
+			tt.corrupt(repo)
+			s := newAdminTestServer(svc, false, nil)
+
+			// The service filters what the index returns, so ask the repo.
+			taggedGo := func() []string {
+				t.Helper()
+				articles, err := repo.ArticlesByTags(context.Background(), []string{"go"})
+				if err != nil {
+					t.Fatal(err)
+				}
+				return articleIDs(articles)
+			}
+			if got := taggedGo(); !slices.Equal(got, tt.wantGo) {
+				t.Fatalf("before the rebuild go tag query = %v, want %v", got, tt.wantGo)
+			}
+
+			rec := doRequest(s, "POST", "/admin/reindex", "", "X-API-Key", testAdminKey)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("reindex: status = %d", rec.Code)
+			}
+			var summary ReindexSummary
+			decodeJSON(t, rec, &summary)
+			if want := (ReindexSummary{Articles: 2, Entries: 2, Fixed: tt.wantFixed}); summary != want {
+				t.Errorf("summary = %+v, want %+v", summary, want)
+			}
+
+			if got := taggedGo(); !slices.Equal(got, []string{"a1"}) {
+				t.Errorf("after the rebuild go tag query = %v, want [a1]", got)
+			}
+			var listed []Article
+			decodeJSON(t, doRequest(s, "GET", "/articles?tag=go", ""), &listed)
+			if got := articleIDs(listed); !slices.Equal(got, []string{"a1"}) {
+				t.Errorf("after the rebuild GET /articles?tag=go = %v, want [a1]", got)
+			}
+			if _, ok := repo.tags["java"]; ok {
+				t.Error("the rebuilt index still lists a tag nobody carries")
+			}
+			if got := repo.slugs["go"]; got != "a1" {
+				t.Errorf("the rebuilt slug index maps go to %q, want a1", got)
+			}
+		})
+	}
+}
+
+func TestReindexRequiresAdmin(t *testing.T) {
+	_, svc := newIndexedRepo(t)
+	s := newAdminTestServer(svc, false, nil)
+
+	tests := []struct {
+		key  string
+		want int
+	}{
+		{"", http.StatusUnauthorized},
+		{testWriteKey, http.StatusForbidden},
+		{testAdminKey, http.StatusOK},
+	}
+	for _, tt := range tests {
+		var headers []string
+		if tt.key != "" {
+			headers = []string{"X-API-Key", tt.key}
+		}
+		if rec := doRequest(s, "POST", "/admin/reindex", "", headers...); rec.Code != tt.want {
+			t.Errorf("key %q: status = %d, want %d", tt.key, rec.Code, tt.want)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+)
+
+// markdown renders article content. Raw HTML in the source is dropped and
+// links with dangerous schemes (javascript:, etc.) are neutralised, so the
+// output is safe to embed as is.
+var markdown = goldmark.New(goldmark.WithExtensions(extension.GFM))
+
+// experimentalMarkdown is markdown plus footnotes and typographic quotes and
+// dashes, served to requests with the experimental-markdown feature.
+var experimentalMarkdown = goldmark.New(goldmark.WithExtensions(extension.GFM, extension.Footnote, extension.Typographer))
+
+// renderMarkdown converts Markdown content into sanitized HTML, with the
+// experimental renderer when the request in ctx asked for it.
+func renderMarkdown(ctx context.Context, content string) (string, error) {
+	md := markdown
+	if featureEnabled(ctx, featureExperimentalMarkdown) {
+		md = experimentalMarkdown
+	}
+
+	var buf bytes.Buffer
+	if err := md.Convert([]byte(content), &buf); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+type previewRequest struct {
+	Content string `json:"content"`
+}
+
+type previewResponse struct {
+	HTML string `json:"html"`
+}
+
+// previewMarkdown renders the posted Markdown without storing anything.
+func (t *articlesHttpTransport) previewMarkdown(w http.ResponseWriter, r *http.Request) {
+	var req previewRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+
+	html, err := renderMarkdown(r.Context(), req.Content)
+	if err != nil {
+		log.Println(err)
+		w.WriteHeader(serverErrorStatus(err))
+		io.WriteString(w, errorBody(err))
+		return
+	}
+
+	t.writeJSON(w, r, http.StatusOK, previewResponse{HTML: html})
+}
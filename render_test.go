@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestPreviewMarkdown(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{name: "heading and emphasis", content: "# Title\n\nSome *emphasis* and `code`.", want: "<h1>Title</h1>\n<p>Some <em>emphasis</em> and <code>code</code>.</p>\n"},
+		{name: "table", content: "| a | b |\n|---|---|\n| 1 | 2 |", want: "<table>\n<thead>\n<tr>\n<th>a</th>\n<th>b</th>\n</tr>\n</thead>\n<tbody>\n<tr>\n<td>1</td>\n<td>2</td>\n</tr>\n</tbody>\n</table>\n"},
+		{name: "raw html is dropped", content: "<script>alert(1)</script>\n\nok", want: "<!-- raw HTML omitted -->\n<p>ok</p>\n"},
+		{name: "dangerous links are neutralised", content: "[x](javascript:alert(1))", want: "<p><a href=\"\">x</a></p>\n"},
+		{name: "empty", content: "", want: ""},
+	}
+
+	h := http.HandlerFunc(newArticlesHttpTransport(newTestSvc()).previewMarkdown)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, _ := json.Marshal(previewRequest{Content: tt.content})
+			rec := doRequest(h, "POST", "/preview", string(body))
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, want 200", rec.Code)
+			}
+			var resp previewResponse
+			decodeJSON(t, rec, &resp)
+			if resp.HTML != tt.want {
+				t.Errorf("html = %q, want %q", resp.HTML, tt.want)
+			}
+
+			// The preview must render exactly as stored content would.
+			rendered, err := renderMarkdown(context.Background(), tt.content)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.HTML != rendered {
+				t.Errorf("preview %q differs from renderMarkdown %q", resp.HTML, rendered)
+			}
+		})
+	}
+}
+
+func TestPreviewMarkdownBadBody(t *testing.T) {
+	h := http.HandlerFunc(newArticlesHttpTransport(newTestSvc()).previewMarkdown)
+	for _, body := range []string{"", "{", `{"content":1}`} {
+		if rec := doRequest(h, "POST", "/preview", body); rec.Code != http.StatusBadRequest {
+			t.Errorf("body %q: status = %d, want 400", body, rec.Code)
+		}
+	}
+	if rec := doRequest(h, "POST", "/preview", "{}"); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"html":""`) {
+		t.Errorf("empty object: got %d %s, want 200 with empty html", rec.Code, rec.Body)
+	}
+}
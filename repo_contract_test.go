@@ -0,0 +1,352 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// testRepoContract runs the behaviour every ArticlesRepo must share (see the
+// interface doc) against fresh repos from newRepo, so a new backend or
+// decorator only needs a line in TestRepoContract.
+func testRepoContract(t *testing.T, newRepo func() ArticlesRepo) {
+	day := func(d int) time.Time { return time.Date(2024, 3, d, 12, 0, 0, 0, time.UTC) }
+
+	tests := []struct {
+		name string
+		run  func(t *testing.T, ctx context.Context, repo ArticlesRepo)
+	}{
+		{"get missing", func(t *testing.T, ctx context.Context, repo ArticlesRepo) {
+			if _, err := repo.ArticleByID(ctx, "missing"); !errors.Is(err, ErrArticleNotFound) {
+				t.Errorf("err = %v, want ErrArticleNotFound", err)
+			}
+		}},
+		{"update missing", func(t *testing.T, ctx context.Context, repo ArticlesRepo) {
+			if err := repo.UpdateArticle(ctx, Article{ID: "missing", Title: "T"}); !errors.Is(err, ErrArticleNotFound) {
+				t.Errorf("err = %v, want ErrArticleNotFound", err)
+			}
+			if ids := storedIDs(t, repo); len(ids) != 0 {
+				t.Errorf("update of a missing ID stored %v", ids)
+			}
+		}},
+		{"delete missing", func(t *testing.T, ctx context.Context, repo ArticlesRepo) {
+			if err := repo.DeleteArticle(ctx, "missing"); !errors.Is(err, ErrArticleNotFound) {
+				t.Errorf("err = %v, want ErrArticleNotFound", err)
+			}
+		}},
+		{"insert then get", func(t *testing.T, ctx context.Context, repo ArticlesRepo) {
+			want := Article{ID: "a1", Title: "One", Content: "Body", Tags: []string{"go"}, PublishAt: day(1)}
+			contractInsert(t, repo, want)
+			got := contractGet(t, repo, "a1")
+			if got.Title != want.Title || got.Content != want.Content ||
+				!slices.Equal(got.Tags, want.Tags) || !got.PublishAt.Equal(want.PublishAt) {
+				t.Errorf("got %+v, want %+v", got, want)
+			}
+		}},
+		{"insert replaces the same ID", func(t *testing.T, ctx context.Context, repo ArticlesRepo) {
+			contractInsert(t, repo, Article{ID: "a1", Title: "Old", Tags: []string{"old"}})
+			contractInsert(t, repo, Article{ID: "a1", Title: "New", Tags: []string{"new"}})
+			if got := contractGet(t, repo, "a1"); got.Title != "New" {
+				t.Errorf("Title = %q, want New", got.Title)
+			}
+			if ids := storedIDs(t, repo); !slices.Equal(ids, []string{"a1"}) {
+				t.Errorf("stored %v, want [a1]", ids)
+			}
+			if freq := contractTagFrequency(t, repo); freq["old"] != 0 || freq["new"] != 1 {
+				t.Errorf("TagFrequency = %v, want only new", freq)
+			}
+		}},
+		{"update replaces", func(t *testing.T, ctx context.Context, repo ArticlesRepo) {
+			contractInsert(t, repo, Article{ID: "a1", Title: "Old", Tags: []string{"old"}})
+			if err := repo.UpdateArticle(ctx, Article{ID: "a1", Title: "New", Tags: []string{"new"}}); err != nil {
+				t.Fatal(err)
+			}
+			if got := contractGet(t, repo, "a1"); got.Title != "New" {
+				t.Errorf("Title = %q, want New", got.Title)
+			}
+			if matches, err := repo.ArticlesByTags(ctx, []string{"old"}); err != nil || len(matches) != 0 {
+				t.Errorf("ArticlesByTags(old) = %v, %v; want none", matches, err)
+			}
+		}},
+		{"delete then get and re-insert", func(t *testing.T, ctx context.Context, repo ArticlesRepo) {
+			contractInsert(t, repo, Article{ID: "a1", Title: "First", Tags: []string{"go"}})
+			if err := repo.DeleteArticle(ctx, "a1"); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := repo.ArticleByID(ctx, "a1"); !errors.Is(err, ErrArticleNotFound) {
+				t.Errorf("get after delete: err = %v, want ErrArticleNotFound", err)
+			}
+			if err := repo.DeleteArticle(ctx, "a1"); !errors.Is(err, ErrArticleNotFound) {
+				t.Errorf("second delete: err = %v, want ErrArticleNotFound", err)
+			}
+			if freq := contractTagFrequency(t, repo); len(freq) != 0 {
+				t.Errorf("TagFrequency after delete = %v, want empty", freq)
+			}
+
+			contractInsert(t, repo, Article{ID: "a1", Title: "Second"})
+			if got := contractGet(t, repo, "a1"); got.Title != "Second" {
+				t.Errorf("Title after re-insert = %q, want Second", got.Title)
+			}
+		}},
+		{"list empty", func(t *testing.T, ctx context.Context, repo ArticlesRepo) {
+			articles, err := repo.AllArticles(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if articles == nil || len(articles) != 0 {
+				t.Errorf("AllArticles = %#v, want an empty non-nil slice", articles)
+			}
+		}},
+		{"list", func(t *testing.T, ctx context.Context, repo ArticlesRepo) {
+			for _, id := range []string{"c", "a", "b"} {
+				contractInsert(t, repo, Article{ID: id, Title: id})
+			}
+			if ids := storedIDs(t, repo); !slices.Equal(ids, []string{"a", "b", "c"}) {
+				t.Errorf("stored %v, want [a b c]", ids)
+			}
+		}},
+		{"by title", func(t *testing.T, ctx context.Context, repo ArticlesRepo) {
+			contractInsert(t, repo,
+				Article{ID: "a1", Title: "Hello  World"},
+				Article{ID: "a2", Title: "hello world"},
+				Article{ID: "a3", Title: "Hello"},
+			)
+			matches, err := repo.ArticlesByTitle(ctx, "HELLO world")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ids := articleIDs(matches); !slices.Equal(ids, []string{"a1", "a2"}) {
+				t.Errorf("ArticlesByTitle = %v, want [a1 a2]", ids)
+			}
+		}},
+		{"in range", func(t *testing.T, ctx context.Context, repo ArticlesRepo) {
+			contractInsert(t, repo,
+				Article{ID: "d1", Title: "T", PublishAt: day(1)},
+				Article{ID: "d2", Title: "T", PublishAt: day(2)},
+				Article{ID: "d3", Title: "T", PublishAt: day(3)},
+				Article{ID: "none", Title: "T"},
+			)
+			for _, tt := range []struct {
+				from, to time.Time
+				want     []string
+			}{
+				{day(2), day(3), []string{"d2", "d3"}},
+				{day(2), time.Time{}, []string{"d2", "d3"}},
+				{time.Time{}, day(1), []string{"d1"}},
+				{time.Time{}, time.Time{}, []string{"d1", "d2", "d3"}},
+			} {
+				matches, err := repo.ArticlesInRange(ctx, tt.from, tt.to)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if ids := articleIDs(matches); !slices.Equal(ids, tt.want) {
+					t.Errorf("ArticlesInRange(%v, %v) = %v, want %v", tt.from, tt.to, ids, tt.want)
+				}
+			}
+		}},
+		{"by tags", func(t *testing.T, ctx context.Context, repo ArticlesRepo) {
+			contractInsert(t, repo,
+				Article{ID: "a1", Title: "T", Tags: []string{"go", "web"}},
+				Article{ID: "a2", Title: "T", Tags: []string{"go"}},
+				Article{ID: "a3", Title: "T", Tags: []string{"Go"}},
+			)
+			for _, tt := range []struct {
+				tags   []string
+				all    []string
+				anyTag []string
+			}{
+				{[]string{"go"}, []string{"a1", "a2"}, []string{"a1", "a2"}},
+				{[]string{"go", "web"}, []string{"a1"}, []string{"a1", "a2"}},
+				{[]string{"Go", "web"}, nil, []string{"a1", "a3"}},
+				{nil, []string{"a1", "a2", "a3"}, nil},
+			} {
+				matches, err := repo.ArticlesByTags(ctx, tt.tags)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if ids := articleIDs(matches); !slices.Equal(ids, tt.all) {
+					t.Errorf("ArticlesByTags(%v) = %v, want %v", tt.tags, ids, tt.all)
+				}
+				matches, err = repo.ArticlesByAnyTag(ctx, tt.tags)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if ids := articleIDs(matches); !slices.Equal(ids, tt.anyTag) {
+					t.Errorf("ArticlesByAnyTag(%v) = %v, want %v", tt.tags, ids, tt.anyTag)
+				}
+			}
+		}},
+		{"tag frequency and suggestions", func(t *testing.T, ctx context.Context, repo ArticlesRepo) {
+			contractInsert(t, repo,
+				Article{ID: "a1", Title: "T", Tags: []string{"golang", "go"}},
+				Article{ID: "a2", Title: "T", Tags: []string{"go", "web"}},
+				Article{ID: "a3", Title: "T", Tags: []string{"go", "gopher"}},
+			)
+			freq := contractTagFrequency(t, repo)
+			if freq["go"] != 3 || freq["golang"] != 1 || freq["web"] != 1 || len(freq) != 4 {
+				t.Errorf("TagFrequency = %v", freq)
+			}
+
+			suggestions, err := repo.SuggestTags(ctx, "GO", 2)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := []TagSuggestion{{Tag: "go", Count: 3}, {Tag: "golang", Count: 1}}
+			if !slices.Equal(suggestions, want) {
+				t.Errorf("SuggestTags = %v, want %v", suggestions, want)
+			}
+		}},
+		{"each article", func(t *testing.T, ctx context.Context, repo ArticlesRepo) {
+			contractInsert(t, repo, Article{ID: "a", Title: "T"}, Article{ID: "b", Title: "T"}, Article{ID: "c", Title: "T"})
+			var seen []string
+			if err := repo.EachArticle(ctx, func(article Article) error {
+				seen = append(seen, article.ID)
+				return nil
+			}); err != nil {
+				t.Fatal(err)
+			}
+			slices.Sort(seen)
+			if !slices.Equal(seen, []string{"a", "b", "c"}) {
+				t.Errorf("visited %v, want [a b c]", seen)
+			}
+
+			errStop := errors.New("stop")
+			calls := 0
+			err := repo.EachArticle(ctx, func(Article) error {
+				calls++
+				return errStop
+			})
+			if !errors.Is(err, errStop) || calls != 1 {
+				t.Errorf("EachArticle = %v after %d calls, want errStop after 1", err, calls)
+			}
+		}},
+		{"clear", func(t *testing.T, ctx context.Context, repo ArticlesRepo) {
+			contractInsert(t, repo, Article{ID: "a", Title: "T", Tags: []string{"go"}}, Article{ID: "b", Title: "T"})
+			removed, err := repo.Clear(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if removed != 2 {
+				t.Errorf("removed = %d, want 2", removed)
+			}
+			if ids := storedIDs(t, repo); len(ids) != 0 {
+				t.Errorf("stored after Clear = %v", ids)
+			}
+			if freq := contractTagFrequency(t, repo); len(freq) != 0 {
+				t.Errorf("TagFrequency after Clear = %v", freq)
+			}
+			contractInsert(t, repo, Article{ID: "a", Title: "Again"})
+		}},
+		{"by slug", func(t *testing.T, ctx context.Context, repo ArticlesRepo) {
+			contractInsert(t, repo,
+				Article{ID: "a1", Title: "One", Slug: "one"},
+				Article{ID: "a2", Title: "Two", Slug: "two"},
+				Article{ID: "a3", Title: "No slug"},
+			)
+			bySlug := func(slug string) string {
+				t.Helper()
+				article, err := repo.ArticleBySlug(ctx, slug)
+				if errors.Is(err, ErrArticleNotFound) {
+					return ""
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				return article.ID
+			}
+			if got := bySlug("two"); got != "a2" {
+				t.Errorf("two = %q, want a2", got)
+			}
+			if got := bySlug(""); got != "" {
+				t.Errorf("the empty slug found %q", got)
+			}
+
+			if err := repo.UpdateArticle(ctx, Article{ID: "a1", Title: "One", Slug: "uno"}); err != nil {
+				t.Fatal(err)
+			}
+			if err := repo.DeleteArticle(ctx, "a2"); err != nil {
+				t.Fatal(err)
+			}
+			for slug, want := range map[string]string{"one": "", "uno": "a1", "two": ""} {
+				if got := bySlug(slug); got != want {
+					t.Errorf("after the update and delete %s = %q, want %q", slug, got, want)
+				}
+			}
+			if _, err := repo.Clear(ctx); err != nil {
+				t.Fatal(err)
+			}
+			if got := bySlug("uno"); got != "" {
+				t.Errorf("after Clear uno = %q", got)
+			}
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.run(t, context.Background(), newRepo())
+		})
+	}
+}
+
+func TestRepoContract(t *testing.T) {
+	backends := []struct {
+		name    string
+		newRepo func() ArticlesRepo
+	}{
+		{"inMemory", func() ArticlesRepo { return newInMemoryRepo() }},
+		{"sharded", func() ArticlesRepo { return newShardedRepo(4) }},
+	}
+	for _, backend := range backends {
+		t.Run(backend.name, func(t *testing.T) {
+			testRepoContract(t, backend.newRepo)
+		})
+		t.Run("metrics/"+backend.name, func(t *testing.T) {
+			testRepoContract(t, func() ArticlesRepo {
+				return newMetricsRepo(backend.newRepo(), prometheus.NewRegistry())
+			})
+		})
+		t.Run("timing/"+backend.name, func(t *testing.T) {
+			testRepoContract(t, func() ArticlesRepo { return newTimingRepo(backend.newRepo()) })
+		})
+	}
+}
+
+func contractInsert(t *testing.T, repo ArticlesRepo, articles ...Article) {
+	t.Helper()
+	for _, article := range articles {
+		if err := repo.InsertArticle(context.Background(), article); err != nil {
+			t.Fatalf("insert %s: %v", article.ID, err)
+		}
+	}
+}
+
+func contractGet(t *testing.T, repo ArticlesRepo, id string) Article {
+	t.Helper()
+	article, err := repo.ArticleByID(context.Background(), id)
+	if err != nil {
+		t.Fatalf("get %s: %v", id, err)
+	}
+	return *article
+}
+
+func contractTagFrequency(t *testing.T, repo ArticlesRepo) map[string]int {
+	t.Helper()
+	freq, err := repo.TagFrequency(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return freq
+}
+
+// articleIDs returns the IDs of articles, sorted, or nil when there are none.
+func articleIDs(articles []Article) []string {
+	var ids []string
+	for _, article := range articles {
+		ids = append(ids, article.ID)
+	}
+	slices.Sort(ids)
+	return ids
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+)
+
+// maxRequestIDLength caps request IDs accepted from clients; longer ones are
+// replaced like any other unusable ID.
+const maxRequestIDLength = 128
+
+type requestIDKey struct{}
+
+// requestIDMiddleware gives every request a correlation ID. An ID already
+// set in header by the client or a proxy is kept when usable, otherwise a
+// new one is generated. The ID is echoed in the same response header and
+// stored in the request context, from where it follows the request into the
+// access log and into the events its writes publish.
+func requestIDMiddleware(header string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(header)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+		w.Header().Set(header, id)
+		ctx := context.WithValue(r.Context(), requestIDKey{}, id)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// validRequestID accepts non-empty IDs of printable ASCII without spaces, so
+// an ID can be logged and echoed without quoting.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// newRequestID returns a random 32 character hex ID.
+func newRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// requestIDFromContext returns the ID requestIDMiddleware stored in ctx, or
+// "" outside a request or when propagation is disabled.
+func requestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
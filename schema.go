@@ -0,0 +1,84 @@
+package main
+
+import (
+	"net/http"
+	"sort"
+)
+
+// articleSchema describes Article as a JSON Schema (draft 2020-12). It is
+// maintained by hand next to Article.Validate; limits are taken from the same
+// constants so the two cannot drift apart.
+func articleSchema() map[string]interface{} {
+	mimeTypes := make([]string, 0, len(attachmentMIMETypes))
+	for mimeType := range attachmentMIMETypes {
+		mimeTypes = append(mimeTypes, mimeType)
+	}
+	sort.Strings(mimeTypes)
+
+	nonBlank := map[string]interface{}{"type": "string", "pattern": `\S`}
+
+	return map[string]interface{}{
+		"$schema":  "https://json-schema.org/draft/2020-12/schema",
+		"$id":      "/articles/schema",
+		"title":    "Article",
+		"type":     "object",
+		"required": []string{"id", "title"},
+		"properties": map[string]interface{}{
+			"id":    nonBlank,
+			"title": nonBlank,
+			"tags": map[string]interface{}{
+				"type":     []string{"array", "null"},
+				"maxItems": maxTagsPerArticle,
+				"items":    nonBlank,
+			},
+			"content": map[string]interface{}{"type": "string"},
+			// Responses always use date-time; requests may also send a
+			// date or epoch seconds (see Article.UnmarshalJSON).
+			"publishAt": map[string]interface{}{
+				"anyOf": []interface{}{
+					map[string]interface{}{"type": "string", "format": "date-time"},
+					map[string]interface{}{"type": "string", "format": "date"},
+					map[string]interface{}{"type": "string", "pattern": "^-?[0-9]+$"},
+					map[string]interface{}{"type": "integer"},
+					map[string]interface{}{"type": "null"},
+				},
+			},
+			"attachments": map[string]interface{}{
+				"type": []string{"array", "null"},
+				"items": map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"url": map[string]interface{}{
+							"type":    "string",
+							"format":  "uri",
+							"pattern": "^https?://",
+						},
+						"mimeType": map[string]interface{}{"enum": mimeTypes},
+						"size":     map[string]interface{}{"type": "integer", "minimum": 0},
+						"alt":      map[string]interface{}{"type": "string"},
+					},
+				},
+			},
+			"pinned":    map[string]interface{}{"type": "boolean"},
+			"status":    map[string]interface{}{"enum": []interface{}{"", StatusDraft, StatusPublished}},
+			"seq":       map[string]interface{}{"type": "integer", "readOnly": true},
+			"slug":      map[string]interface{}{"type": "string", "pattern": `^[^\s/?#]*$`},
+			"excerpt":   map[string]interface{}{"type": "string"},
+			"locked":    map[string]interface{}{"type": "boolean", "readOnly": true},
+			"lockedBy":  map[string]interface{}{"type": "string", "readOnly": true},
+			"scheduled": map[string]interface{}{"type": "boolean", "readOnly": true},
+			"modifiedAt": map[string]interface{}{
+				"type": "string", "format": "date-time", "readOnly": true,
+			},
+		},
+	}
+}
+
+func (t *articlesHttpTransport) articleSchema(w http.ResponseWriter, _ *http.Request) {
+	writeEncoded(w, schemaEncoding{}, http.StatusOK, articleSchema())
+}
+
+// schemaEncoding is compact JSON served as a JSON Schema document.
+type schemaEncoding struct{ jsonEncoding }
+
+func (schemaEncoding) contentType() string { return "application/schema+json" }
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+)
+
+// compileServedSchema fetches GET /articles/schema and compiles it.
+func compileServedSchema(t *testing.T) *jsonschema.Schema {
+	t.Helper()
+	rec := doRequest(newTestHandler(newTestSvc()), "GET", "/articles/schema", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rec.Code)
+	}
+
+	doc, err := jsonschema.UnmarshalJSON(rec.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const url = "https://quirky-thoughts.test/articles/schema"
+	c := jsonschema.NewCompiler()
+	if err := c.AddResource(url, doc); err != nil {
+		t.Fatal(err)
+	}
+	schema, err := c.Compile(url)
+	if err != nil {
+		t.Fatalf("compiling the served schema: %v", err)
+	}
+	return schema
+}
+
+func TestArticleSchemaValidatesArticles(t *testing.T) {
+	schema := compileServedSchema(t)
+
+	sample, err := json.Marshal(Article{
+		ID:          "a1",
+		Title:       "Sample",
+		Tags:        []string{"go", "http"},
+		Content:     "Hello",
+		PublishAt:   time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+		Attachments: []Attachment{{URL: "https://cdn.example.com/a.png", MIMEType: "image/png", Size: 1, Alt: "a"}},
+		Status:      StatusPublished,
+		Slug:        "sample",
+		ModifiedAt:  time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		doc   string
+		valid bool
+	}{
+		{name: "served article", doc: string(sample), valid: true},
+		{name: "minimal", doc: `{"id":"a1","title":"T"}`, valid: true},
+		{name: "date publishAt", doc: `{"id":"a1","title":"T","publishAt":"2024-05-01"}`, valid: true},
+		{name: "epoch publishAt", doc: `{"id":"a1","title":"T","publishAt":1714564800}`, valid: true},
+		{name: "missing title", doc: `{"id":"a1"}`},
+		{name: "blank id", doc: `{"id":"  ","title":"T"}`},
+		{name: "too many tags", doc: `{"id":"a1","title":"T","tags":["1","2","3","4","5","6","7","8","9","10","11"]}`},
+		{name: "unknown status", doc: `{"id":"a1","title":"T","status":"archived"}`},
+		{name: "unsupported attachment", doc: `{"id":"a1","title":"T","attachments":[{"url":"https://x.test/a","mimeType":"text/html"}]}`},
+		{name: "slug with slash", doc: `{"id":"a1","title":"T","slug":"a/b"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inst, err := jsonschema.UnmarshalJSON(strings.NewReader(tt.doc))
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = schema.Validate(inst)
+			if tt.valid && err != nil {
+				t.Errorf("rejected: %v", err)
+			}
+			if !tt.valid && err == nil {
+				t.Error("accepted, want rejected")
+			}
+
+			// The schema must agree with the server's own validation.
+			var article Article
+			if err := json.Unmarshal([]byte(tt.doc), &article); err != nil {
+				t.Fatal(err)
+			}
+			if got := article.Validate() == nil; got != tt.valid {
+				t.Errorf("Article.Validate() accepted = %v, want %v like the schema", got, tt.valid)
+			}
+		})
+	}
+}
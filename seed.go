@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+)
+
+// seedArticles adds the articles in the JSON array at path through svc, so
+// they are validated like any create. Articles whose ID is already stored
+// are skipped, which makes seeding safe to repeat against a store that
+// survives restarts. Articles that fail to add are logged and counted in
+// failed; only an unreadable file is an error.
+func seedArticles(ctx context.Context, svc ArticlesService, path string) (seeded, skipped, failed int, err error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, 0, 0, fmt.Errorf("-seed-file: %w", err)
+	}
+	var articles []Article
+	if err := json.Unmarshal(data, &articles); err != nil {
+		return 0, 0, 0, fmt.Errorf("-seed-file %s: %w", path, err)
+	}
+
+	for i, article := range articles {
+		if article.ID != "" {
+			_, err := svc.Article(ctx, article.ID)
+			if err == nil {
+				skipped++
+				continue
+			}
+			if !errors.Is(err, ErrArticleNotFound) {
+				return seeded, skipped, failed, err
+			}
+		}
+		if _, err := svc.AddArticle(ctx, article); err != nil {
+			slog.Warn("seed article rejected", "index", i, "id", article.ID, "err", err)
+			failed++
+			continue
+		}
+		seeded++
+	}
+	return seeded, skipped, failed, nil
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// writeSeedFile writes content to a seed file in a temporary directory.
+func writeSeedFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "seed.json")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestSeedArticles(t *testing.T) {
+	svc := newTestSvc()
+	mustAdd(t, svc, Article{ID: "a2", Title: "Already here"})
+	// a3 has no title and fails validation.
+	path := writeSeedFile(t, `[
+		{"id":"a1","title":"First","tags":["go"]},
+		{"id":"a2","title":"Replacement"},
+		{"id":"a3"},
+		{"id":"a4","title":"Fourth"}
+	]`)
+
+	tests := []struct {
+		name                                string
+		wantSeeded, wantSkipped, wantFailed int
+	}{
+		{"first boot", 2, 1, 1},
+		// A restart against the same store adds nothing.
+		{"restart", 0, 3, 1},
+	}
+	for _, tt := range tests {
+		seeded, skipped, failed, err := seedArticles(context.Background(), svc, path)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if seeded != tt.wantSeeded || skipped != tt.wantSkipped || failed != tt.wantFailed {
+			t.Errorf("%s: seeded %d, skipped %d, failed %d; want %d, %d, %d", tt.name, seeded, skipped, failed, tt.wantSeeded, tt.wantSkipped, tt.wantFailed)
+		}
+	}
+
+	h := newTestHandler(svc)
+	var listed []Article
+	decodeJSON(t, doRequest(h, "GET", "/articles", ""), &listed)
+	if got := articleIDs(listed); !slices.Equal(got, []string{"a1", "a2", "a4"}) {
+		t.Errorf("listing = %v, want [a1 a2 a4]", got)
+	}
+	if rec := doRequest(h, "GET", "/articles/a1", ""); rec.Code != http.StatusOK || decodeArticle(t, rec).Title != "First" {
+		t.Errorf("GET a1: status %d", rec.Code)
+	}
+	if got := mustGet(t, svc, "a2"); got.Title != "Already here" {
+		t.Errorf("seeding replaced a2 with %q", got.Title)
+	}
+}
+
+func TestSeedArticlesBadFile(t *testing.T) {
+	tests := []struct {
+		name string
+		path string
+	}{
+		{"missing", filepath.Join(t.TempDir(), "missing.json")},
+		{"not an array", writeSeedFile(t, `{"id":"a1"}`)},
+		{"malformed", writeSeedFile(t, `[{"id":`)},
+	}
+	for _, tt := range tests {
+		svc := newTestSvc()
+		if _, _, _, err := seedArticles(context.Background(), svc, tt.path); err == nil {
+			t.Errorf("%s: no error", tt.name)
+		}
+		if ids := storedIDs(t, svc.repo); len(ids) != 0 {
+			t.Errorf("%s: stored %v", tt.name, ids)
+		}
+	}
+}
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"slices"
+	"sort"
+	"time"
+)
+
+// Discrepancy is one broken invariant found by a self-check.
+type Discrepancy struct {
+	// Kind names the invariant: missing_tag_entry, stale_tag_entry,
+	// id_mismatch, missing_insert_seq, stale_insert_seq, missing_slug_entry,
+	// stale_slug_entry, duplicate_slug or wrong_shard.
+	Kind   string `json:"kind"`
+	ID     string `json:"id"`
+	Tag    string `json:"tag,omitempty"`
+	Detail string `json:"detail"`
+}
+
+// SelfCheckReport is the outcome of checking a repo's secondary indexes
+// against its articles.
+type SelfCheckReport struct {
+	// Articles is how many articles were checked.
+	Articles int `json:"articles"`
+	// OK reports that no discrepancy was found.
+	OK            bool          `json:"ok"`
+	Discrepancies []Discrepancy `json:"discrepancies"`
+}
+
+// add merges other into the report.
+func (r *SelfCheckReport) add(other SelfCheckReport) {
+	r.Articles += other.Articles
+	r.Discrepancies = append(r.Discrepancies, other.Discrepancies...)
+}
+
+// finish sorts the discrepancies so reports compare cleanly and sets OK.
+func (r *SelfCheckReport) finish() {
+	if r.Discrepancies == nil {
+		r.Discrepancies = []Discrepancy{}
+	}
+	sort.Slice(r.Discrepancies, func(i, j int) bool {
+		a, b := r.Discrepancies[i], r.Discrepancies[j]
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		if a.ID != b.ID {
+			return a.ID < b.ID
+		}
+		return a.Tag < b.Tag
+	})
+	r.OK = len(r.Discrepancies) == 0
+}
+
+// selfChecker is implemented by repos with secondary indexes that can be
+// checked against the articles themselves. Checking never modifies state;
+// Reindex repairs what it finds.
+type selfChecker interface {
+	SelfCheck(ctx context.Context) (SelfCheckReport, error)
+}
+
+// selfCheckRepo checks repo's indexes if it has any.
+func selfCheckRepo(ctx context.Context, repo ArticlesRepo) (SelfCheckReport, error) {
+	if c, ok := repo.(selfChecker); ok {
+		return c.SelfCheck(ctx)
+	}
+	report := SelfCheckReport{}
+	report.finish()
+	return report, nil
+}
+
+// SelfCheck verifies that every article is stored under its own ID,
+// indexed under each of its tags, its slug and in the insertion order, that
+// the indexes hold nothing else, and that no two articles share a slug.
+func (repo *inMemoryRepo) SelfCheck(_ context.Context) (SelfCheckReport, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	report := SelfCheckReport{Articles: len(repo.articles)}
+	slugs := make(map[string]string, len(repo.articles))
+	for id, article := range repo.articles {
+		if article.ID != id {
+			report.Discrepancies = append(report.Discrepancies, Discrepancy{
+				Kind: "id_mismatch", ID: id,
+				Detail: fmt.Sprintf("stored under %s but carries ID %s", id, article.ID),
+			})
+		}
+		for _, tag := range article.Tags {
+			if _, ok := repo.tags[tag][id]; !ok {
+				report.Discrepancies = append(report.Discrepancies, Discrepancy{
+					Kind: "missing_tag_entry", ID: id, Tag: tag,
+					Detail: "article carries the tag but the tag index doesn't list it",
+				})
+			}
+		}
+		if _, ok := repo.insertedAt[id]; !ok {
+			report.Discrepancies = append(report.Discrepancies, Discrepancy{
+				Kind: "missing_insert_seq", ID: id,
+				Detail: "article has no insertion sequence",
+			})
+		}
+		if article.Slug == "" {
+			continue
+		}
+		if _, ok := repo.slugs[article.Slug]; !ok {
+			report.Discrepancies = append(report.Discrepancies, Discrepancy{
+				Kind: "missing_slug_entry", ID: id,
+				Detail: fmt.Sprintf("article carries slug %q but the slug index doesn't list it", article.Slug),
+			})
+		}
+		if other, ok := slugs[article.Slug]; ok {
+			// Report the pair once, under the smaller ID.
+			first, second := min(id, other), max(id, other)
+			report.Discrepancies = append(report.Discrepancies, Discrepancy{
+				Kind: "duplicate_slug", ID: first,
+				Detail: fmt.Sprintf("slug %q is also used by %s", article.Slug, second),
+			})
+			continue
+		}
+		slugs[article.Slug] = id
+	}
+
+	for tag, ids := range repo.tags {
+		for id := range ids {
+			article, ok := repo.articles[id]
+			switch {
+			case !ok:
+				report.Discrepancies = append(report.Discrepancies, Discrepancy{
+					Kind: "stale_tag_entry", ID: id, Tag: tag,
+					Detail: "tag index lists an article that doesn't exist",
+				})
+			case !slices.Contains(article.Tags, tag):
+				report.Discrepancies = append(report.Discrepancies, Discrepancy{
+					Kind: "stale_tag_entry", ID: id, Tag: tag,
+					Detail: "tag index lists an article that doesn't carry the tag",
+				})
+			}
+		}
+	}
+	for slug, id := range repo.slugs {
+		if article, ok := repo.articles[id]; !ok || article.Slug != slug {
+			report.Discrepancies = append(report.Discrepancies, Discrepancy{
+				Kind: "stale_slug_entry", ID: id,
+				Detail: fmt.Sprintf("slug index lists %q for an article that doesn't carry it", slug),
+			})
+		}
+	}
+	for id := range repo.insertedAt {
+		if _, ok := repo.articles[id]; !ok {
+			report.Discrepancies = append(report.Discrepancies, Discrepancy{
+				Kind: "stale_insert_seq", ID: id,
+				Detail: "insertion sequence kept for an article that doesn't exist",
+			})
+		}
+	}
+
+	report.finish()
+	return report, nil
+}
+
+// SelfCheck checks every shard, that each article lives in the shard its ID
+// hashes to and, since slugs are global, that no two articles in different
+// shards share a slug.
+func (repo *shardedRepo) SelfCheck(ctx context.Context) (SelfCheckReport, error) {
+	var total SelfCheckReport
+	// slugs maps each slug to the shard and ID of the first article seen
+	// carrying it.
+	type owner struct {
+		shard int
+		id    string
+	}
+	slugs := make(map[string]owner)
+	for i, shard := range repo.shards {
+		report, err := shard.SelfCheck(ctx)
+		if err != nil {
+			return total, err
+		}
+		total.add(report)
+
+		shard.mu.RLock()
+		for id, article := range shard.articles {
+			if repo.shard(id) != shard {
+				total.Discrepancies = append(total.Discrepancies, Discrepancy{
+					Kind: "wrong_shard", ID: id,
+					Detail: fmt.Sprintf("stored in shard %d, which its ID doesn't hash to", i),
+				})
+			}
+			if article.Slug == "" {
+				continue
+			}
+			other, ok := slugs[article.Slug]
+			switch {
+			case !ok:
+				slugs[article.Slug] = owner{shard: i, id: id}
+			case other.shard != i:
+				// Duplicates within a shard are the shard's to report.
+				first, second := min(id, other.id), max(id, other.id)
+				total.Discrepancies = append(total.Discrepancies, Discrepancy{
+					Kind: "duplicate_slug", ID: first,
+					Detail: fmt.Sprintf("slug %q is also used by %s", article.Slug, second),
+				})
+			}
+		}
+		shard.mu.RUnlock()
+	}
+	total.finish()
+	return total, nil
+}
+
+func (repo *metricsRepo) SelfCheck(ctx context.Context) (report SelfCheckReport, err error) {
+	started := time.Now()
+	defer func() { repo.observe("selfcheck", started, err) }()
+	return selfCheckRepo(ctx, repo.next)
+}
+
+func (repo *timingRepo) SelfCheck(ctx context.Context) (SelfCheckReport, error) {
+	defer repo.track(ctx)()
+	return selfCheckRepo(ctx, repo.next)
+}
+
+// SelfCheck reports where the repo's secondary indexes disagree with the
+// articles, without changing anything. Reindex repairs what it finds.
+func (svc *articleSvc) SelfCheck(ctx context.Context) (SelfCheckReport, error) {
+	return selfCheckRepo(ctx, svc.repo)
+}
+
+// selfCheck reports index discrepancies. It answers 200 either way; the
+// report's ok field tells whether POST /admin/reindex is needed.
+func (t *adminHttpTransport) selfCheck(w http.ResponseWriter, r *http.Request) {
+	report, err := t.svc.SelfCheck(r.Context())
+	if err != nil {
+		log.Println(err)
+		w.WriteHeader(serverErrorStatus(err))
+		io.WriteString(w, errorBody(err))
+		return
+	}
+
+	writeEncoded(w, jsonEncoding{}, http.StatusOK, report)
+}
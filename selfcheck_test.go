@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"strconv"
+	"testing"
+)
+
+func TestSelfCheck(t *testing.T) {
+	tests := []struct {
+		name    string
+		corrupt func(repo *inMemoryRepo)
+		want    []Discrepancy
+	}{
+		{"consistent", func(*inMemoryRepo) {}, nil},
+		{"missing tag entry", func(repo *inMemoryRepo) { delete(repo.tags["go"], "a1") },
+			[]Discrepancy{{Kind: "missing_tag_entry", ID: "a1", Tag: "go"}}},
+		{"tag entry for an article without the tag", func(repo *inMemoryRepo) { repo.tags["go"]["a2"] = struct{}{} },
+			[]Discrepancy{{Kind: "stale_tag_entry", ID: "a2", Tag: "go"}}},
+		{"tag entry for a missing article", func(repo *inMemoryRepo) { repo.tags["go"]["gone"] = struct{}{} },
+			[]Discrepancy{{Kind: "stale_tag_entry", ID: "gone", Tag: "go"}}},
+		{"stored under another ID", func(repo *inMemoryRepo) {
+			a := repo.articles["a2"]
+			a.ID = "other"
+			repo.articles["a2"] = a
+		}, []Discrepancy{{Kind: "id_mismatch", ID: "a2"}}},
+		{"missing insert sequence", func(repo *inMemoryRepo) { delete(repo.insertedAt, "a1") },
+			[]Discrepancy{{Kind: "missing_insert_seq", ID: "a1"}}},
+		{"stale insert sequence", func(repo *inMemoryRepo) { repo.insertedAt["gone"] = 99 },
+			[]Discrepancy{{Kind: "stale_insert_seq", ID: "gone"}}},
+		{"missing slug entry", func(repo *inMemoryRepo) { delete(repo.slugs, "go") },
+			[]Discrepancy{{Kind: "missing_slug_entry", ID: "a1"}}},
+		{"slug entry for a missing article", func(repo *inMemoryRepo) { repo.slugs["gone"] = "gone" },
+			[]Discrepancy{{Kind: "stale_slug_entry", ID: "gone"}}},
+		{"duplicate slug", func(repo *inMemoryRepo) {
+			a := repo.articles["a2"]
+			a.Slug = repo.articles["a1"].Slug
+			repo.articles["a2"] = a
+		}, []Discrepancy{{Kind: "duplicate_slug", ID: "a1"}, {Kind: "stale_slug_entry", ID: "a2"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo, svc := newIndexedRepo(t)
+			tt.corrupt(repo)
+			s := newAdminTestServer(svc, false, nil)
+
+			check := func() SelfCheckReport {
+				t.Helper()
+				rec := doRequest(s, "GET", "/admin/selfcheck", "", "X-API-Key", testAdminKey)
+				if rec.Code != http.StatusOK {
+					t.Fatalf("status = %d", rec.Code)
+				}
+				var report SelfCheckReport
+				decodeJSON(t, rec, &report)
+				return report
+			}
+
+			report := check()
+			if report.Articles != 2 || report.OK != (len(tt.want) == 0) {
+				t.Errorf("report = %+v, want 2 articles and ok = %v", report, len(tt.want) == 0)
+			}
+			if got := kindsOf(report.Discrepancies); !slices.Equal(got, kindsOf(tt.want)) {
+				t.Errorf("discrepancies = %+v, want %+v", report.Discrepancies, tt.want)
+			}
+			// Checking changes nothing: the same report comes back.
+			if again := check(); !slices.Equal(kindsOf(again.Discrepancies), kindsOf(report.Discrepancies)) {
+				t.Errorf("a second check found %+v", again.Discrepancies)
+			}
+		})
+	}
+}
+
+// kindsOf reduces discrepancies to what a test can predict: everything but
+// the human readable detail.
+func kindsOf(discrepancies []Discrepancy) []Discrepancy {
+	out := make([]Discrepancy, 0, len(discrepancies))
+	for _, d := range discrepancies {
+		out = append(out, Discrepancy{Kind: d.Kind, ID: d.ID, Tag: d.Tag})
+	}
+	return out
+}
+
+func TestSelfCheckAfterReindex(t *testing.T) {
+	repo, svc := newIndexedRepo(t)
+	delete(repo.tags["go"], "a1")
+	repo.tags["rust"]["a1"] = struct{}{}
+
+	report, err := svc.SelfCheck(context.Background())
+	if err != nil || len(report.Discrepancies) != 2 {
+		t.Fatalf("before reindex: %+v, %v; want two discrepancies", report, err)
+	}
+	if _, err := svc.Reindex(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if report, err := svc.SelfCheck(context.Background()); err != nil || !report.OK {
+		t.Errorf("after reindex: %+v, %v; want ok", report, err)
+	}
+}
+
+func TestSelfCheckWrongShard(t *testing.T) {
+	repo := newShardedRepo(4)
+	svc := newArticleSvc(repo)
+	mustAdd(t, svc, Article{ID: "a1", Title: "A"})
+
+	// Move a1 to a shard its ID doesn't hash to.
+	home := repo.shard("a1")
+	var stray *inMemoryRepo
+	for _, shard := range repo.shards {
+		if shard != home {
+			stray = shard
+			break
+		}
+	}
+	stray.articles["a1"], stray.insertedAt["a1"] = home.articles["a1"], home.insertedAt["a1"]
+	stray.slugs["a"] = "a1"
+	delete(home.articles, "a1")
+	delete(home.insertedAt, "a1")
+	delete(home.slugs, "a")
+
+	report, err := svc.SelfCheck(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []Discrepancy{{Kind: "wrong_shard", ID: "a1"}}; !slices.Equal(kindsOf(report.Discrepancies), want) || report.OK {
+		t.Errorf("report = %+v, want %v", report, want)
+	}
+}
+
+func TestSelfCheckDuplicateSlugAcrossShards(t *testing.T) {
+	repo := newShardedRepo(4)
+	svc := newArticleSvc(repo)
+	mustAdd(t, svc, Article{ID: "a1", Title: "A", Slug: "one"})
+	// Find an ID that lands in another shard than a1.
+	id := "b0"
+	for i := 1; repo.shard(id) == repo.shard("a1"); i++ {
+		id = "b" + strconv.Itoa(i)
+	}
+	mustAdd(t, svc, Article{ID: id, Title: "B", Slug: "two"})
+
+	// Give the second article a1's slug, keeping its shard's index in step.
+	shard := repo.shard(id)
+	article := shard.articles[id]
+	article.Slug = "one"
+	shard.articles[id] = article
+	delete(shard.slugs, "two")
+	shard.slugs["one"] = id
+
+	report, err := svc.SelfCheck(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []Discrepancy{{Kind: "duplicate_slug", ID: min("a1", id)}}; !slices.Equal(kindsOf(report.Discrepancies), want) || report.OK {
+		t.Errorf("report = %+v, want %v", report, want)
+	}
+}
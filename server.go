@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"slices"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// serverTimeouts bounds how long a connection may spend on each phase of a
+// request, so slow or stalled clients can't hold connections open
+// indefinitely. Zero disables a timeout.
+type serverTimeouts struct {
+	readHeader time.Duration
+	read       time.Duration
+	write      time.Duration
+	idle       time.Duration
+}
+
+// newServer returns the server for handler, with the timeouts and HTTP/2
+// setting of cfg.
+func newServer(cfg config, handler http.Handler) *http.Server {
+	srv := &http.Server{
+		Addr:              cfg.addr,
+		Handler:           handler,
+		ReadHeaderTimeout: cfg.timeouts.readHeader,
+		ReadTimeout:       cfg.timeouts.read,
+		WriteTimeout:      cfg.timeouts.write,
+		IdleTimeout:       cfg.timeouts.idle,
+	}
+	if !cfg.http2 {
+		// A non-nil map stops net/http from configuring HTTP/2 on TLS
+		// listeners.
+		srv.TLSNextProto = make(map[string]func(*http.Server, *tls.Conn, http.Handler))
+	}
+	return srv
+}
+
+// listen starts serving plaintext HTTP, or HTTPS when a certificate pair or an
+// ACME domain is configured. It blocks until the server stops.
+func listen(srv *http.Server, cfg config) error {
+	switch {
+	case cfg.acmeDomain != "":
+		manager := &autocert.Manager{
+			Prompt:     autocert.AcceptTOS,
+			HostPolicy: autocert.HostWhitelist(cfg.acmeDomain),
+			Cache:      autocert.DirCache(cfg.acmeCacheDir),
+		}
+		srv.TLSConfig = manager.TLSConfig()
+		if !cfg.http2 {
+			srv.TLSConfig.NextProtos = slices.DeleteFunc(srv.TLSConfig.NextProtos, func(proto string) bool {
+				return proto == "h2"
+			})
+		}
+		return srv.ListenAndServeTLS("", "")
+	case cfg.tlsCert != "":
+		return srv.ListenAndServeTLS(cfg.tlsCert, cfg.tlsKey)
+	default:
+		return srv.ListenAndServe()
+	}
+}
+
+// serve runs srv until SIGINT or SIGTERM is received and then shuts it down
+// gracefully, giving in-flight requests up to cfg.shutdownTimeout to finish.
+// inFlight is only read, to log how many requests the shutdown waited on.
+func serve(srv *http.Server, cfg config, inFlight *atomic.Int64) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	errc := make(chan error, 1)
+	go func() {
+		errc <- listen(srv, cfg)
+	}()
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+
+	log.Printf("shutting down, draining %d in-flight requests (timeout %s)", inFlight.Load(), cfg.shutdownTimeout)
+	started := time.Now()
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.shutdownTimeout)
+	defer cancel()
+
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Printf("drain gave up after %s with %d requests still in flight", time.Since(started).Round(time.Millisecond), inFlight.Load())
+		return err
+	}
+	log.Printf("drained in %s", time.Since(started).Round(time.Millisecond))
+	if err := <-errc; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
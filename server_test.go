@@ -0,0 +1,369 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"io"
+	"log"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// writeSelfSignedCert writes a certificate for 127.0.0.1 and its key as PEM
+// files in dir and returns their paths and the parsed certificate.
+func writeSelfSignedCert(t *testing.T, dir string) (certFile, keyFile string, cert *x509.Certificate) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "quirky-thoughts test"},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cert, err = x509.ParseCertificate(der); err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certFile, keyFile = filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile, cert
+}
+
+// freeAddr returns a loopback address nothing is listening on.
+func freeAddr(t *testing.T) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	return l.Addr().String()
+}
+
+func TestListenServesTLS(t *testing.T) {
+	certFile, keyFile, cert := writeSelfSignedCert(t, t.TempDir())
+
+	cfg := testConfig()
+	cfg.addr = freeAddr(t)
+	cfg.tlsCert, cfg.tlsKey = certFile, keyFile
+	cfg.http2 = true
+	srv := newServer(cfg, okHandler)
+
+	errc := make(chan error, 1)
+	go func() { errc <- listen(srv, cfg) }()
+	defer func() {
+		srv.Close()
+		if err := <-errc; err != http.ErrServerClosed {
+			t.Errorf("listen() = %v, want http.ErrServerClosed", err)
+		}
+	}()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(cert)
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots}}}
+
+	var (
+		resp *http.Response
+		err  error
+	)
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if resp, err = client.Get("https://" + cfg.addr + "/"); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		t.Fatalf("GET over TLS: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || string(body) != "ok" {
+		t.Errorf("got %d %q, want 200 ok", resp.StatusCode, body)
+	}
+	if resp.TLS == nil {
+		t.Error("response did not arrive over TLS")
+	}
+}
+
+func TestListenHTTP2Switch(t *testing.T) {
+	certFile, keyFile, cert := writeSelfSignedCert(t, t.TempDir())
+	roots := x509.NewCertPool()
+	roots.AddCert(cert)
+
+	tests := []struct {
+		http2     bool
+		wantProto int
+	}{
+		{true, 2},
+		{false, 1},
+	}
+	for _, tt := range tests {
+		cfg := testConfig()
+		cfg.addr = freeAddr(t)
+		cfg.tlsCert, cfg.tlsKey = certFile, keyFile
+		cfg.http2 = tt.http2
+		srv := newServer(cfg, okHandler)
+		errc := make(chan error, 1)
+		go func() { errc <- listen(srv, cfg) }()
+
+		client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots}, ForceAttemptHTTP2: true}}
+		var (
+			resp *http.Response
+			err  error
+		)
+		for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+			if resp, err = client.Get("https://" + cfg.addr + "/"); err == nil {
+				break
+			}
+		}
+		if err != nil {
+			t.Fatalf("http2=%v: GET: %v", tt.http2, err)
+		}
+		resp.Body.Close()
+		if resp.ProtoMajor != tt.wantProto {
+			t.Errorf("http2=%v: served over %s, want HTTP/%d", tt.http2, resp.Proto, tt.wantProto)
+		}
+		client.CloseIdleConnections()
+		srv.Close()
+		<-errc
+	}
+}
+
+func TestSlowHeadersDisconnected(t *testing.T) {
+	cfg := testConfig()
+	cfg.addr = freeAddr(t)
+	cfg.timeouts.readHeader = 100 * time.Millisecond
+	srv := newServer(cfg, okHandler)
+	errc := make(chan error, 1)
+	go func() { errc <- listen(srv, cfg) }()
+	defer func() {
+		srv.Close()
+		<-errc
+	}()
+
+	var (
+		conn net.Conn
+		err  error
+	)
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if conn, err = net.Dial("tcp", cfg.addr); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	// Start a request and never finish its headers.
+	started := time.Now()
+	if _, err := io.WriteString(conn, "GET / HTTP/1.1\r\nHost: example.com\r\n"); err != nil {
+		t.Fatal(err)
+	}
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	io.Copy(io.Discard, conn)
+	if elapsed := time.Since(started); elapsed >= 5*time.Second {
+		t.Fatal("the server kept a connection with unfinished headers open")
+	} else if elapsed < cfg.timeouts.readHeader {
+		t.Errorf("connection closed after %v, before the %v header timeout", elapsed, cfg.timeouts.readHeader)
+	}
+}
+
+// syncBuffer is a bytes.Buffer safe for the concurrent writes of a logger.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// captureLog sends the standard logger's output to a buffer for the rest of
+// the test.
+func captureLog(t *testing.T) *syncBuffer {
+	buf := new(syncBuffer)
+	prev := log.Writer()
+	log.SetOutput(buf)
+	t.Cleanup(func() { log.SetOutput(prev) })
+	return buf
+}
+
+// waitFor polls cond until it holds or a few seconds have passed.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); !cond(); time.Sleep(5 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+	}
+}
+
+// startServe runs serve with a /slow route that blocks until release is
+// closed, and waits until it answers /healthz. It returns serve's result
+// channel and the server address.
+func startServe(t *testing.T, shutdownTimeout time.Duration, entered chan<- struct{}, release <-chan struct{}) (<-chan error, string) {
+	var inFlight atomic.Int64
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", healthz(&inFlight))
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		entered <- struct{}{}
+		<-release
+		io.WriteString(w, "done")
+	})
+
+	cfg := testConfig()
+	cfg.addr = freeAddr(t)
+	cfg.shutdownTimeout = shutdownTimeout
+	srv := newServer(cfg, inFlightMiddleware(&inFlight, mux))
+
+	errc := make(chan error, 1)
+	go func() { errc <- serve(srv, cfg, &inFlight) }()
+	t.Cleanup(func() { srv.Close() })
+
+	waitFor(t, "the server to start", func() bool {
+		resp, err := http.Get("http://" + cfg.addr + "/healthz")
+		if err != nil {
+			return false
+		}
+		resp.Body.Close()
+		return true
+	})
+	return errc, cfg.addr
+}
+
+// healthInFlight reads the in-flight count from /healthz, which counts
+// itself.
+func healthInFlight(t *testing.T, addr string) int64 {
+	t.Helper()
+	resp, err := http.Get("http://" + addr + "/healthz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var health healthStatus
+	if err := json.NewDecoder(resp.Body).Decode(&health); err != nil {
+		t.Fatal(err)
+	}
+	return health.InFlight
+}
+
+func TestServeDrainsInFlightRequests(t *testing.T) {
+	logs := captureLog(t)
+	entered, release := make(chan struct{}, 1), make(chan struct{})
+	errc, addr := startServe(t, 5*time.Second, entered, release)
+
+	type result struct {
+		status int
+		body   string
+		err    error
+	}
+	slow := make(chan result, 1)
+	go func() {
+		resp, err := http.Get("http://" + addr + "/slow")
+		if err != nil {
+			slow <- result{err: err}
+			return
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		slow <- result{resp.StatusCode, string(body), err}
+	}()
+	<-entered
+
+	if n := healthInFlight(t, addr); n != 2 {
+		t.Errorf("/healthz inFlight = %d, want 2 (the slow request and itself)", n)
+	}
+
+	if err := syscall.Kill(os.Getpid(), syscall.SIGTERM); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "shutdown to start", func() bool {
+		return strings.Contains(logs.String(), "draining 1 in-flight requests (timeout 5s)")
+	})
+	select {
+	case err := <-errc:
+		t.Fatalf("serve returned %v while a request was in flight", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	if r := <-slow; r.err != nil || r.status != http.StatusOK || r.body != "done" {
+		t.Errorf("in-flight request got %d %q, %v; want 200 done", r.status, r.body, r.err)
+	}
+	if err := <-errc; err != nil {
+		t.Errorf("serve() = %v, want nil", err)
+	}
+	if !strings.Contains(logs.String(), "drained in ") {
+		t.Errorf("no drain time logged:\n%s", logs)
+	}
+}
+
+func TestServeDrainTimeout(t *testing.T) {
+	logs := captureLog(t)
+	entered, release := make(chan struct{}, 1), make(chan struct{})
+	defer close(release)
+	errc, addr := startServe(t, 50*time.Millisecond, entered, release)
+
+	go func() {
+		if resp, err := http.Get("http://" + addr + "/slow"); err == nil {
+			resp.Body.Close()
+		}
+	}()
+	<-entered
+
+	if err := syscall.Kill(os.Getpid(), syscall.SIGTERM); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-errc; !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("serve() = %v, want context.DeadlineExceeded", err)
+	}
+	if !strings.Contains(logs.String(), "with 1 requests still in flight") {
+		t.Errorf("no timeout logged:\n%s", logs)
+	}
+}
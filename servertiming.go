@@ -0,0 +1,184 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// requestTiming accumulates the time a request spends in repository calls.
+type requestTiming struct {
+	started time.Time
+	repo    atomic.Int64 // nanoseconds
+}
+
+type requestTimingKey struct{}
+
+// header formats the Server-Timing value: time in the repo and time in the
+// handler so far, both in milliseconds.
+func (t *requestTiming) header() string {
+	repo := time.Duration(t.repo.Load())
+	return fmt.Sprintf("repo;dur=%.3f, total;dur=%.3f", ms(repo), ms(time.Since(t.started)))
+}
+
+func ms(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// serverTimingMiddleware reports in a Server-Timing header how long the
+// request spent in repository calls and in total, measured when the
+// response headers are written. Repo time is only seen when the repo is
+// wrapped in a timingRepo.
+func serverTimingMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		timing := &requestTiming{started: time.Now()}
+		ctx := context.WithValue(r.Context(), requestTimingKey{}, timing)
+		next.ServeHTTP(&serverTimingWriter{ResponseWriter: w, timing: timing}, r.WithContext(ctx))
+	})
+}
+
+// serverTimingWriter adds the Server-Timing header just before the response
+// headers go out. Like statusRecorder, it passes Flush and Hijack through.
+type serverTimingWriter struct {
+	http.ResponseWriter
+	timing      *requestTiming
+	wroteHeader bool
+}
+
+func (w *serverTimingWriter) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		w.Header().Set("Server-Timing", w.timing.header())
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *serverTimingWriter) Write(p []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+func (w *serverTimingWriter) Flush() {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (w *serverTimingWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("response does not support hijacking")
+	}
+	return hijacker.Hijack()
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (w *serverTimingWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// timingRepo decorates an ArticlesRepo to add the duration of every call to
+// the Server-Timing of the request in its context. Calls outside a timed
+// request pass straight through. EachArticle's time includes its callback.
+type timingRepo struct {
+	next ArticlesRepo
+}
+
+func newTimingRepo(next ArticlesRepo) *timingRepo {
+	return &timingRepo{next: next}
+}
+
+// track starts timing a call; the returned function stops it.
+func (repo *timingRepo) track(ctx context.Context) func() {
+	timing, ok := ctx.Value(requestTimingKey{}).(*requestTiming)
+	if !ok {
+		return func() {}
+	}
+	started := time.Now()
+	return func() { timing.repo.Add(int64(time.Since(started))) }
+}
+
+func (repo *timingRepo) InsertArticle(ctx context.Context, article Article) error {
+	defer repo.track(ctx)()
+	return repo.next.InsertArticle(ctx, article)
+}
+
+func (repo *timingRepo) UpdateArticle(ctx context.Context, article Article) error {
+	defer repo.track(ctx)()
+	return repo.next.UpdateArticle(ctx, article)
+}
+
+func (repo *timingRepo) DeleteArticle(ctx context.Context, id string) error {
+	defer repo.track(ctx)()
+	return repo.next.DeleteArticle(ctx, id)
+}
+
+func (repo *timingRepo) ArticleByID(ctx context.Context, id string) (*Article, error) {
+	defer repo.track(ctx)()
+	return repo.next.ArticleByID(ctx, id)
+}
+
+func (repo *timingRepo) ArticleBySlug(ctx context.Context, slug string) (*Article, error) {
+	defer repo.track(ctx)()
+	return repo.next.ArticleBySlug(ctx, slug)
+}
+
+func (repo *timingRepo) AllArticles(ctx context.Context) ([]Article, error) {
+	defer repo.track(ctx)()
+	return repo.next.AllArticles(ctx)
+}
+
+func (repo *timingRepo) ArticlesByTitle(ctx context.Context, title string) ([]Article, error) {
+	defer repo.track(ctx)()
+	return repo.next.ArticlesByTitle(ctx, title)
+}
+
+func (repo *timingRepo) ArticlesByTags(ctx context.Context, tags []string) ([]Article, error) {
+	defer repo.track(ctx)()
+	return repo.next.ArticlesByTags(ctx, tags)
+}
+
+func (repo *timingRepo) ArticlesByAnyTag(ctx context.Context, tags []string) ([]Article, error) {
+	defer repo.track(ctx)()
+	return repo.next.ArticlesByAnyTag(ctx, tags)
+}
+
+func (repo *timingRepo) TagFrequency(ctx context.Context) (map[string]int, error) {
+	defer repo.track(ctx)()
+	return repo.next.TagFrequency(ctx)
+}
+
+func (repo *timingRepo) SuggestTags(ctx context.Context, prefix string, limit int) ([]TagSuggestion, error) {
+	defer repo.track(ctx)()
+	return repo.next.SuggestTags(ctx, prefix, limit)
+}
+
+func (repo *timingRepo) ArticlesInRange(ctx context.Context, from, to time.Time) ([]Article, error) {
+	defer repo.track(ctx)()
+	return repo.next.ArticlesInRange(ctx, from, to)
+}
+
+func (repo *timingRepo) EachArticle(ctx context.Context, fn func(Article) error) error {
+	defer repo.track(ctx)()
+	return repo.next.EachArticle(ctx, fn)
+}
+
+func (repo *timingRepo) Clear(ctx context.Context) (int, error) {
+	defer repo.track(ctx)()
+	return repo.next.Clear(ctx)
+}
+
+func (repo *timingRepo) Reindex(ctx context.Context) (ReindexSummary, error) {
+	defer repo.track(ctx)()
+	return reindexRepo(ctx, repo.next)
+}
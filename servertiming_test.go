@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// slowRepo is an inMemoryRepo whose ArticleByID takes at least delay.
+type slowRepo struct {
+	*inMemoryRepo
+	delay time.Duration
+}
+
+func (repo slowRepo) ArticleByID(ctx context.Context, id string) (*Article, error) {
+	time.Sleep(repo.delay)
+	return repo.inMemoryRepo.ArticleByID(ctx, id)
+}
+
+var serverTimingPattern = regexp.MustCompile(`^repo;dur=(\d+\.\d{3}), total;dur=(\d+\.\d{3})$`)
+
+// parseServerTiming returns the repo and total durations of a Server-Timing
+// header, failing the test when it is malformed.
+func parseServerTiming(t *testing.T, header string) (repo, total time.Duration) {
+	t.Helper()
+	m := serverTimingPattern.FindStringSubmatch(header)
+	if m == nil {
+		t.Fatalf("Server-Timing = %q, want repo;dur=<ms>, total;dur=<ms>", header)
+	}
+	parse := func(s string) time.Duration {
+		ms, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return time.Duration(ms * float64(time.Millisecond))
+	}
+	return parse(m[1]), parse(m[2])
+}
+
+func TestServerTiming(t *testing.T) {
+	const delay = 20 * time.Millisecond
+	svc := newArticleSvc(newTimingRepo(slowRepo{newInMemoryRepo(), delay}))
+	mustAdd(t, svc, Article{ID: "a1", Title: "A"})
+	h := serverTimingMiddleware(newTestHandler(svc))
+
+	tests := []struct {
+		target  string
+		want    int
+		minRepo time.Duration
+	}{
+		{"/articles/a1", http.StatusOK, delay},
+		{"/articles/missing", http.StatusNotFound, delay},
+		// Listing doesn't go through the slow call.
+		{"/articles", http.StatusOK, 0},
+	}
+	for _, tt := range tests {
+		rec := doRequest(h, "GET", tt.target, "")
+		if rec.Code != tt.want {
+			t.Errorf("%s: status = %d, want %d", tt.target, rec.Code, tt.want)
+		}
+		repo, total := parseServerTiming(t, rec.Header().Get("Server-Timing"))
+		if repo < tt.minRepo || total < repo {
+			t.Errorf("%s: repo %v, total %v; want repo >= %v and total >= repo", tt.target, repo, total, tt.minRepo)
+		}
+	}
+
+	if got := doRequest(newTestHandler(svc), "GET", "/articles/a1", "").Header().Get("Server-Timing"); got != "" {
+		t.Errorf("without the middleware Server-Timing = %q", got)
+	}
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"hash/fnv"
+	"time"
+)
+
+// shardedRepo partitions articles across several inMemoryRepo shards keyed by
+// a hash of the article ID, so writes to different articles rarely contend on
+// the same lock. Operations spanning every article lock all shards.
+type shardedRepo struct {
+	shards []*inMemoryRepo
+}
+
+// newShardedRepo creates a repo with n shards, each built with opts. Any
+// capacity given through withCapacity applies per shard.
+func newShardedRepo(n int, opts ...inMemoryRepoOption) *shardedRepo {
+	if n < 1 {
+		n = 1
+	}
+
+	repo := &shardedRepo{shards: make([]*inMemoryRepo, n)}
+	for i := range repo.shards {
+		repo.shards[i] = newInMemoryRepo(opts...)
+	}
+	return repo
+}
+
+func (repo *shardedRepo) shard(id string) *inMemoryRepo {
+	h := fnv.New32a()
+	h.Write([]byte(id))
+	return repo.shards[h.Sum32()%uint32(len(repo.shards))]
+}
+
+func (repo *shardedRepo) InsertArticle(ctx context.Context, article Article) error {
+	return repo.shard(article.ID).InsertArticle(ctx, article)
+}
+
+func (repo *shardedRepo) UpdateArticle(ctx context.Context, article Article) error {
+	return repo.shard(article.ID).UpdateArticle(ctx, article)
+}
+
+func (repo *shardedRepo) DeleteArticle(ctx context.Context, id string) error {
+	return repo.shard(id).DeleteArticle(ctx, id)
+}
+
+func (repo *shardedRepo) ArticleByID(ctx context.Context, id string) (*Article, error) {
+	return repo.shard(id).ArticleByID(ctx, id)
+}
+
+// ArticleBySlug asks every shard, since slugs are unique across all of
+// them but an article lives in the shard of its ID.
+func (repo *shardedRepo) ArticleBySlug(ctx context.Context, slug string) (*Article, error) {
+	for _, shard := range repo.shards {
+		article, err := shard.ArticleBySlug(ctx, slug)
+		if !errors.Is(err, ErrArticleNotFound) {
+			return article, err
+		}
+	}
+	return nil, ErrArticleNotFound
+}
+
+// AllArticles read-locks every shard before copying so the result is a
+// consistent snapshot across shards.
+func (repo *shardedRepo) AllArticles(_ context.Context) ([]Article, error) {
+	total := 0
+	for _, shard := range repo.shards {
+		shard.mu.RLock()
+		defer shard.mu.RUnlock()
+		total += len(shard.articles)
+	}
+
+	articles := make([]Article, 0, total)
+	for _, shard := range repo.shards {
+		for _, article := range shard.articles {
+			articles = append(articles, article)
+		}
+	}
+	return articles, nil
+}
+
+func (repo *shardedRepo) ArticlesByTitle(ctx context.Context, title string) ([]Article, error) {
+	var articles []Article
+	for _, shard := range repo.shards {
+		matches, err := shard.ArticlesByTitle(ctx, title)
+		if err != nil {
+			return nil, err
+		}
+		articles = append(articles, matches...)
+	}
+	return articles, nil
+}
+
+func (repo *shardedRepo) ArticlesByTags(ctx context.Context, tags []string) ([]Article, error) {
+	var articles []Article
+	for _, shard := range repo.shards {
+		matches, err := shard.ArticlesByTags(ctx, tags)
+		if err != nil {
+			return nil, err
+		}
+		articles = append(articles, matches...)
+	}
+	return articles, nil
+}
+
+// ArticlesByAnyTag needs no deduplication across shards, since every
+// article lives in exactly one.
+func (repo *shardedRepo) ArticlesByAnyTag(ctx context.Context, tags []string) ([]Article, error) {
+	var articles []Article
+	for _, shard := range repo.shards {
+		matches, err := shard.ArticlesByAnyTag(ctx, tags)
+		if err != nil {
+			return nil, err
+		}
+		articles = append(articles, matches...)
+	}
+	return articles, nil
+}
+
+// TagFrequency adds up the counts of every shard.
+func (repo *shardedRepo) TagFrequency(ctx context.Context) (map[string]int, error) {
+	counts := make(map[string]int)
+	for _, shard := range repo.shards {
+		shardCounts, err := shard.TagFrequency(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for tag, n := range shardCounts {
+			counts[tag] += n
+		}
+	}
+	return counts, nil
+}
+
+// SuggestTags merges every match of every shard before ranking, since a tag
+// can be spread over several shards.
+func (repo *shardedRepo) SuggestTags(ctx context.Context, prefix string, limit int) ([]TagSuggestion, error) {
+	counts := make(map[string]int)
+	for _, shard := range repo.shards {
+		suggestions, err := shard.SuggestTags(ctx, prefix, 0)
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range suggestions {
+			counts[s.Tag] += s.Count
+		}
+	}
+	return rankTagSuggestions(counts, limit), nil
+}
+
+func (repo *shardedRepo) ArticlesInRange(ctx context.Context, from, to time.Time) ([]Article, error) {
+	var articles []Article
+	for _, shard := range repo.shards {
+		matches, err := shard.ArticlesInRange(ctx, from, to)
+		if err != nil {
+			return nil, err
+		}
+		articles = append(articles, matches...)
+	}
+	return articles, nil
+}
+
+func (repo *shardedRepo) EachArticle(ctx context.Context, fn func(Article) error) error {
+	for _, shard := range repo.shards {
+		if err := shard.EachArticle(ctx, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (repo *shardedRepo) Clear(ctx context.Context) (int, error) {
+	removed := 0
+	for _, shard := range repo.shards {
+		n, err := shard.Clear(ctx)
+		if err != nil {
+			return removed, err
+		}
+		removed += n
+	}
+	return removed, nil
+}
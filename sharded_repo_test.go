@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"strconv"
+	"sync/atomic"
+	"testing"
+)
+
+// lockingRepos are the repos the concurrency benchmarks compare.
+var lockingRepos = []struct {
+	name string
+	new  func() ArticlesRepo
+}{
+	{name: "single-lock", new: func() ArticlesRepo { return newInMemoryRepo() }},
+	{name: "sharded-16", new: func() ArticlesRepo { return newShardedRepo(16) }},
+}
+
+// BenchmarkConcurrentInserts compares one inMemoryRepo, behind a single
+// lock, with a shardedRepo when many goroutines insert at once.
+func BenchmarkConcurrentInserts(b *testing.B) {
+	for _, r := range lockingRepos {
+		b.Run(r.name, func(b *testing.B) {
+			repo := r.new()
+			ctx := context.Background()
+			var next atomic.Int64
+			b.ReportAllocs()
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					article := benchArticle(int(next.Add(1)))
+					if err := repo.InsertArticle(ctx, article); err != nil {
+						b.Error(err)
+						return
+					}
+				}
+			})
+		})
+	}
+}
+
+// BenchmarkConcurrentUpdates is BenchmarkConcurrentInserts for updates of a
+// fixed set of articles, so map growth doesn't dominate.
+func BenchmarkConcurrentUpdates(b *testing.B) {
+	const articles = 10000
+	for _, r := range lockingRepos {
+		b.Run(r.name, func(b *testing.B) {
+			repo := r.new()
+			ctx := context.Background()
+			for i := 0; i < articles; i++ {
+				if err := repo.InsertArticle(ctx, benchArticle(i)); err != nil {
+					b.Fatal(err)
+				}
+			}
+			var next atomic.Int64
+			b.ReportAllocs()
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					article := benchArticle(int(next.Add(1) % articles))
+					article.Title = "Updated " + strconv.Itoa(int(next.Load()))
+					if err := repo.UpdateArticle(ctx, article); err != nil {
+						b.Error(err)
+						return
+					}
+				}
+			})
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// PreviousArticle returns the published article immediately before id in
+// PublishAt order, or nil when id is the first one.
+func (svc *articleSvc) PreviousArticle(ctx context.Context, id string, exclude []string) (*Article, error) {
+	return svc.adjacentArticle(ctx, id, false, exclude)
+}
+
+// NextArticle returns the published article immediately after id in
+// PublishAt order, or nil when id is the last one.
+func (svc *articleSvc) NextArticle(ctx context.Context, id string, exclude []string) (*Article, error) {
+	return svc.adjacentArticle(ctx, id, true, exclude)
+}
+
+// adjacentArticle finds the neighbour of id among the published articles
+// that are already out and carry none of the exclude tags, ordered by
+// PublishAt with ties broken by ID. An article without a PublishAt has no
+// place in that order and so no neighbours.
+func (svc *articleSvc) adjacentArticle(ctx context.Context, id string, after bool, exclude []string) (*Article, error) {
+	ref, err := svc.repo.ArticleByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if ref.PublishAt.IsZero() {
+		return nil, nil
+	}
+
+	articles, err := svc.repo.ArticlesInRange(ctx, time.Time{}, svc.clock.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	var best *Article
+	for i := range articles {
+		candidate := &articles[i]
+		if candidate.Status != StatusPublished || candidate.ID == ref.ID || restricted(*candidate, exclude) {
+			continue
+		}
+		if after {
+			if publishedBefore(*ref, *candidate) && (best == nil || publishedBefore(*candidate, *best)) {
+				best = candidate
+			}
+		} else if publishedBefore(*candidate, *ref) && (best == nil || publishedBefore(*best, *candidate)) {
+			best = candidate
+		}
+	}
+	return best, nil
+}
+
+// publishedBefore orders articles by PublishAt, oldest first, and by ID on
+// ties.
+func publishedBefore(a, b Article) bool {
+	if !a.PublishAt.Equal(b.PublishAt) {
+		return a.PublishAt.Before(b.PublishAt)
+	}
+	return a.ID < b.ID
+}
+
+// siblingsResponse holds the neighbours of an article; either is null at the
+// ends of the range.
+type siblingsResponse struct {
+	Previous *Article `json:"previous"`
+	Next     *Article `json:"next"`
+}
+
+// articleSiblings serves the previous and next published articles around
+// {id}, for post navigation. Neighbours the caller may not read are skipped.
+func (t *articlesHttpTransport) articleSiblings(w http.ResponseWriter, r *http.Request) {
+	articleID := mux.Vars(r)["id"]
+	denied := t.deniedTags(r)
+	article, err := t.requestedArticle(r, articleID)
+	if err == nil && t.hidesDraft(r, *article) {
+		err = ErrArticleNotFound
+	}
+	if err == nil && restricted(*article, denied) {
+		t.writeRestricted(w, articleID)
+		return
+	}
+
+	var resp siblingsResponse
+	if err == nil {
+		resp.Previous, err = t.svc.PreviousArticle(r.Context(), articleID, denied)
+	}
+	if err == nil {
+		resp.Next, err = t.svc.NextArticle(r.Context(), articleID, denied)
+	}
+	if errors.Is(err, ErrArticleNotFound) {
+		writeArticleNotFound(w, articleID)
+		return
+	}
+	if err != nil {
+		log.Println(err)
+		w.WriteHeader(serverErrorStatus(err))
+		io.WriteString(w, errorBody(err))
+		return
+	}
+
+	for _, sibling := range []*Article{resp.Previous, resp.Next} {
+		if sibling != nil {
+			*sibling = sibling.withExcerpt()
+		}
+	}
+	t.writeJSON(w, r, http.StatusOK, resp)
+}
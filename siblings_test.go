@@ -0,0 +1,67 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestArticleSiblings(t *testing.T) {
+	clock := newFakeClock(time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC))
+	base := clock.Now().Add(-24 * time.Hour)
+	svc := newTestSvc(withClock(clock))
+	mustAdd(t, svc,
+		Article{ID: "p1", Title: "One", Status: StatusPublished, PublishAt: base},
+		Article{ID: "d1", Title: "Draft", Status: StatusDraft, PublishAt: base.Add(30 * time.Minute)},
+		// p2 and p3 share a PublishAt and are ordered by ID.
+		Article{ID: "p3", Title: "Three", Status: StatusPublished, PublishAt: base.Add(time.Hour)},
+		Article{ID: "p2", Title: "Two", Status: StatusPublished, PublishAt: base.Add(time.Hour)},
+		Article{ID: "p4", Title: "Four", Status: StatusPublished, PublishAt: base.Add(3 * time.Hour)},
+		Article{ID: "f1", Title: "Future", Status: StatusPublished, PublishAt: clock.Now().Add(time.Hour)},
+		Article{ID: "n1", Title: "Undated draft", Status: StatusDraft},
+	)
+	h := newTestHandler(svc)
+
+	tests := []struct {
+		id                     string
+		want                   int
+		wantPrevious, wantNext string
+	}{
+		{"p1", http.StatusOK, "", "p2"},
+		{"p2", http.StatusOK, "p1", "p3"},
+		{"p3", http.StatusOK, "p2", "p4"},
+		{"p4", http.StatusOK, "p3", ""},
+		// Drafts and articles not yet out are nobody's neighbours but
+		// still have their own.
+		{"d1", http.StatusOK, "p1", "p2"},
+		{"f1", http.StatusOK, "p4", ""},
+		{"n1", http.StatusOK, "", ""},
+		{"missing", http.StatusNotFound, "", ""},
+	}
+	for _, tt := range tests {
+		rec := doRequest(h, "GET", "/articles/"+tt.id+"/siblings", "")
+		if rec.Code != tt.want {
+			t.Errorf("%s: status = %d, want %d", tt.id, rec.Code, tt.want)
+			continue
+		}
+		if rec.Code != http.StatusOK {
+			continue
+		}
+		var resp siblingsResponse
+		decodeJSON(t, rec, &resp)
+		if got := siblingID(resp.Previous); got != tt.wantPrevious {
+			t.Errorf("%s: previous = %q, want %q", tt.id, got, tt.wantPrevious)
+		}
+		if got := siblingID(resp.Next); got != tt.wantNext {
+			t.Errorf("%s: next = %q, want %q", tt.id, got, tt.wantNext)
+		}
+	}
+
+	// Once f1 is out it joins the order.
+	clock.Advance(2 * time.Hour)
+	var resp siblingsResponse
+	decodeJSON(t, doRequest(h, "GET", "/articles/p4/siblings", ""), &resp)
+	if got := siblingID(resp.Next); got != "f1" {
+		t.Errorf("after f1 is published, next of p4 = %q, want f1", got)
+	}
+}
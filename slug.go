@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// slugify is the default slug strategy. It lowercases title, strips accents
+// from Latin letters and joins the remaining ASCII letters and digits with
+// single dashes; anything else, including non-Latin scripts, separates
+// words. It may return "".
+func slugify(title string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range norm.NFD.String(strings.ToLower(title)) {
+		switch {
+		case unicode.Is(unicode.Mn, r):
+			// A combining accent left over from decomposition.
+		case r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)):
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			dash = false
+		default:
+			dash = true
+		}
+	}
+	return b.String()
+}
+
+// withSlugFunc replaces slugify as the strategy that turns titles into
+// slugs, e.g. to transliterate non-Latin scripts. Whatever it returns is
+// made unique by the service; an empty result falls back to the ID.
+func withSlugFunc(fn func(title string) string) svcOption {
+	return func(svc *articleSvc) {
+		svc.slugFunc = fn
+	}
+}
+
+// ErrSlugConflict is returned in strict slug mode when the slug generated
+// from an article's title belongs to another article.
+var ErrSlugConflict = errors.New("the title produces a slug that is already in use; choose a distinct title or slug")
+
+// withStrictSlugs rejects new articles whose generated slug is taken with
+// ErrSlugConflict, instead of making it unique with a numeric suffix.
+func withStrictSlugs(strict bool) svcOption {
+	return func(svc *articleSvc) {
+		svc.strictSlugs = strict
+	}
+}
+
+// assignSlug returns the slug to store for article. A slug the client chose
+// must not belong to another article; a generated one gets a numeric suffix
+// until it is unique, or fails with ErrSlugConflict in strict mode. Slugs are
+// looked up in the repo's slug index. svc.changes.mu must be held, so no
+// other write can take the slug in the meantime.
+func (svc *articleSvc) assignSlug(ctx context.Context, article Article) (string, error) {
+	if article.Slug != "" {
+		taken, err := svc.slugTaken(ctx, article.Slug, article.ID)
+		if err != nil {
+			return "", err
+		}
+		if taken {
+			return "", &ValidationError{Fields: []FieldError{{Field: "slug", Message: "is already in use"}}}
+		}
+		return article.Slug, nil
+	}
+
+	base := svc.slugFunc(article.Title)
+	if base == "" {
+		base = slugify(article.ID)
+	}
+	if base == "" {
+		base = "article"
+	}
+
+	slug := base
+	for i := 2; ; i++ {
+		taken, err := svc.slugTaken(ctx, slug, article.ID)
+		if err != nil {
+			return "", err
+		}
+		if !taken {
+			return slug, nil
+		}
+		if svc.strictSlugs {
+			return "", fmt.Errorf("%w: %s", ErrSlugConflict, base)
+		}
+		slug = fmt.Sprintf("%s-%d", base, i)
+	}
+}
+
+// slugTaken reports whether slug belongs to an article other than id.
+func (svc *articleSvc) slugTaken(ctx context.Context, slug, id string) (bool, error) {
+	other, err := svc.repo.ArticleBySlug(ctx, slug)
+	if errors.Is(err, ErrArticleNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return other.ID != id, nil
+}
+
+// validSlug reports whether slug can be used in a URL path segment.
+func validSlug(slug string) bool {
+	return !strings.ContainsFunc(slug, func(r rune) bool {
+		return unicode.IsSpace(r) || r == '/' || r == '?' || r == '#'
+	})
+}
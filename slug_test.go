@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"unicode"
+)
+
+// transliterations is a tiny romanization table standing in for a real
+// transliteration library.
+var transliterations = map[rune]string{
+	'ü': "ue", 'ß': "ss", 'é': "e", 'è': "e", 'û': "u", 'ñ': "n",
+	'北': "bei", '京': "jing", '東': "to", '日': "ni", '本': "hon",
+}
+
+// transliterate romanizes title word by word, dropping what it can't map.
+func transliterate(title string) string {
+	var parts []string
+	for _, word := range strings.Fields(strings.ToLower(title)) {
+		var b strings.Builder
+		flush := func() {
+			if b.Len() > 0 {
+				parts = append(parts, b.String())
+				b.Reset()
+			}
+		}
+		for _, r := range word {
+			switch {
+			case unicode.Is(unicode.Han, r):
+				// Each Han character is a syllable of its own.
+				flush()
+				b.WriteString(transliterations[r])
+				flush()
+			case transliterations[r] != "":
+				b.WriteString(transliterations[r])
+			case r >= 'a' && r <= 'z' || r >= '0' && r <= '9':
+				b.WriteRune(r)
+			}
+		}
+		flush()
+	}
+	return strings.Join(parts, "-")
+}
+
+func TestSlugify(t *testing.T) {
+	tests := []struct {
+		title, want string
+	}{
+		{"Hello, World!", "hello-world"},
+		{"  Leading and trailing  ", "leading-and-trailing"},
+		{"Crème brûlée", "creme-brulee"},
+		// Decomposition drops accents but can't rewrite ü or ß the German way.
+		{"Über Straße", "uber-stra-e"},
+		{"北京", ""},
+		{"Go 1.23 と 北京", "go-1-23"},
+	}
+	for _, tt := range tests {
+		if got := slugify(tt.title); got != tt.want {
+			t.Errorf("slugify(%q) = %q, want %q", tt.title, got, tt.want)
+		}
+	}
+}
+
+func TestSlugFunc(t *testing.T) {
+	tests := []struct {
+		name   string
+		titles []string
+		want   []string
+	}{
+		{"accented", []string{"Über Straße"}, []string{"ueber-strasse"}},
+		{"accented duplicates", []string{"Crème Brûlée", "Crème brûlée!"}, []string{"creme-brulee", "creme-brulee-2"}},
+		{"CJK", []string{"北京"}, []string{"bei-jing"}},
+		{"CJK duplicates", []string{"東京", "東京", "東京"}, []string{"to-jing", "to-jing-2", "to-jing-3"}},
+		{"mixed scripts", []string{"日本 niño"}, []string{"ni-hon-nino"}},
+		{"nothing to transliterate", []string{"ひらがな"}, []string{"a0"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := newTestSvc(withSlugFunc(transliterate))
+			for i, title := range tt.titles {
+				id := "a" + strconv.Itoa(i)
+				mustAdd(t, svc, Article{ID: id, Title: title})
+				if got := mustGet(t, svc, id).Slug; got != tt.want[i] {
+					t.Errorf("slug for %q = %q, want %q", title, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestSlugFuncStrict(t *testing.T) {
+	svc := newTestSvc(withSlugFunc(transliterate), withStrictSlugs(true))
+	mustAdd(t, svc, Article{ID: "a1", Title: "北京"})
+
+	rec := doRequest(newTestHandler(svc), "PUT", "/articles", `{"id":"a2","title":"北京"}`)
+	if rec.Code != http.StatusConflict {
+		t.Errorf("status = %d, want 409 for a transliterated slug that is taken", rec.Code)
+	}
+}
+
+func TestSlugFuncNonASCIIResult(t *testing.T) {
+	// A strategy may keep non-Latin scripts; the slug is unique all the same.
+	keep := func(title string) string { return strings.Join(strings.Fields(title), "-") }
+	svc := newTestSvc(withSlugFunc(keep))
+	mustAdd(t, svc,
+		Article{ID: "a1", Title: "東京 タワー"},
+		Article{ID: "a2", Title: "東京  タワー"},
+	)
+	if got := mustGet(t, svc, "a1").Slug; got != "東京-タワー" {
+		t.Errorf("first slug = %q", got)
+	}
+	if got := mustGet(t, svc, "a2").Slug; got != "東京-タワー-2" {
+		t.Errorf("second slug = %q", got)
+	}
+}
+
+func TestSlugUniqueness(t *testing.T) {
+	svc := newTestSvc()
+	mustAdd(t, svc,
+		Article{ID: "a1", Title: "Hello"},
+		Article{ID: "a2", Title: "Hello"},
+		Article{ID: "a3", Title: "Other", Slug: "custom"},
+	)
+	h := newTestHandler(svc)
+
+	steps := []struct {
+		name, method, target, body string
+		want                       int
+	}{
+		{"client slug taken", "PUT", "/articles", `{"id":"b1","title":"T","slug":"hello"}`, http.StatusUnprocessableEntity},
+		{"update to a taken slug", "PUT", "/articles/a3", `{"title":"Other","slug":"hello-2"}`, http.StatusUnprocessableEntity},
+		{"update keeps its own slug", "PUT", "/articles/a1", `{"title":"Hello again","slug":"hello"}`, http.StatusOK},
+		{"delete frees the slug", "DELETE", "/articles/a3", "", http.StatusNoContent},
+		{"freed slug reused", "PUT", "/articles", `{"id":"b2","title":"T","slug":"custom"}`, http.StatusCreated},
+	}
+	for _, step := range steps {
+		if rec := doRequest(h, step.method, step.target, step.body); rec.Code != step.want {
+			t.Fatalf("%s: status = %d, want %d: %s", step.name, rec.Code, step.want, rec.Body)
+		}
+	}
+
+	for id, want := range map[string]string{"a1": "hello", "a2": "hello-2", "b2": "custom"} {
+		if got := mustGet(t, svc, id).Slug; got != want {
+			t.Errorf("%s: slug = %q, want %q", id, got, want)
+		}
+	}
+}
+
+// noScanRepo fails EachArticle and AllArticles, so writes that scan every
+// article to find a free slug show up as errors.
+type noScanRepo struct {
+	*inMemoryRepo
+}
+
+func (noScanRepo) EachArticle(context.Context, func(Article) error) error {
+	return errors.New("scanned every article")
+}
+
+func (noScanRepo) AllArticles(context.Context) ([]Article, error) {
+	return nil, errors.New("scanned every article")
+}
+
+func TestSlugAssignedFromIndex(t *testing.T) {
+	svc := newArticleSvc(noScanRepo{newInMemoryRepo()})
+	for i := 0; i < 3; i++ {
+		mustAdd(t, svc, Article{ID: "a" + strconv.Itoa(i), Title: "Same"})
+	}
+	if err := svc.UpdateArticle(context.Background(), Article{ID: "a0", Title: "Same", Slug: "renamed"}); err != nil {
+		t.Fatal(err)
+	}
+	for id, want := range map[string]string{"a0": "renamed", "a1": "same-2", "a2": "same-3"} {
+		if got := mustGet(t, svc, id).Slug; got != want {
+			t.Errorf("%s: slug = %q, want %q", id, got, want)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"errors"
+	"io/fs"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+)
+
+// staticMaxAge is how long browsers may cache files served from -static-dir
+// before revalidating them against Last-Modified.
+const staticMaxAge = time.Hour
+
+// staticHandler serves the files under dir. Directories are only served
+// through their index.html; listings are never generated.
+func staticHandler(dir string) http.Handler {
+	files := http.FileServer(noListingFS{http.Dir(dir)})
+	cacheControl := "public, max-age=" + strconv.Itoa(int(staticMaxAge/time.Second))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		files.ServeHTTP(&staticCacheWriter{ResponseWriter: w, cacheControl: cacheControl}, r)
+	})
+}
+
+// staticCacheWriter adds Cache-Control to successful responses only, so a
+// missing file doesn't stay cached once it is deployed.
+type staticCacheWriter struct {
+	http.ResponseWriter
+	cacheControl string
+	wroteHeader  bool
+}
+
+func (w *staticCacheWriter) WriteHeader(status int) {
+	if !w.wroteHeader && status < http.StatusBadRequest {
+		w.Header().Set("Cache-Control", w.cacheControl)
+	}
+	w.wroteHeader = true
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *staticCacheWriter) Write(p []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+// noListingFS hides directories that have no index.html, so the file server
+// answers them with 404 instead of a listing.
+type noListingFS struct {
+	fs http.FileSystem
+}
+
+func (n noListingFS) Open(name string) (http.File, error) {
+	f, err := n.fs.Open(name)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	if info.IsDir() {
+		index, err := n.fs.Open(name + "/index.html")
+		if err != nil {
+			f.Close()
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil, os.ErrNotExist
+			}
+			return nil, err
+		}
+		index.Close()
+	}
+	return f, nil
+}
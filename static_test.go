@@ -0,0 +1,64 @@
+package main
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+func TestStaticHandler(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"app.js":          "console.log('hi')",
+		"docs/index.html": "<h1>Docs</h1>",
+		"img/logo.svg":    "<svg/>",
+	} {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Mount it the way main does, next to the API.
+	root := mux.NewRouter()
+	newArticlesHttpTransport(newTestSvc()).setupRoutes(root.PathPrefix("/articles").Subrouter())
+	root.PathPrefix("/static/").Handler(http.StripPrefix("/static", staticHandler(dir))).Methods("GET", "HEAD")
+
+	tests := []struct {
+		method, target string
+		want           int
+		body           string
+		cached         bool
+	}{
+		{"GET", "/static/app.js", http.StatusOK, "console.log('hi')", true},
+		{"HEAD", "/static/app.js", http.StatusOK, "", true},
+		{"GET", "/static/docs/", http.StatusOK, "<h1>Docs</h1>", true},
+		// Directories without an index.html are not listed.
+		{"GET", "/static/", http.StatusNotFound, "", false},
+		{"GET", "/static/img/", http.StatusNotFound, "", false},
+		{"GET", "/static/missing.js", http.StatusNotFound, "", false},
+		{"POST", "/static/app.js", http.StatusMethodNotAllowed, "", false},
+		// The API is not shadowed.
+		{"GET", "/articles", http.StatusOK, "[]", false},
+	}
+	for _, tt := range tests {
+		rec := doRequest(root, tt.method, tt.target, "")
+		if rec.Code != tt.want {
+			t.Errorf("%s %s: status = %d, want %d", tt.method, tt.target, rec.Code, tt.want)
+			continue
+		}
+		if tt.body != "" && strings.TrimSpace(rec.Body.String()) != tt.body {
+			t.Errorf("%s %s: body = %q, want %q", tt.method, tt.target, rec.Body, tt.body)
+		}
+		if got := rec.Header().Get("Cache-Control") == "public, max-age=3600"; got != tt.cached {
+			t.Errorf("%s %s: Cache-Control = %q, want cached = %v", tt.method, tt.target, rec.Header().Get("Cache-Control"), tt.cached)
+		}
+	}
+}
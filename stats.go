@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"io"
+	"log"
+	"net/http"
+	"time"
+	"unicode/utf8"
+)
+
+// Stats summarizes the stored articles.
+type Stats struct {
+	Total     int `json:"total"`
+	Published int `json:"published"`
+	Drafts    int `json:"drafts"`
+	// Deleted counts the delete markers the change feed keeps: articles
+	// deleted since startup and not re-created, minus those whose markers
+	// were purged after -tombstone-retention. It can shrink over time.
+	Deleted int `json:"deleted"`
+	// Tags is the number of distinct tags in use.
+	Tags int `json:"tags"`
+	// AvgContentLength is the mean content length in characters.
+	AvgContentLength float64 `json:"avgContentLength"`
+	// OldestPublishAt and NewestPublishAt ignore articles without a
+	// PublishAt and are omitted when no article has one.
+	OldestPublishAt *time.Time `json:"oldestPublishAt,omitempty"`
+	NewestPublishAt *time.Time `json:"newestPublishAt,omitempty"`
+}
+
+// Stats computes Stats in a single pass over the repo.
+func (svc *articleSvc) Stats(ctx context.Context) (Stats, error) {
+	var (
+		stats        Stats
+		tags         = make(map[string]bool)
+		contentChars int
+	)
+	err := svc.repo.EachArticle(ctx, func(article Article) error {
+		stats.Total++
+		if article.Status == StatusDraft {
+			stats.Drafts++
+		} else {
+			stats.Published++
+		}
+		for _, tag := range article.Tags {
+			tags[tag] = true
+		}
+		contentChars += utf8.RuneCountInString(article.Content)
+
+		if publishAt := article.PublishAt; !publishAt.IsZero() {
+			if stats.OldestPublishAt == nil || publishAt.Before(*stats.OldestPublishAt) {
+				stats.OldestPublishAt = &publishAt
+			}
+			if stats.NewestPublishAt == nil || publishAt.After(*stats.NewestPublishAt) {
+				stats.NewestPublishAt = &publishAt
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return Stats{}, err
+	}
+
+	stats.Tags = len(tags)
+	if stats.Total > 0 {
+		stats.AvgContentLength = float64(contentChars) / float64(stats.Total)
+	}
+	stats.Deleted = svc.changes.deleted()
+	return stats, nil
+}
+
+func (t *articlesHttpTransport) stats(w http.ResponseWriter, r *http.Request) {
+	stats, err := t.svc.Stats(r.Context())
+	if err != nil {
+		log.Println(err)
+		w.WriteHeader(serverErrorStatus(err))
+		io.WriteString(w, errorBody(err))
+		return
+	}
+
+	t.writeJSON(w, r, http.StatusOK, stats)
+}
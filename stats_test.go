@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// eachOnlyRepo counts EachArticle passes and refuses AllArticles, so Stats
+// can't quietly materialize the whole store.
+type eachOnlyRepo struct {
+	*inMemoryRepo
+	passes atomic.Int64
+}
+
+func (repo *eachOnlyRepo) AllArticles(context.Context) ([]Article, error) {
+	panic("Stats must not load every article at once")
+}
+
+func (repo *eachOnlyRepo) EachArticle(ctx context.Context, fn func(Article) error) error {
+	repo.passes.Add(1)
+	return repo.inMemoryRepo.EachArticle(ctx, fn)
+}
+
+func TestStats(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2024, 3, d, 0, 0, 0, 0, time.UTC) }
+	svc := newTestSvc(withDefaultPublishAt(false))
+	mustAdd(t, svc,
+		Article{ID: "a1", Title: "T", Tags: []string{"go", "web"}, Content: "1234", PublishAt: day(10)},
+		Article{ID: "a2", Title: "T", Tags: []string{"go"}, Content: "ééé", PublishAt: day(2)},
+		Article{ID: "a3", Title: "T", Status: StatusDraft, Tags: []string{"draft"}, Content: "12345", PublishAt: day(20)},
+		Article{ID: "a4", Title: "T", Content: "", Status: StatusDraft},
+		Article{ID: "gone", Title: "T", Tags: []string{"gone"}},
+		Article{ID: "back", Title: "T"},
+	)
+	for _, id := range []string{"gone", "back"} {
+		if err := svc.DeleteArticle(context.Background(), id); err != nil {
+			t.Fatal(err)
+		}
+	}
+	mustAdd(t, svc, Article{ID: "back", Title: "T", Content: "12"})
+
+	repo := &eachOnlyRepo{inMemoryRepo: svc.repo.(*inMemoryRepo)}
+	svc.repo = repo
+	h := requireScope(scopeAdmin)(http.HandlerFunc(newArticlesHttpTransport(svc).stats))
+	h = testAuth(h, testKeys)
+
+	rec := doRequest(h, "GET", "/stats", "", "X-API-Key", testAdminKey)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+	}
+	var stats Stats
+	decodeJSON(t, rec, &stats)
+
+	oldest, newest := day(2), day(20)
+	want := Stats{
+		Total:            5,
+		Published:        3,
+		Drafts:           2,
+		Deleted:          1,
+		Tags:             3,
+		AvgContentLength: float64(4+3+5+0+2) / 5,
+		OldestPublishAt:  &oldest,
+		NewestPublishAt:  &newest,
+	}
+	if stats.Total != want.Total || stats.Published != want.Published || stats.Drafts != want.Drafts ||
+		stats.Deleted != want.Deleted || stats.Tags != want.Tags || stats.AvgContentLength != want.AvgContentLength {
+		t.Errorf("stats = %+v, want %+v", stats, want)
+	}
+	if stats.OldestPublishAt == nil || !stats.OldestPublishAt.Equal(oldest) ||
+		stats.NewestPublishAt == nil || !stats.NewestPublishAt.Equal(newest) {
+		t.Errorf("publishAt range = %v..%v, want %v..%v", stats.OldestPublishAt, stats.NewestPublishAt, oldest, newest)
+	}
+	if n := repo.passes.Load(); n != 1 {
+		t.Errorf("Stats made %d passes over the repo, want 1", n)
+	}
+}
+
+func TestStatsEmpty(t *testing.T) {
+	h := http.HandlerFunc(newArticlesHttpTransport(newTestSvc()).stats)
+	rec := doRequest(h, "GET", "/stats", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+	}
+	if strings.Contains(rec.Body.String(), "PublishAt") {
+		t.Errorf("empty stats carry publishAt bounds: %s", rec.Body)
+	}
+	var stats Stats
+	decodeJSON(t, rec, &stats)
+	if stats != (Stats{}) {
+		t.Errorf("stats = %+v, want all zero", stats)
+	}
+}
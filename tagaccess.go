@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// parseTagScopes parses -tag-scopes entries of the form tag=scope, such as
+// premium=premium:read.
+func parseTagScopes(entries []string) (map[string]string, error) {
+	if len(entries) == 0 {
+		return nil, nil
+	}
+	scopes := make(map[string]string, len(entries))
+	for _, entry := range entries {
+		tag, scope, ok := strings.Cut(entry, "=")
+		tag, scope = strings.TrimSpace(tag), strings.TrimSpace(scope)
+		if !ok || tag == "" || scope == "" {
+			return nil, fmt.Errorf("-tag-scopes: %q is not tag=scope", entry)
+		}
+		scopes[tag] = scope
+	}
+	return scopes, nil
+}
+
+// withTagScopes restricts articles carrying one of the tags to callers
+// holding the mapped scope. Others get 403 for such an article, or 404 when
+// hide is set, and don't see it in listings.
+func withTagScopes(scopes map[string]string, hide bool) transportOption {
+	return func(t *articlesHttpTransport) {
+		t.tagScopes = scopes
+		t.hideRestricted = hide
+	}
+}
+
+// deniedTags returns the restricted tags the caller lacks the scope for,
+// sorted. The admin scope satisfies every tag.
+func (t *articlesHttpTransport) deniedTags(r *http.Request) []string {
+	p := principalFromContext(r.Context())
+	if len(t.tagScopes) == 0 || p.hasScope(scopeAdmin) {
+		return nil
+	}
+	var denied []string
+	for tag, scope := range t.tagScopes {
+		if !p.hasScope(scope) {
+			denied = append(denied, tag)
+		}
+	}
+	sort.Strings(denied)
+	return denied
+}
+
+// restricted reports whether article carries one of the denied tags.
+func restricted(article Article, denied []string) bool {
+	for _, tag := range article.Tags {
+		for _, d := range denied {
+			if tag == d {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// withoutRestricted drops the articles carrying one of the denied tags.
+func withoutRestricted(articles []Article, denied []string) []Article {
+	if len(denied) == 0 {
+		return articles
+	}
+	visible := make([]Article, 0, len(articles))
+	for _, article := range articles {
+		if !restricted(article, denied) {
+			visible = append(visible, article)
+		}
+	}
+	return visible
+}
+
+// writeRestricted answers a request for an article the caller may not read
+// because of its tags.
+func (t *articlesHttpTransport) writeRestricted(w http.ResponseWriter, id string) {
+	if t.hideRestricted {
+		writeArticleNotFound(w, id)
+		return
+	}
+	w.WriteHeader(http.StatusForbidden)
+	io.WriteString(w, "forbidden")
+}
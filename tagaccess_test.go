@@ -0,0 +1,216 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+)
+
+const testPremiumKey = "premium-key"
+
+// restrictedKeys are the API keys of the tag access tests: premium may read
+// articles tagged premium, write may not.
+var restrictedKeys = map[string][]string{
+	testPremiumKey: {"premium:read"},
+	testWriteKey:   {scopeWrite},
+	testAdminKey:   {scopeAdmin},
+}
+
+// newRestrictedSvc stores o1, x1 and o2, published in that order, where only
+// x1 carries the restricted premium tag.
+func newRestrictedSvc(t *testing.T, opts ...svcOption) *articleSvc {
+	t.Helper()
+	svc := newTestSvc(opts...)
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	mustAdd(t, svc,
+		Article{ID: "o1", Title: "Open one", Tags: []string{"go"}, PublishAt: base},
+		Article{ID: "x1", Title: "Premium", Tags: []string{"go", "premium"}, PublishAt: base.Add(time.Hour)},
+		Article{ID: "o2", Title: "Open two", Tags: []string{"go"}, PublishAt: base.Add(2 * time.Hour)},
+	)
+	return svc
+}
+
+// newRestrictedHandler serves newRestrictedSvc with premium requiring the
+// premium:read scope.
+func newRestrictedHandler(t *testing.T, hide bool) http.Handler {
+	t.Helper()
+	h := newTestHandler(newRestrictedSvc(t), withTagScopes(map[string]string{"premium": "premium:read"}, hide))
+	return testAuth(h, restrictedKeys)
+}
+
+func TestRestrictedTags(t *testing.T) {
+	mergePatch := []string{"Content-Type", mergePatchContentType}
+
+	tests := []struct {
+		method, target, body string
+		headers              []string
+		// want is the status a caller without the scope gets; callers
+		// with it get wantAllowed.
+		want, wantAllowed int
+	}{
+		{"GET", "/articles/x1", "", nil, http.StatusForbidden, http.StatusOK},
+		{"GET", "/articles/x1/attachments", "", nil, http.StatusForbidden, http.StatusOK},
+		{"GET", "/articles/x1/export.md", "", nil, http.StatusForbidden, http.StatusOK},
+		{"GET", "/articles/x1/siblings", "", nil, http.StatusForbidden, http.StatusOK},
+		// An empty merge patch changes nothing and would echo the article.
+		{"PATCH", "/articles/x1", `{}`, mergePatch, http.StatusForbidden, http.StatusOK},
+		{"POST", "/articles/x1/clone", "", nil, http.StatusForbidden, http.StatusCreated},
+		{"POST", "/articles/x1/pin", "", nil, http.StatusForbidden, http.StatusOK},
+		{"GET", "/articles/o1", "", nil, http.StatusOK, http.StatusOK},
+	}
+	for _, hide := range []bool{false, true} {
+		h := newRestrictedHandler(t, hide)
+		for _, tt := range tests {
+			want := tt.want
+			if hide && want == http.StatusForbidden {
+				want = http.StatusNotFound
+			}
+			for _, key := range []string{"", testWriteKey} {
+				headers := tt.headers
+				if key != "" {
+					headers = append(slices.Clone(headers), "X-API-Key", key)
+				}
+				rec := doRequest(h, tt.method, tt.target, tt.body, headers...)
+				if rec.Code != want {
+					t.Errorf("hide=%v %s %s %v: status = %d, want %d", hide, tt.method, tt.target, headers, rec.Code, want)
+				}
+				if rec.Code != tt.wantAllowed && rec.Header().Get("X-Unchanged") != "" {
+					t.Errorf("hide=%v %s %s %v: X-Unchanged set on a refusal", hide, tt.method, tt.target, headers)
+				}
+			}
+			for _, key := range []string{testPremiumKey, testAdminKey} {
+				headers := append(slices.Clone(tt.headers), "X-API-Key", key)
+				if rec := doRequest(h, tt.method, tt.target, tt.body, headers...); rec.Code != tt.wantAllowed {
+					t.Errorf("hide=%v %s %s %v: status = %d, want %d", hide, tt.method, tt.target, headers, rec.Code, tt.wantAllowed)
+				}
+			}
+		}
+	}
+}
+
+func TestRestrictedTagsSkippedAsSiblings(t *testing.T) {
+	h := newRestrictedHandler(t, false)
+
+	tests := []struct {
+		headers                []string
+		wantPrevious, wantNext string
+	}{
+		{nil, "", "o2"},
+		{[]string{"X-API-Key", testPremiumKey}, "", "x1"},
+	}
+	for _, tt := range tests {
+		rec := doRequest(h, "GET", "/articles/o1/siblings", "", tt.headers...)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%v: status = %d", tt.headers, rec.Code)
+		}
+		var resp siblingsResponse
+		decodeJSON(t, rec, &resp)
+		if got := siblingID(resp.Previous); got != tt.wantPrevious {
+			t.Errorf("%v: previous = %q, want %q", tt.headers, got, tt.wantPrevious)
+		}
+		if got := siblingID(resp.Next); got != tt.wantNext {
+			t.Errorf("%v: next = %q, want %q", tt.headers, got, tt.wantNext)
+		}
+	}
+}
+
+// siblingID returns the ID of a sibling, or "" when there is none.
+func siblingID(article *Article) string {
+	if article == nil {
+		return ""
+	}
+	return article.ID
+}
+
+func TestRestrictedTagsInChanges(t *testing.T) {
+	h := newRestrictedHandler(t, false)
+
+	tests := []struct {
+		headers []string
+		want    []string
+	}{
+		{nil, []string{"o1", "o2"}},
+		{[]string{"X-API-Key", testWriteKey}, []string{"o1", "o2"}},
+		{[]string{"X-API-Key", testPremiumKey}, []string{"o1", "o2", "x1"}},
+	}
+	for _, tt := range tests {
+		rec := doRequest(h, "GET", "/articles/changes", "", tt.headers...)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%v: status = %d", tt.headers, rec.Code)
+		}
+		var resp changesResponse
+		decodeJSON(t, rec, &resp)
+		var ids []string
+		for _, change := range resp.Changes {
+			ids = append(ids, change.ID)
+		}
+		slices.Sort(ids)
+		if !slices.Equal(ids, tt.want) {
+			t.Errorf("%v: changes for %v, want %v", tt.headers, ids, tt.want)
+		}
+	}
+}
+
+func TestRestrictedTagsInEvents(t *testing.T) {
+	bus := newEventBus()
+	svc := newTestSvc(withEventBus(bus))
+	h := newTestHandler(svc, withEvents(bus), withTagScopes(map[string]string{"premium": "premium:read"}, false))
+	srv := httptest.NewServer(testAuth(h, restrictedKeys))
+	t.Cleanup(func() {
+		bus.Close()
+		srv.Close()
+	})
+
+	anonSSE := openEventStream(t, srv)
+	premiumSSE := openEventStream(t, srv, "X-API-Key", testPremiumKey)
+	anonWS := dialArticlesWebSocket(t, srv, bus)
+	premiumWS := dialArticlesWebSocket(t, srv, bus, "X-API-Key", testPremiumKey)
+	waitFor(t, "all four streams to subscribe", func() bool {
+		bus.mu.RLock()
+		defer bus.mu.RUnlock()
+		return len(bus.subs) == 4
+	})
+
+	putArticle(t, srv, `{"id":"x1","title":"Premium","tags":["premium"]}`)
+	putArticle(t, srv, `{"id":"o1","title":"Open","tags":["go"]}`)
+
+	// Events arrive in order, so a caller without the scope gets o1 first.
+	for _, tt := range []struct {
+		name string
+		next func() string
+		want []string
+	}{
+		{"anonymous SSE", func() string { return sseArticleID(t, readEvent(t, anonSSE)) }, []string{"o1"}},
+		{"premium SSE", func() string { return sseArticleID(t, readEvent(t, premiumSSE)) }, []string{"x1", "o1"}},
+		{"anonymous WS", func() string { return readWSEvent(t, anonWS).Article.ID }, []string{"o1"}},
+		{"premium WS", func() string { return readWSEvent(t, premiumWS).Article.ID }, []string{"x1", "o1"}},
+	} {
+		for _, want := range tt.want {
+			if got := tt.next(); got != want {
+				t.Errorf("%s: got an event for %q, want %q", tt.name, got, want)
+			}
+		}
+	}
+}
+
+func TestRestrictedTagsInTagSuggestions(t *testing.T) {
+	h := newRestrictedHandler(t, false)
+
+	tests := []struct {
+		headers []string
+		want    []TagSuggestion
+	}{
+		{nil, []TagSuggestion{{Tag: "go", Count: 2}}},
+		{[]string{"X-API-Key", testPremiumKey}, []TagSuggestion{{Tag: "go", Count: 3}, {Tag: "premium", Count: 1}}},
+	}
+	for _, tt := range tests {
+		rec := doRequest(h, "GET", "/tags/suggest", "", tt.headers...)
+		var got []TagSuggestion
+		decodeJSON(t, rec, &got)
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%v: suggestions = %v, want %v", tt.headers, got, tt.want)
+		}
+	}
+}
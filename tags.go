@@ -0,0 +1,411 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// loadTagVocabulary parses -tag-vocabulary: a comma separated list of tags,
+// or @path naming a file with one tag per line. Blank lines and lines
+// starting with # are skipped. An empty spec returns nil, allowing any tag.
+func loadTagVocabulary(spec string) ([]string, error) {
+	path, ok := strings.CutPrefix(spec, "@")
+	if !ok {
+		return splitList(spec), nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("-tag-vocabulary: %w", err)
+	}
+	var tags []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "#") {
+			tags = append(tags, line)
+		}
+	}
+	if len(tags) == 0 {
+		return nil, fmt.Errorf("-tag-vocabulary: %s lists no tags", path)
+	}
+	return tags, nil
+}
+
+// withTagVocabulary restricts article tags to tags. An empty list allows
+// any tag.
+func withTagVocabulary(tags []string) svcOption {
+	return func(svc *articleSvc) {
+		if len(tags) == 0 {
+			svc.tagVocabulary = nil
+			return
+		}
+		svc.tagVocabulary = make(map[string]bool, len(tags))
+		for _, tag := range tags {
+			svc.tagVocabulary[tag] = true
+		}
+	}
+}
+
+// checkTagVocabulary rejects tags outside the configured vocabulary, naming
+// every offending tag.
+func (svc *articleSvc) checkTagVocabulary(article Article) error {
+	if svc.tagVocabulary == nil {
+		return nil
+	}
+
+	var unknown []string
+	for _, tag := range article.Tags {
+		if !svc.tagVocabulary[tag] && !slices.Contains(unknown, tag) {
+			unknown = append(unknown, tag)
+		}
+	}
+	if len(unknown) > 0 {
+		return &ValidationError{Fields: []FieldError{{
+			Field:   "tags",
+			Message: "not in the allowed vocabulary: " + strings.Join(unknown, ", "),
+		}}}
+	}
+	return nil
+}
+
+// RenameTag replaces tag from with to on every article carrying it. Articles
+// that already carry to simply lose from, so no article ends up with the same
+// tag twice. Locked articles keep their tags. It returns the number of
+// articles changed.
+func (svc *articleSvc) RenameTag(ctx context.Context, from, to string) (int, error) {
+	from, to = strings.TrimSpace(from), strings.TrimSpace(to)
+
+	var fields []FieldError
+	if from == "" {
+		fields = append(fields, FieldError{Field: "from", Message: "is required"})
+	}
+	if to == "" {
+		fields = append(fields, FieldError{Field: "to", Message: "is required"})
+	}
+	if from != "" && from == to {
+		fields = append(fields, FieldError{Field: "to", Message: "must differ from from"})
+	}
+	if len(fields) > 0 {
+		return 0, &ValidationError{Fields: fields}
+	}
+
+	articles, err := svc.repo.ArticlesByTags(ctx, []string{from})
+	if err != nil {
+		return 0, err
+	}
+
+	affected := 0
+	for _, article := range articles {
+		tags, changed := renameTag(article.Tags, from, to)
+		if !changed || article.Locked {
+			continue
+		}
+
+		article.Tags = tags
+		if err := svc.UpdateArticle(ctx, article); err != nil {
+			return affected, err
+		}
+		affected++
+	}
+	return affected, nil
+}
+
+// BulkTags adds and removes tags on every article matching filter. Each
+// article is written on its own, so a failure leaves the articles before it
+// changed. Locked articles and articles already carrying the requested
+// tags are left alone. It returns the number of articles changed.
+func (svc *articleSvc) BulkTags(ctx context.Context, filter ArticleFilter, add, remove []string) (int, error) {
+	add, remove = trimTags(add), trimTags(remove)
+
+	var fields []FieldError
+	if len(add) == 0 && len(remove) == 0 {
+		fields = append(fields, FieldError{Field: "add", Message: "or remove must list at least one tag"})
+	}
+	for _, tag := range remove {
+		if slices.Contains(add, tag) {
+			fields = append(fields, FieldError{Field: "remove", Message: fmt.Sprintf("must not also be added: %s", tag)})
+		}
+	}
+	if len(fields) > 0 {
+		return 0, &ValidationError{Fields: fields}
+	}
+
+	articles, err := svc.Articles(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+
+	affected := 0
+	for _, article := range articles {
+		tags, changed := changeTags(article.Tags, add, remove)
+		if !changed || article.Locked {
+			continue
+		}
+
+		article.Tags = tags
+		if err := svc.UpdateArticle(ctx, article); err != nil {
+			return affected, err
+		}
+		affected++
+	}
+	return affected, nil
+}
+
+// trimTags trims every tag and drops the empty ones.
+func trimTags(tags []string) []string {
+	trimmed := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			trimmed = append(trimmed, tag)
+		}
+	}
+	return trimmed
+}
+
+// changeTags returns a copy of tags without remove and with the tags of add
+// it lacked appended, and whether anything changed.
+func changeTags(tags, add, remove []string) ([]string, bool) {
+	changed := false
+	result := make([]string, 0, len(tags)+len(add))
+	for _, tag := range tags {
+		if slices.Contains(remove, tag) {
+			changed = true
+			continue
+		}
+		result = append(result, tag)
+	}
+	for _, tag := range add {
+		if !slices.Contains(result, tag) {
+			result = append(result, tag)
+			changed = true
+		}
+	}
+	return result, changed
+}
+
+// renameTag returns a copy of tags with from replaced by to and duplicates of
+// to removed, and whether anything changed.
+func renameTag(tags []string, from, to string) ([]string, bool) {
+	changed := false
+	renamed := make([]string, 0, len(tags))
+	seen := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		if tag == from {
+			tag = to
+			changed = true
+		}
+		if seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		renamed = append(renamed, tag)
+	}
+	return renamed, changed
+}
+
+// TagSuggestion is a tag offered for autocompletion and the number of
+// articles carrying it.
+type TagSuggestion struct {
+	Tag   string `json:"tag"`
+	Count int    `json:"count"`
+}
+
+// hasPrefixFold is strings.HasPrefix ignoring case.
+func hasPrefixFold(s, prefix string) bool {
+	return len(s) >= len(prefix) && strings.EqualFold(s[:len(prefix)], prefix)
+}
+
+// rankTagSuggestions orders counts most used first, by tag on ties, and keeps
+// the first limit of them, or all when limit is zero.
+func rankTagSuggestions(counts map[string]int, limit int) []TagSuggestion {
+	suggestions := make([]TagSuggestion, 0, len(counts))
+	for tag, n := range counts {
+		suggestions = append(suggestions, TagSuggestion{Tag: tag, Count: n})
+	}
+	sort.Slice(suggestions, func(i, j int) bool {
+		if suggestions[i].Count != suggestions[j].Count {
+			return suggestions[i].Count > suggestions[j].Count
+		}
+		return suggestions[i].Tag < suggestions[j].Tag
+	})
+	if limit > 0 && len(suggestions) > limit {
+		suggestions = suggestions[:limit]
+	}
+	return suggestions
+}
+
+func (svc *articleSvc) SuggestTags(ctx context.Context, prefix string, limit int) ([]TagSuggestion, error) {
+	return svc.repo.SuggestTags(ctx, strings.TrimSpace(prefix), limit)
+}
+
+func (t *articlesHttpTransport) setupTagRoutes(r *mux.Router) *mux.Router {
+	r.Handle("/rename", requireScope(scopeWrite)(http.HandlerFunc(t.renameTag))).Methods("POST")
+	r.HandleFunc("/suggest", t.suggestTags).Methods("GET")
+	return r
+}
+
+// suggestTags autocompletes tags: it lists the tags starting with ?prefix=,
+// ignoring case, most used first, up to limit (default 10, at most 100).
+func (t *articlesHttpTransport) suggestTags(w http.ResponseWriter, r *http.Request) {
+	limit := 10
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > 100 {
+			w.WriteHeader(http.StatusBadRequest)
+			io.WriteString(w, "limit must be between 1 and 100")
+			return
+		}
+		limit = n
+	}
+
+	var (
+		prefix      = strings.TrimSpace(r.URL.Query().Get("prefix"))
+		drafts      = t.showsDrafts(r)
+		denied      = t.deniedTags(r)
+		suggestions []TagSuggestion
+		err         error
+	)
+	if drafts && len(denied) == 0 {
+		suggestions, err = t.svc.SuggestTags(r.Context(), prefix, limit)
+	} else {
+		suggestions, err = t.visibleTagSuggestions(r.Context(), drafts, denied, prefix, limit)
+	}
+	if err != nil {
+		log.Println(err)
+		w.WriteHeader(serverErrorStatus(err))
+		io.WriteString(w, errorBody(err))
+		return
+	}
+
+	t.writeJSON(w, r, http.StatusOK, suggestions)
+}
+
+// visibleTagSuggestions is SuggestTags counting only the articles a caller
+// may read: drafts only when drafts is set, and none carrying a denied tag.
+// The repo's tag index counts every article, so this walks the articles
+// instead.
+func (t *articlesHttpTransport) visibleTagSuggestions(ctx context.Context, drafts bool, denied []string, prefix string, limit int) ([]TagSuggestion, error) {
+	counts := make(map[string]int)
+	err := t.svc.EachArticle(ctx, func(article Article) error {
+		if (!drafts && article.Status == StatusDraft) || restricted(article, denied) {
+			return nil
+		}
+		for i, tag := range article.Tags {
+			if hasPrefixFold(tag, prefix) && !slices.Contains(article.Tags[:i], tag) {
+				counts[tag]++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rankTagSuggestions(counts, limit), nil
+}
+
+type renameTagRequest struct {
+	From    string `json:"from"`
+	To      string `json:"to"`
+	Confirm bool   `json:"confirm"`
+}
+
+type renameTagResponse struct {
+	Affected int `json:"affected"`
+}
+
+func (t *articlesHttpTransport) renameTag(w http.ResponseWriter, r *http.Request) {
+	var req renameTagRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+
+	if !req.Confirm {
+		w.WriteHeader(http.StatusBadRequest)
+		io.WriteString(w, `renaming a tag rewrites every article carrying it; set "confirm": true to proceed`)
+		return
+	}
+
+	affected, err := t.svc.RenameTag(r.Context(), req.From, req.To)
+	if err != nil {
+		log.Println(err)
+		var verr *ValidationError
+		if errors.As(err, &verr) {
+			writeValidationError(w, verr)
+			return
+		}
+		w.WriteHeader(serverErrorStatus(err))
+		io.WriteString(w, errorBody(err))
+		return
+	}
+
+	t.writeJSON(w, r, http.StatusOK, renameTagResponse{Affected: affected})
+}
+
+// bulkTagsRequest selects articles the way GET /articles does and lists the
+// tags to add to and remove from each of them.
+type bulkTagsRequest struct {
+	Filter struct {
+		Tags    []string  `json:"tags"`
+		AnyTags []string  `json:"anyTags"`
+		From    time.Time `json:"from"`
+		To      time.Time `json:"to"`
+		Pinned  bool      `json:"pinned"`
+	} `json:"filter"`
+	Add     []string `json:"add"`
+	Remove  []string `json:"remove"`
+	Confirm bool     `json:"confirm"`
+}
+
+type bulkTagsResponse struct {
+	Affected int `json:"affected"`
+}
+
+// bulkTags serves POST /articles/tags/bulk, such as adding "archive" to
+// every article published in 2022.
+func (t *articlesHttpTransport) bulkTags(w http.ResponseWriter, r *http.Request) {
+	var req bulkTagsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+
+	if !req.Confirm {
+		w.WriteHeader(http.StatusBadRequest)
+		io.WriteString(w, `bulk tagging rewrites every matching article; set "confirm": true to proceed`)
+		return
+	}
+	filter := ArticleFilter{PinnedOnly: req.Filter.Pinned, Tags: req.Filter.Tags, AnyTags: req.Filter.AnyTags, From: req.Filter.From, To: req.Filter.To}
+	if !filter.From.IsZero() && !filter.To.IsZero() && filter.From.After(filter.To) {
+		w.WriteHeader(http.StatusBadRequest)
+		io.WriteString(w, "from must not be after to")
+		return
+	}
+
+	affected, err := t.svc.BulkTags(r.Context(), filter, req.Add, req.Remove)
+	if err != nil {
+		log.Println(err)
+		var verr *ValidationError
+		if errors.As(err, &verr) {
+			writeValidationError(w, verr)
+			return
+		}
+		w.WriteHeader(serverErrorStatus(err))
+		io.WriteString(w, errorBody(err))
+		return
+	}
+
+	t.writeJSON(w, r, http.StatusOK, bulkTagsResponse{Affected: affected})
+}
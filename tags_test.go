@@ -0,0 +1,106 @@
+package main
+
+import (
+	"net/http"
+	"slices"
+	"testing"
+)
+
+func TestRenameTag(t *testing.T) {
+	svc := newTestSvc()
+	mustAdd(t, svc,
+		Article{ID: "a", Title: "A", Tags: []string{"golang", "web"}},
+		Article{ID: "b", Title: "B", Tags: []string{"go", "golang"}},
+		Article{ID: "c", Title: "C", Tags: []string{"rust"}},
+	)
+	h := testAuth(newTestHandler(svc), testKeys)
+
+	rec := doRequest(h, "POST", "/tags/rename", `{"from":"golang","to":"go","confirm":true}`, "X-API-Key", testWriteKey)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", rec.Code, rec.Body)
+	}
+	var resp renameTagResponse
+	decodeJSON(t, rec, &resp)
+	if resp.Affected != 2 {
+		t.Errorf("affected = %d, want 2", resp.Affected)
+	}
+
+	want := map[string][]string{
+		"a": {"go", "web"},
+		// b already carried go, so the rename must not duplicate it.
+		"b": {"go"},
+		"c": {"rust"},
+	}
+	for id, tags := range want {
+		if got := mustGet(t, svc, id).Tags; !slices.Equal(got, tags) {
+			t.Errorf("%s: tags = %v, want %v", id, got, tags)
+		}
+	}
+
+	rec = doRequest(h, "POST", "/tags/rename", `{"from":"golang","to":"go","confirm":true}`, "X-API-Key", testWriteKey)
+	decodeJSON(t, rec, &resp)
+	if rec.Code != http.StatusOK || resp.Affected != 0 {
+		t.Errorf("renaming again: got %d affected %d, want 200 and 0", rec.Code, resp.Affected)
+	}
+}
+
+func TestRenameTagRejects(t *testing.T) {
+	const valid = `{"from":"a","to":"b","confirm":true}`
+
+	tests := []struct {
+		name string
+		body string
+		key  string
+		want int
+	}{
+		{name: "anonymous", body: valid, want: http.StatusUnauthorized},
+		{name: "without write scope", body: valid, key: testReadKey, want: http.StatusForbidden},
+		{name: "unconfirmed", body: `{"from":"a","to":"b"}`, key: testWriteKey, want: http.StatusBadRequest},
+		{name: "missing from", body: `{"to":"b","confirm":true}`, key: testWriteKey, want: http.StatusUnprocessableEntity},
+		{name: "missing to", body: `{"from":"a","to":"  ","confirm":true}`, key: testWriteKey, want: http.StatusUnprocessableEntity},
+		{name: "same tag", body: `{"from":"a","to":" a ","confirm":true}`, key: testWriteKey, want: http.StatusUnprocessableEntity},
+		{name: "not json", body: `{`, key: testWriteKey, want: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := newTestSvc()
+			mustAdd(t, svc, Article{ID: "x", Title: "X", Tags: []string{"a"}})
+			h := testAuth(newTestHandler(svc), testKeys)
+			rec := doRequest(h, "POST", "/tags/rename", tt.body, "X-API-Key", tt.key)
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+			if got := mustGet(t, svc, "x").Tags; !slices.Equal(got, []string{"a"}) {
+				t.Errorf("tags changed to %v", got)
+			}
+		})
+	}
+}
+
+func TestBulkTagsRejects(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want int
+	}{
+		{name: "unconfirmed", body: `{"add":["b"]}`, want: http.StatusBadRequest},
+		{name: "nothing to do", body: `{"confirm":true}`, want: http.StatusUnprocessableEntity},
+		{name: "added and removed", body: `{"add":["b"],"remove":["b"],"confirm":true}`, want: http.StatusUnprocessableEntity},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := newTestSvc()
+			mustAdd(t, svc, Article{ID: "x", Title: "X", Tags: []string{"a"}})
+			h := testAuth(newTestHandler(svc), testKeys)
+			rec := doRequest(h, "POST", "/articles/tags/bulk", tt.body, "X-API-Key", testWriteKey)
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+			if got := mustGet(t, svc, "x").Tags; !slices.Equal(got, []string{"a"}) {
+				t.Errorf("tags changed to %v", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ContentTransformer rewrites article content before it is stored, for
+// example to expand shortcodes or filter words. Returning an error rejects
+// the write.
+type ContentTransformer interface {
+	Transform(ctx context.Context, content string) (string, error)
+}
+
+// ContentTransformerFunc adapts a function to ContentTransformer.
+type ContentTransformerFunc func(ctx context.Context, content string) (string, error)
+
+func (f ContentTransformerFunc) Transform(ctx context.Context, content string) (string, error) {
+	return f(ctx, content)
+}
+
+// TransformError is returned when a content transformer rejects an article.
+type TransformError struct {
+	Err error
+}
+
+func (e *TransformError) Error() string {
+	return "content rejected: " + e.Err.Error()
+}
+
+func (e *TransformError) Unwrap() error {
+	return e.Err
+}
+
+// contentTransformers maps the names accepted by -content-transformers to
+// the transformers they stand for.
+var contentTransformers = map[string]ContentTransformer{
+	"autolink": ContentTransformerFunc(autoLink),
+}
+
+// checkContentTransformers reports the first name that isn't a registered
+// transformer.
+func checkContentTransformers(names []string) error {
+	for _, name := range names {
+		if _, ok := contentTransformers[name]; !ok {
+			return fmt.Errorf("-content-transformers: unknown transformer %q", name)
+		}
+	}
+	return nil
+}
+
+// withContentTransformers runs content through transformers, in order, on
+// every create and update. Each transformer receives the output of the one
+// before it.
+func withContentTransformers(transformers ...ContentTransformer) svcOption {
+	return func(svc *articleSvc) {
+		svc.transformers = transformers
+	}
+}
+
+// withNamedContentTransformers is withContentTransformers for the names of
+// registered transformers. Unknown names are skipped; config.validate
+// rejects them before the service is used.
+func withNamedContentTransformers(names []string) svcOption {
+	var transformers []ContentTransformer
+	for _, name := range names {
+		if transformer, ok := contentTransformers[name]; ok {
+			transformers = append(transformers, transformer)
+		}
+	}
+	return withContentTransformers(transformers...)
+}
+
+// transformContent applies the transformer pipeline to content.
+func (svc *articleSvc) transformContent(ctx context.Context, content string) (string, error) {
+	for _, transformer := range svc.transformers {
+		var err error
+		if content, err = transformer.Transform(ctx, content); err != nil {
+			return "", &TransformError{Err: err}
+		}
+	}
+	return content, nil
+}
+
+var bareURL = regexp.MustCompile(`https?://[^\s<>()\[\]"']+`)
+
+// autoLink turns bare http and https URLs into Markdown autolinks. URLs
+// that are already part of a link, such as [text](url) or <url>, are left
+// alone, so running it again over its own output changes nothing.
+func autoLink(_ context.Context, content string) (string, error) {
+	var b strings.Builder
+	last := 0
+	for _, loc := range bareURL.FindAllStringIndex(content, -1) {
+		start, end := loc[0], loc[1]
+		if start > 0 && strings.ContainsRune(`(<"'=`, rune(content[start-1])) {
+			continue
+		}
+		// Sentence punctuation right after a URL is not part of it.
+		end = start + len(strings.TrimRight(content[start:end], ".,;:!?"))
+
+		b.WriteString(content[last:start])
+		b.WriteString("<" + content[start:end] + ">")
+		last = end
+	}
+	b.WriteString(content[last:])
+	return b.String(), nil
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// appending is a transformer that appends suffix and records its call in
+// calls.
+func appending(suffix string, calls *[]string) ContentTransformer {
+	return ContentTransformerFunc(func(_ context.Context, content string) (string, error) {
+		*calls = append(*calls, suffix+"<"+content)
+		return content + suffix, nil
+	})
+}
+
+func TestContentTransformerOrder(t *testing.T) {
+	var calls []string
+	svc := newTestSvc(withContentTransformers(appending("-a", &calls), appending("-b", &calls), appending("-c", &calls)))
+	h := newTestHandler(svc)
+
+	if rec := doRequest(h, "PUT", "/articles", `{"id":"a1","title":"One","content":"x"}`); rec.Code != http.StatusCreated {
+		t.Fatalf("create: status %d %s", rec.Code, rec.Body)
+	}
+	if got := mustGet(t, svc, "a1").Content; got != "x-a-b-c" {
+		t.Errorf("created content = %q, want x-a-b-c", got)
+	}
+	// Each transformer saw the output of the one before it.
+	if want := "-a<x -b<x-a -c<x-a-b"; strings.Join(calls, " ") != want {
+		t.Errorf("calls = %q, want %q", strings.Join(calls, " "), want)
+	}
+
+	calls = nil
+	if rec := doRequest(h, "PUT", "/articles/a1", `{"title":"One","content":"y"}`); rec.Code != http.StatusOK {
+		t.Fatalf("update: status %d %s", rec.Code, rec.Body)
+	}
+	if got := mustGet(t, svc, "a1").Content; got != "y-a-b-c" {
+		t.Errorf("updated content = %q, want y-a-b-c", got)
+	}
+	if len(calls) != 3 {
+		t.Errorf("update ran %d transformers, want 3", len(calls))
+	}
+}
+
+func TestContentTransformerRejects(t *testing.T) {
+	var calls []string
+	reject := ContentTransformerFunc(func(_ context.Context, content string) (string, error) {
+		if strings.Contains(content, "darn") {
+			return "", errors.New("mind your language")
+		}
+		return content, nil
+	})
+	svc := newTestSvc(withContentTransformers(appending("-a", &calls), reject, appending("-c", &calls)))
+	mustAdd(t, svc, Article{ID: "a1", Title: "One", Content: "fine"})
+	h := newTestHandler(svc)
+
+	tests := []struct {
+		method, target, body string
+	}{
+		{"PUT", "/articles", `{"id":"a2","title":"Two","content":"darn"}`},
+		{"PUT", "/articles/a1", `{"title":"One","content":"darn it"}`},
+	}
+	for _, tt := range tests {
+		calls = nil
+		rec := doRequest(h, tt.method, tt.target, tt.body)
+		if rec.Code != http.StatusUnprocessableEntity || !strings.Contains(rec.Body.String(), "mind your language") {
+			t.Errorf("%s %s: got %d %q, want 422 with the reason", tt.method, tt.target, rec.Code, rec.Body)
+		}
+		if len(calls) != 1 {
+			t.Errorf("%s %s: %d transformers ran, want the pipeline to stop at the rejection", tt.method, tt.target, len(calls))
+		}
+	}
+	if _, err := svc.Article(context.Background(), "a2"); !errors.Is(err, ErrArticleNotFound) {
+		t.Errorf("rejected article was stored: %v", err)
+	}
+	if got := mustGet(t, svc, "a1").Content; got != "fine-a-c" {
+		t.Errorf("a1 content = %q after a rejected update, want it unchanged", got)
+	}
+}
+
+func TestAutoLink(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"see https://example.com", "see <https://example.com>"},
+		{"see http://example.com/a?b=c.", "see <http://example.com/a?b=c>."},
+		{"two: https://a.example, https://b.example!", "two: <https://a.example>, <https://b.example>!"},
+		{"[text](https://example.com)", "[text](https://example.com)"},
+		{"<https://example.com>", "<https://example.com>"},
+		{`<a href="https://example.com">x</a>`, `<a href="https://example.com">x</a>`},
+		{"no links here", "no links here"},
+	}
+	for _, tt := range tests {
+		got, err := autoLink(context.Background(), tt.in)
+		if err != nil || got != tt.want {
+			t.Errorf("autoLink(%q) = %q, %v; want %q", tt.in, got, err, tt.want)
+		}
+		if again, _ := autoLink(context.Background(), got); again != got {
+			t.Errorf("autoLink is not idempotent on %q: %q", got, again)
+		}
+	}
+}
+
+func TestNamedContentTransformers(t *testing.T) {
+	if err := checkContentTransformers([]string{"autolink"}); err != nil {
+		t.Errorf("autolink rejected: %v", err)
+	}
+	if err := checkContentTransformers([]string{"autolink", "shout"}); err == nil || !strings.Contains(err.Error(), `"shout"`) {
+		t.Errorf("unknown transformer: err = %v", err)
+	}
+
+	svc := newTestSvc(withNamedContentTransformers([]string{"autolink"}))
+	mustAdd(t, svc, Article{ID: "a1", Title: "One", Content: "at https://example.com"})
+	if got := mustGet(t, svc, "a1").Content; got != "at <https://example.com>" {
+		t.Errorf("content = %q", got)
+	}
+}
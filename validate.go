@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+)
+
+// ValidateArticle runs article through what AddArticle does before storing
+// it: the defaults, the content transformers and every rule on the article
+// itself. Rule failures are reported in one ValidationError, a rejected
+// content as a TransformError. It does not check whether the ID is already
+// taken.
+func (svc *articleSvc) ValidateArticle(ctx context.Context, article Article) error {
+	_, err := svc.prepareNewArticle(ctx, article)
+	return err
+}
+
+// checkArticle runs every rule AddArticle and UpdateArticle enforce on the
+// article itself and reports all failures in one ValidationError.
+func (svc *articleSvc) checkArticle(article Article) error {
+	var fields []FieldError
+	for _, err := range []error{article.Validate(), svc.checkContentLength(article), svc.checkTagVocabulary(article)} {
+		var verr *ValidationError
+		if errors.As(err, &verr) {
+			fields = append(fields, verr.Fields...)
+		}
+	}
+	if len(fields) > 0 {
+		return &ValidationError{Fields: fields}
+	}
+	return nil
+}
+
+type validateResponse struct {
+	Valid  bool         `json:"valid"`
+	Errors []FieldError `json:"errors,omitempty"`
+}
+
+// validateArticle checks an article the way a create would, without
+// storing it: 200 with {"valid": true}, or 422 with the field errors. Content
+// a transformer rejects is reported as an error on the content field.
+func (t *articlesHttpTransport) validateArticle(w http.ResponseWriter, r *http.Request) {
+	var article Article
+	if err := json.NewDecoder(r.Body).Decode(&article); err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+
+	resp := validateResponse{Valid: true}
+	status := http.StatusOK
+	if err := t.svc.ValidateArticle(r.Context(), article); err != nil {
+		var (
+			verr *ValidationError
+			terr *TransformError
+		)
+		switch {
+		case errors.As(err, &verr):
+			resp = validateResponse{Errors: verr.Fields}
+		case errors.As(err, &terr):
+			resp = validateResponse{Errors: []FieldError{{Field: "content", Message: terr.Error()}}}
+		default:
+			log.Println(err)
+			w.WriteHeader(serverErrorStatus(err))
+			io.WriteString(w, errorBody(err))
+			return
+		}
+		status = http.StatusUnprocessableEntity
+	}
+
+	t.writeJSON(w, r, status, resp)
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestValidateArticle(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		wantFields []string
+	}{
+		{"valid", `{"id":"a1","title":"T","tags":["go"],"content":"short"}`, nil},
+		{"missing id and title", `{"content":"x"}`, []string{"id", "title"}},
+		{"blank title", `{"id":"a1","title":"   "}`, []string{"title"}},
+		{"too many tags", `{"id":"a1","title":"T","tags":["go","go","go","go","go","go","go","go","go","go","go"]}`, []string{"tags"}},
+		{"empty tag", `{"id":"a1","title":"T","tags":["go",""]}`, []string{"tags"}},
+		{"unknown tag", `{"id":"a1","title":"T","tags":["rust"]}`, []string{"tags"}},
+		{"content too long", `{"id":"a1","title":"T","content":"` + strings.Repeat("x", 21) + `"}`, []string{"content"}},
+		{"bad attachment", `{"id":"a1","title":"T","attachments":[{"url":"ftp://x","mimeType":"text/html","size":-1}]}`,
+			[]string{"attachments[0].url", "attachments[0].mimeType", "attachments[0].size"}},
+		{"every rule at once", `{"title":"","tags":["rust"],"content":"` + strings.Repeat("x", 21) + `"}`, []string{"id", "title", "content", "tags"}},
+		// autolink adds two characters, pushing this over the limit.
+		{"too long once transformed", `{"id":"a1","title":"T","content":"http://abc.example.o"}`, []string{"content"}},
+		{"rejected by a transformer", `{"id":"a1","title":"T","content":"forbidden"}`, []string{"content"}},
+	}
+	rejectForbidden := ContentTransformerFunc(func(_ context.Context, content string) (string, error) {
+		if content == "forbidden" {
+			return "", errors.New("forbidden content")
+		}
+		return content, nil
+	})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := newTestSvc(
+				withTagVocabulary([]string{"go", "web"}),
+				withMaxContentLength(20),
+				withContentTransformers(rejectForbidden, ContentTransformerFunc(autoLink)),
+			)
+			h := newTestHandler(svc)
+
+			rec := doRequest(h, "POST", "/articles/validate", tt.body)
+			var resp validateResponse
+			decodeJSON(t, rec, &resp)
+
+			var fields []string
+			for _, f := range resp.Errors {
+				fields = append(fields, f.Field)
+				if f.Message == "" {
+					t.Errorf("field %s has no message", f.Field)
+				}
+			}
+			if tt.wantFields == nil {
+				if rec.Code != http.StatusOK || !resp.Valid || len(resp.Errors) != 0 {
+					t.Errorf("got %d %+v, want 200 valid", rec.Code, resp)
+				}
+			} else {
+				if rec.Code != http.StatusUnprocessableEntity || resp.Valid {
+					t.Errorf("got %d valid=%v, want 422 invalid", rec.Code, resp.Valid)
+				}
+				for _, want := range tt.wantFields {
+					if !slices.Contains(fields, want) {
+						t.Errorf("errors %v are missing %s", fields, want)
+					}
+				}
+			}
+			if ids := storedIDs(t, svc.repo); len(ids) != 0 {
+				t.Fatalf("validate stored %v", ids)
+			}
+
+			// The create path applies the same rules.
+			create := doRequest(h, "PUT", "/articles", tt.body)
+			if wantCreated := tt.wantFields == nil; (create.Code == http.StatusCreated) != wantCreated {
+				t.Errorf("validate and create disagree: create answered %d: %s", create.Code, create.Body)
+			}
+		})
+	}
+}
+
+func TestValidateArticleBadBody(t *testing.T) {
+	h := newTestHandler(newTestSvc())
+	for _, body := range []string{"", `{"id":`, `[]`} {
+		if rec := doRequest(h, "POST", "/articles/validate", body); rec.Code != http.StatusBadRequest {
+			t.Errorf("%q: status = %d, want 400", body, rec.Code)
+		}
+	}
+}
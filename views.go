@@ -0,0 +1,215 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// maxTrendingWindow is the longest window trending can be asked for;
+	// older views are pruned.
+	maxTrendingWindow = 30 * 24 * time.Hour
+	// viewBucket is the granularity at which views are kept.
+	viewBucket = time.Hour
+	// viewQueueSize bounds the views waiting to be counted. Views arriving
+	// while it is full are dropped rather than slowing the read down.
+	viewQueueSize = 1024
+)
+
+type articleView struct {
+	id string
+	at time.Time
+}
+
+// viewTracker counts article views in hourly buckets per article. Recording
+// never blocks: views are handed to a background goroutine and dropped when
+// it falls behind.
+type viewTracker struct {
+	queue chan articleView
+	clock Clock
+
+	mu sync.Mutex
+	// buckets maps article ID to bucket start (Unix seconds) to views.
+	buckets map[string]map[int64]int
+}
+
+func newViewTracker(clock Clock) *viewTracker {
+	t := &viewTracker{
+		queue:   make(chan articleView, viewQueueSize),
+		clock:   clock,
+		buckets: make(map[string]map[int64]int),
+	}
+	go t.run()
+	return t
+}
+
+// record counts a view of id, best effort.
+func (t *viewTracker) record(id string) {
+	select {
+	case t.queue <- articleView{id: id, at: t.clock.Now()}:
+	default:
+	}
+}
+
+func (t *viewTracker) run() {
+	prune := time.NewTicker(viewBucket)
+	defer prune.Stop()
+
+	for {
+		select {
+		case view := <-t.queue:
+			t.mu.Lock()
+			counts := t.buckets[view.id]
+			if counts == nil {
+				counts = make(map[int64]int)
+				t.buckets[view.id] = counts
+			}
+			counts[view.at.Truncate(viewBucket).Unix()]++
+			t.mu.Unlock()
+		case <-prune.C:
+			t.prune()
+		}
+	}
+}
+
+// prune drops buckets older than maxTrendingWindow.
+func (t *viewTracker) prune() {
+	cutoff := t.clock.Now().Add(-maxTrendingWindow).Truncate(viewBucket).Unix()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for id, counts := range t.buckets {
+		for start := range counts {
+			if start < cutoff {
+				delete(counts, start)
+			}
+		}
+		if len(counts) == 0 {
+			delete(t.buckets, id)
+		}
+	}
+}
+
+// viewCount is the number of views of an article within a window.
+type viewCount struct {
+	ID    string
+	Views int
+}
+
+// top returns the articles with the most views in the last window, most
+// viewed first and by ID on ties.
+func (t *viewTracker) top(window time.Duration) []viewCount {
+	cutoff := t.clock.Now().Add(-window).Truncate(viewBucket).Unix()
+
+	t.mu.Lock()
+	counts := make([]viewCount, 0, len(t.buckets))
+	for id, buckets := range t.buckets {
+		views := 0
+		for start, n := range buckets {
+			if start >= cutoff {
+				views += n
+			}
+		}
+		if views > 0 {
+			counts = append(counts, viewCount{ID: id, Views: views})
+		}
+	}
+	t.mu.Unlock()
+
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Views != counts[j].Views {
+			return counts[i].Views > counts[j].Views
+		}
+		return counts[i].ID < counts[j].ID
+	})
+	return counts
+}
+
+// withViewTracker makes the transport count article reads and serve
+// trending articles.
+func withViewTracker(views *viewTracker) transportOption {
+	return func(t *articlesHttpTransport) {
+		t.views = views
+	}
+}
+
+// parseWindow parses a trending window such as "7d", "12h" or "90m".
+func parseWindow(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, err
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(s)
+}
+
+type trendingArticle struct {
+	Views   int     `json:"views"`
+	Article Article `json:"article"`
+}
+
+// trendingArticles lists the most viewed published articles within window
+// (default 7d, at most 30d), up to limit (default 10, at most 100).
+func (t *articlesHttpTransport) trendingArticles(w http.ResponseWriter, r *http.Request) {
+	if t.views == nil {
+		w.WriteHeader(http.StatusNotFound)
+		io.WriteString(w, "view tracking is disabled")
+		return
+	}
+
+	window := 7 * 24 * time.Hour
+	if v := r.URL.Query().Get("window"); v != "" {
+		d, err := parseWindow(v)
+		if err != nil || d <= 0 || d > maxTrendingWindow {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(w, "window must be a positive duration such as 7d or 12h, at most %dd", int(maxTrendingWindow.Hours()/24))
+			return
+		}
+		window = d
+	}
+
+	limit := 10
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > 100 {
+			w.WriteHeader(http.StatusBadRequest)
+			io.WriteString(w, "limit must be between 1 and 100")
+			return
+		}
+		limit = n
+	}
+
+	denied := t.deniedTags(r)
+	trending := make([]trendingArticle, 0, limit)
+	for _, count := range t.views.top(window) {
+		if len(trending) == limit {
+			break
+		}
+		article, err := t.svc.Article(r.Context(), count.ID)
+		if errors.Is(err, ErrArticleNotFound) {
+			continue
+		}
+		if err != nil {
+			log.Println(err)
+			w.WriteHeader(serverErrorStatus(err))
+			io.WriteString(w, errorBody(err))
+			return
+		}
+		if article.Status == StatusDraft || restricted(*article, denied) {
+			continue
+		}
+		trending = append(trending, trendingArticle{Views: count.Views, Article: *article})
+	}
+
+	t.writeJSON(w, r, http.StatusOK, trending)
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestTrendingArticles(t *testing.T) {
+	clock := newFakeClock(time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC))
+	views := newViewTracker(clock)
+	svc := newTestSvc(withClock(clock))
+	mustAdd(t, svc,
+		Article{ID: "a", Title: "A"},
+		Article{ID: "b", Title: "B"},
+		Article{ID: "c", Title: "C"},
+		Article{ID: "draft", Title: "D", Status: StatusDraft},
+		Article{ID: "gone", Title: "G"},
+	)
+	h := newTestHandler(svc, withViewTracker(views))
+
+	recorded := 0
+	view := func(id string, n int) {
+		t.Helper()
+		for i := 0; i < n; i++ {
+			if rec := doRequest(h, "GET", "/articles/"+id, ""); rec.Code != http.StatusOK {
+				t.Fatalf("GET %s: status = %d", id, rec.Code)
+			}
+		}
+		recorded += n
+		// Views are counted in the background; wait until they are in.
+		waitFor(t, "views to be counted", func() bool {
+			total := 0
+			for _, count := range views.top(maxTrendingWindow) {
+				total += count.Views
+			}
+			return total == recorded
+		})
+	}
+
+	view("b", 5)
+	clock.Advance(10 * 24 * time.Hour)
+	view("a", 2)
+	view("c", 3)
+	view("gone", 9)
+	views.record("draft")
+	views.record("draft")
+	recorded += 2
+	view("a", 2)
+	if err := svc.DeleteArticle(context.Background(), "gone"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		query string
+		want  []string
+		views []int
+	}{
+		{"", []string{"a", "c"}, []int{4, 3}},
+		{"?window=7d", []string{"a", "c"}, []int{4, 3}},
+		{"?window=30d", []string{"b", "a", "c"}, []int{5, 4, 3}},
+		{"?window=30d&limit=2", []string{"b", "a"}, []int{5, 4}},
+		{"?window=1h", []string{"a", "c"}, []int{4, 3}},
+	}
+	for _, tt := range tests {
+		rec := doRequest(h, "GET", "/articles/trending"+tt.query, "")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: status = %d: %s", tt.query, rec.Code, rec.Body)
+		}
+		var trending []trendingArticle
+		decodeJSON(t, rec, &trending)
+		var ids []string
+		var counts []int
+		for _, entry := range trending {
+			ids = append(ids, entry.Article.ID)
+			counts = append(counts, entry.Views)
+		}
+		if !slices.Equal(ids, tt.want) || !slices.Equal(counts, tt.views) {
+			t.Errorf("%s: trending %v with views %v, want %v with %v", tt.query, ids, counts, tt.want, tt.views)
+		}
+	}
+
+	// Past the retention window every view is gone.
+	clock.Advance(maxTrendingWindow + viewBucket)
+	views.prune()
+	if rec := doRequest(h, "GET", "/articles/trending?window=30d", ""); rec.Body.String() != "[]\n" {
+		t.Errorf("after pruning = %s, want []", rec.Body)
+	}
+}
+
+func TestTrendingTies(t *testing.T) {
+	clock := newFakeClock(time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC))
+	views := newViewTracker(clock)
+	for _, id := range []string{"c", "a", "b", "a", "c", "b"} {
+		views.record(id)
+	}
+	waitFor(t, "views to be counted", func() bool { return len(views.top(time.Hour)) == 3 })
+
+	want := []viewCount{{"a", 2}, {"b", 2}, {"c", 2}}
+	if got := views.top(time.Hour); !slices.Equal(got, want) {
+		t.Errorf("top = %v, want %v", got, want)
+	}
+}
+
+func TestTrendingRejects(t *testing.T) {
+	h := newTestHandler(newTestSvc(), withViewTracker(newViewTracker(realClock{})))
+	for _, query := range []string{"window=0d", "window=31d", "window=soon", "window=-1h", "limit=0", "limit=101", "limit=ten"} {
+		if rec := doRequest(h, "GET", "/articles/trending?"+query, ""); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", query, rec.Code)
+		}
+	}
+
+	if rec := doRequest(newTestHandler(newTestSvc()), "GET", "/articles/trending", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("without view tracking: status = %d, want 404", rec.Code)
+	}
+}
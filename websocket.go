@@ -0,0 +1,139 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	wsWriteWait    = 10 * time.Second
+	wsPongWait     = 60 * time.Second
+	wsPingPeriod   = wsPongWait * 9 / 10
+	wsMaxReadBytes = 4096
+)
+
+var wsUpgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+}
+
+// wsClientMessage is sent by WebSocket clients. A "subscribe" message narrows
+// the stream to events for articles carrying at least one of Tags; an empty
+// tag list restores the unfiltered stream.
+type wsClientMessage struct {
+	Type string   `json:"type"`
+	Tags []string `json:"tags"`
+}
+
+// articlesWebSocket pushes article change events to a WebSocket client until
+// either side closes the connection.
+func (t *articlesHttpTransport) articlesWebSocket(w http.ResponseWriter, r *http.Request) {
+	if t.events == nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	conn, err := wsUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	defer conn.Close()
+
+	events, unsubscribe := t.events.Subscribe()
+	defer unsubscribe()
+	denied := t.deniedTags(r)
+
+	var (
+		filters = make(chan []string)
+		stop    = make(chan struct{})
+		done    = make(chan struct{})
+	)
+	defer close(stop)
+	go readWebSocket(conn, filters, stop, done)
+
+	ping := time.NewTicker(wsPingPeriod)
+	defer ping.Stop()
+
+	var tags map[string]bool
+	for {
+		select {
+		case <-done:
+			return
+		case list := <-filters:
+			tags = nil
+			if len(list) > 0 {
+				tags = make(map[string]bool, len(list))
+				for _, tag := range list {
+					tags[tag] = true
+				}
+			}
+		case ev, ok := <-events:
+			conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+			if !ok {
+				conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutting down"))
+				return
+			}
+			if !hasAnyTag(ev.Article, tags) || t.hidesDraft(r, ev.Article) || restricted(ev.Article, denied) {
+				continue
+			}
+			if err := conn.WriteJSON(ev); err != nil {
+				log.Println(err)
+				return
+			}
+		case <-ping.C:
+			conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+			if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// readWebSocket handles client messages and pongs until the connection fails
+// or stop is closed, then closes done.
+func readWebSocket(conn *websocket.Conn, filters chan<- []string, stop <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+
+	conn.SetReadLimit(wsMaxReadBytes)
+	conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	})
+
+	for {
+		var msg wsClientMessage
+		if err := conn.ReadJSON(&msg); err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				log.Println(err)
+			}
+			return
+		}
+		if msg.Type != "subscribe" {
+			continue
+		}
+
+		select {
+		case filters <- msg.Tags:
+		case <-stop:
+			return
+		}
+	}
+}
+
+// hasAnyTag reports whether article carries one of tags. A nil set matches
+// every article.
+func hasAnyTag(article Article, tags map[string]bool) bool {
+	if tags == nil {
+		return true
+	}
+	for _, tag := range article.Tags {
+		if tags[tag] {
+			return true
+		}
+	}
+	return false
+}
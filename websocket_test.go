@@ -0,0 +1,114 @@
+package main
+
+import (
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// dialArticlesWebSocket connects to /articles/ws and waits until the
+// connection is subscribed to the bus.
+func dialArticlesWebSocket(t *testing.T, srv *httptest.Server, bus *eventBus, headers ...string) *websocket.Conn {
+	t.Helper()
+	header := make(map[string][]string)
+	for i := 0; i+1 < len(headers); i += 2 {
+		header[headers[i]] = []string{headers[i+1]}
+	}
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"/articles/ws", header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(time.Millisecond) {
+		bus.mu.RLock()
+		subscribed := len(bus.subs) > 0
+		bus.mu.RUnlock()
+		if subscribed {
+			return conn
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("websocket never subscribed")
+		}
+	}
+}
+
+// readWSEvent reads the next event, failing the test if none arrives within
+// a few seconds.
+func readWSEvent(t *testing.T, conn *websocket.Conn) articleEvent {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var ev articleEvent
+	if err := conn.ReadJSON(&ev); err != nil {
+		t.Fatalf("reading an event: %v", err)
+	}
+	return ev
+}
+
+func TestArticlesWebSocket(t *testing.T) {
+	srv, _, bus := newEventServer(t)
+	conn := dialArticlesWebSocket(t, srv, bus)
+
+	putArticle(t, srv, `{"id":"a1","title":"First","tags":["go"]}`)
+	ev := readWSEvent(t, conn)
+	if ev.Type != eventArticleCreated || ev.Article.ID != "a1" {
+		t.Errorf("got %s for %q, want %s for a1", ev.Type, ev.Article.ID, eventArticleCreated)
+	}
+}
+
+func TestArticlesWebSocketTagSubscription(t *testing.T) {
+	srv, _, bus := newEventServer(t)
+	conn := dialArticlesWebSocket(t, srv, bus)
+	if err := conn.WriteJSON(wsClientMessage{Type: "subscribe", Tags: []string{"go"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The subscription is applied asynchronously. Until it is, both
+	// articles of a round come through; once it is, the untagged one is
+	// dropped and the first event read is the tagged one.
+	for i := 0; ; i++ {
+		if i == 100 {
+			t.Fatal("the tag subscription was never applied")
+		}
+		n := strconv.Itoa(i)
+		putArticle(t, srv, `{"id":"other`+n+`","title":"Other `+n+`","tags":["rust"]}`)
+		putArticle(t, srv, `{"id":"go`+n+`","title":"Go `+n+`","tags":["go"]}`)
+
+		ev := readWSEvent(t, conn)
+		if ev.Article.ID == "go"+n {
+			break
+		}
+		if ev.Article.ID != "other"+n {
+			t.Fatalf("got an event for %q, want other%s or go%s", ev.Article.ID, n, n)
+		}
+		if ev := readWSEvent(t, conn); ev.Article.ID != "go"+n {
+			t.Fatalf("got an event for %q, want go%s", ev.Article.ID, n)
+		}
+	}
+
+	// An empty subscription restores the full stream: the untagged article
+	// of a round then arrives before the tagged marker.
+	if err := conn.WriteJSON(wsClientMessage{Type: "subscribe"}); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; ; i++ {
+		if i == 100 {
+			t.Fatal("clearing the subscription was never applied")
+		}
+		n := strconv.Itoa(i)
+		putArticle(t, srv, `{"id":"all`+n+`","title":"All `+n+`","tags":["rust"]}`)
+		putArticle(t, srv, `{"id":"marker`+n+`","title":"Marker `+n+`","tags":["go"]}`)
+
+		ev := readWSEvent(t, conn)
+		if ev.Article.ID == "all"+n {
+			break
+		}
+		if ev.Article.ID != "marker"+n {
+			t.Fatalf("got an event for %q, want all%s or marker%s", ev.Article.ID, n, n)
+		}
+	}
+}